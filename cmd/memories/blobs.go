@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"github.com/Oxyrus/memories/internal/blob"
+	"github.com/Oxyrus/memories/internal/config"
+	"github.com/Oxyrus/memories/internal/ingest"
+	"github.com/Oxyrus/memories/internal/storage"
+	"github.com/Oxyrus/memories/internal/storage/sqlite"
+)
+
+const (
+	blobsUsage        = "usage: memories blobs migrate|gc [flags]\n"
+	blobsMigrateUsage = "usage: memories blobs migrate --from <local|s3> --to <local|s3> [--env-file <path>]\n"
+	blobsGCUsage      = "usage: memories blobs gc [--grace <duration>]\n"
+)
+
+// blobGracePeriod is how long an unreferenced content-addressed file is kept
+// after it was last claimed, so uploads in progress in another process can
+// record their photos before the file is collected.
+const blobGracePeriod = time.Hour
+
+// openBlobs returns the store that holds photo files, as selected by
+// MEMORIES_STORAGE.
+func openBlobs(cfg *config.Config) (blob.Store, error) {
+	return openBlobBackend(cfg, cfg.Storage)
+}
+
+// openBlobBackend returns the store for the named backend, configured from
+// cfg whichever backend is active.
+func openBlobBackend(cfg *config.Config, backend string) (blob.Store, error) {
+	switch backend {
+	case "local":
+		return blob.NewLocal(cfg.UploadsDir, "/uploads")
+	case "s3":
+		return blob.NewS3(blob.S3Config{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKey,
+			SecretAccessKey: cfg.S3SecretKey,
+			Prefix:          cfg.S3Prefix,
+			PathStyle:       cfg.S3PathStyle,
+			URLExpiry:       cfg.S3URLExpiry,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q (want local or s3)", backend)
+	}
+}
+
+// newPipeline returns the ingest pipeline for the configured storage layout.
+func newPipeline(cfg *config.Config, logger *slog.Logger, store storage.Store, blobs blob.Store) (*ingest.Pipeline, error) {
+	layout := ingest.Layout(cfg.StorageLayout)
+	if !layout.Valid() {
+		return nil, fmt.Errorf("unknown MEMORIES_STORAGE_LAYOUT %q (want album or content)", cfg.StorageLayout)
+	}
+	return ingest.New(logger, store.Photos(), store.Blobs(), blobs, layout), nil
+}
+
+func runBlobs(args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "migrate":
+			return runBlobsMigrate(args[1:])
+		case "gc":
+			return runBlobsGC(args[1:])
+		}
+	}
+	fmt.Fprint(os.Stderr, blobsUsage)
+	return 2
+}
+
+func runBlobsGC(args []string) int {
+	flags := flag.NewFlagSet("blobs gc", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, blobsGCUsage)
+		flags.PrintDefaults()
+	}
+	grace := flags.Duration("grace", blobGracePeriod, "keep unreferenced files claimed more recently than this")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 || *grace < 0 {
+		flags.Usage()
+		return 2
+	}
+
+	cfg := config.LoadCommand()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	store, err := sqlite.Open(cfg.DBPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open %s: %v\n", cfg.DBPath, err)
+		return 1
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "close %s: %v\n", cfg.DBPath, err)
+		}
+	}()
+
+	blobs, err := openBlobs(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open photo storage: %v\n", err)
+		return 1
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	pipeline, err := newPipeline(cfg, logger, store, blobs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "blobs gc: %v\n", err)
+		return 1
+	}
+
+	removed, err := pipeline.Collect(ctx, time.Now().Add(-*grace))
+	fmt.Fprintf(os.Stdout, "removed %d unreferenced files\n", removed)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "blobs gc: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func runBlobsMigrate(args []string) int {
+	flags := flag.NewFlagSet("blobs migrate", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, blobsMigrateUsage)
+		flags.PrintDefaults()
+	}
+	from := flags.String("from", "", "backend the photo files are stored in now")
+	to := flags.String("to", "", "backend to copy the photo files to")
+	envFile := flags.String("env-file", ".env", "file to set MEMORIES_STORAGE in once every file is copied")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *from == "" || *to == "" || flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+	if *from == *to {
+		fmt.Fprintf(os.Stderr, "--from and --to are both %s\n", *from)
+		return 2
+	}
+
+	cfg := config.LoadCommand()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	src, err := openBlobBackend(cfg, *from)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open %s storage: %v\n", *from, err)
+		return 1
+	}
+	dst, err := openBlobBackend(cfg, *to)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open %s storage: %v\n", *to, err)
+		return 1
+	}
+
+	store, err := sqlite.Open(cfg.DBPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open %s: %v\n", cfg.DBPath, err)
+		return 1
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "close %s: %v\n", cfg.DBPath, err)
+		}
+	}()
+
+	files, err := photoFiles(ctx, store)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "list photo files: %v\n", err)
+		return 1
+	}
+
+	var copied, present, failed int
+	for i, file := range files {
+		if err := ctx.Err(); err != nil {
+			fmt.Fprintf(os.Stderr, "interrupted; run the command again to continue\n")
+			return 1
+		}
+
+		status, err := migrateBlob(ctx, dst, src, file)
+		if err != nil {
+			fmt.Fprintf(os.Stdout, "[%d/%d] %s: error: %v\n", i+1, len(files), file.key, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(os.Stdout, "[%d/%d] %s: %s\n", i+1, len(files), file.key, status)
+		if status == "copied" {
+			copied++
+		} else {
+			present++
+		}
+	}
+
+	fmt.Fprintf(os.Stdout, "copied %d files to %s, %d already there\n", copied, *to, present)
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d files could not be copied; MEMORIES_STORAGE was not changed, run the command again to retry\n", failed)
+		return 1
+	}
+
+	if err := switchStorage(*envFile, *to); err != nil {
+		fmt.Fprintf(os.Stderr, "every file was copied, but MEMORIES_STORAGE could not be set in %s: %v\n", *envFile, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stdout, "set MEMORIES_STORAGE=%s in %s; restart the server to use it. Files in %s storage were kept.\n", *to, *envFile, *from)
+	return 0
+}
+
+// photoFile is a stored file referenced by a photo. sha256 is the recorded
+// hash of an original, or empty for variants and photos recorded before
+// hashes were stored.
+type photoFile struct {
+	key    string
+	sha256 string
+}
+
+// photoFiles lists the originals and variants of every photo, each key once.
+func photoFiles(ctx context.Context, store storage.Store) ([]photoFile, error) {
+	albums, err := store.Albums().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []photoFile
+	seen := make(map[string]bool)
+	add := func(key, sha256 string) {
+		if !seen[key] {
+			seen[key] = true
+			files = append(files, photoFile{key: key, sha256: sha256})
+		}
+	}
+	for _, album := range albums {
+		photos, err := store.Photos().ListByAlbum(ctx, album.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, photo := range photos {
+			add(photo.Filename, photo.SHA256)
+			for _, variant := range photo.Variants {
+				add(variant.Filename, "")
+			}
+		}
+	}
+	return files, nil
+}
+
+// migrateBlob copies file from src to dst unless an identical copy is already
+// there, which is what makes an interrupted migration resumable. Originals
+// must also match the hash recorded when they were uploaded.
+func migrateBlob(ctx context.Context, dst, src blob.Store, file photoFile) (string, error) {
+	_, err := dst.Stat(ctx, file.key)
+	if err == nil {
+		want, err := blob.Checksum(ctx, src, file.key)
+		if err != nil {
+			return "", err
+		}
+		if err := checkRecordedHash(file, want); err != nil {
+			return "", err
+		}
+		got, err := blob.Checksum(ctx, dst, file.key)
+		if err != nil {
+			return "", err
+		}
+		if got == want {
+			return "already copied", nil
+		}
+	} else if !errors.Is(err, blob.ErrNotFound) {
+		return "", err
+	}
+
+	sum, err := blob.Copy(ctx, dst, src, file.key)
+	if err != nil {
+		return "", err
+	}
+	if err := checkRecordedHash(file, sum); err != nil {
+		return "", err
+	}
+	return "copied", nil
+}
+
+func checkRecordedHash(file photoFile, sum string) error {
+	if file.sha256 != "" && sum != file.sha256 {
+		return fmt.Errorf("%w: the stored file does not match the hash recorded for the photo", blob.ErrChecksumMismatch)
+	}
+	return nil
+}
+
+var storageLinePattern = regexp.MustCompile(`^\s*(export\s+)?MEMORIES_STORAGE\s*=`)
+
+// switchStorage sets MEMORIES_STORAGE to backend in the env file, replacing an
+// existing assignment or appending one, and warns when the process environment
+// would override the file.
+func switchStorage(envFile, backend string) error {
+	content, err := os.ReadFile(envFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	fromFile, _ := godotenv.Read(envFile)
+	if current := os.Getenv("MEMORIES_STORAGE"); current != "" && current != fromFile["MEMORIES_STORAGE"] {
+		fmt.Fprintf(os.Stderr, "warning: MEMORIES_STORAGE is set in the environment, which takes precedence over %s; set it to %s there too\n", envFile, backend)
+	}
+
+	var out bytes.Buffer
+	replaced := false
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if storageLinePattern.MatchString(line) {
+			if replaced {
+				continue
+			}
+			line = "MEMORIES_STORAGE=" + backend
+			replaced = true
+		}
+		out.WriteString(line + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if !replaced {
+		out.WriteString("MEMORIES_STORAGE=" + backend + "\n")
+	}
+
+	mode := os.FileMode(0o600)
+	if info, err := os.Stat(envFile); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(envFile), ".env-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(out.Bytes()); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), envFile)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/Oxyrus/memories/internal/config"
+	"github.com/Oxyrus/memories/internal/ingest"
+	"github.com/Oxyrus/memories/internal/storage"
+	"github.com/Oxyrus/memories/internal/storage/sqlite"
+)
+
+const importUsage = "usage: memories import --album <slug> [--create] [--title <title>] [--dry-run] <dir>\n"
+
+func runImport(args []string) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, importUsage)
+		flags.PrintDefaults()
+	}
+	slug := flags.String("album", "", "slug of the album to import into")
+	create := flags.Bool("create", false, "create the album if it does not exist")
+	title := flags.String("title", "", "title for an album created with --create (default: derived from the slug)")
+	dryRun := flags.Bool("dry-run", false, "list the photos that would be imported without importing them")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *slug == "" || flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	if !storage.ValidSlug(*slug) {
+		fmt.Fprintf(os.Stderr, "invalid album slug %q: use lowercase letters, numbers and hyphens\n", *slug)
+		return 2
+	}
+	dir := flags.Arg(0)
+
+	files, ignored, err := findPhotos(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "import: %v\n", err)
+		return 1
+	}
+
+	cfg := config.LoadCommand()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	store, err := sqlite.Open(cfg.DBPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open %s: %v\n", cfg.DBPath, err)
+		return 1
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "close %s: %v\n", cfg.DBPath, err)
+		}
+	}()
+
+	album, err := store.Albums().GetBySlug(ctx, *slug)
+	switch {
+	case errors.Is(err, storage.ErrNotFound) && *create:
+		input := storage.AlbumCreate{Slug: *slug, Title: strings.TrimSpace(*title)}
+		if input.Title == "" {
+			input.Title = titleFromSlug(*slug)
+		}
+		if *dryRun {
+			fmt.Fprintf(os.Stdout, "would create album %q (%s)\n", input.Title, input.Slug)
+			album = storage.Album{Slug: input.Slug, Title: input.Title}
+			break
+		}
+		album, err = store.Albums().Create(ctx, input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "create album %s: %v\n", *slug, err)
+			return 1
+		}
+		fmt.Fprintf(os.Stdout, "created album %q (%s)\n", album.Title, album.Slug)
+	case errors.Is(err, storage.ErrNotFound):
+		fmt.Fprintf(os.Stderr, "album %q does not exist; pass --create to create it\n", *slug)
+		return 1
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "load album %s: %v\n", *slug, err)
+		return 1
+	}
+
+	if *dryRun {
+		for i, file := range files {
+			fmt.Fprintf(os.Stdout, "[%d/%d] %s: would import\n", i+1, len(files), file)
+		}
+		fmt.Fprintf(os.Stdout, "would import %d photos into %s, %d other files ignored\n", len(files), album.Slug, ignored)
+		return 0
+	}
+
+	blobs, err := openBlobs(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open photo storage: %v\n", err)
+		return 1
+	}
+
+	// The pipeline only logs what the progress lines below cannot show.
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	pipeline, err := newPipeline(cfg, logger, store, blobs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "import: %v\n", err)
+		return 1
+	}
+
+	var imported, duplicates, failed int
+	for i, file := range files {
+		if ctx.Err() != nil {
+			fmt.Fprintf(os.Stderr, "import interrupted after %d of %d files\n", i, len(files))
+			break
+		}
+
+		result := pipeline.Ingest(ctx, album, file, ingest.CopyFile(filepath.Join(dir, file)), ingest.Options{})
+		var line string
+		switch result.Status {
+		case ingest.StatusUploaded:
+			imported++
+			line = "imported"
+		case ingest.StatusDuplicate:
+			duplicates++
+			line = fmt.Sprintf("skipped, same as photo %d", result.ExistingPhotoID)
+		default:
+			failed++
+			line = fmt.Sprintf("%s: %s", result.Status, result.Error)
+		}
+		fmt.Fprintf(os.Stdout, "[%d/%d] %s: %s\n", i+1, len(files), file, line)
+	}
+
+	fmt.Fprintf(os.Stdout, "imported %d photos into %s, %d duplicates skipped, %d other files ignored\n", imported, album.Slug, duplicates, ignored)
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d photos could not be imported\n", failed)
+		return 1
+	}
+	if ctx.Err() != nil {
+		return 1
+	}
+	return 0
+}
+
+// findPhotos walks dir recursively and returns the paths of photos relative
+// to dir in lexical order, along with how many other files were ignored.
+// Hidden files and directories are skipped without being counted.
+func findPhotos(dir string) (files []string, ignored int, err error) {
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if !d.Type().IsRegular() || !ingest.IsImageFile(d.Name()) {
+			ignored++
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, rel)
+		return nil
+	})
+	return files, ignored, err
+}
+
+// titleFromSlug turns "summer-roadtrip-2019" into "Summer Roadtrip 2019".
+func titleFromSlug(slug string) string {
+	words := strings.Split(slug, "-")
+	for i, word := range words {
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	return strings.Join(words, " ")
+}
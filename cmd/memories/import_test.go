@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Oxyrus/memories/internal/storage"
+	"github.com/Oxyrus/memories/internal/storage/sqlite"
+)
+
+func TestFindPhotosSkipsHiddenFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"b.jpg",
+		"2019/a.PNG",
+		"2019/notes.txt",
+		".hidden.jpg",
+		".thumbnails/c.jpg",
+		"2019/.DS_Store",
+	} {
+		writeTestFile(t, filepath.Join(dir, name), []byte("photo"))
+	}
+
+	files, ignored, err := findPhotos(dir)
+	if err != nil {
+		t.Fatalf("findPhotos: %v", err)
+	}
+	want := []string{filepath.Join("2019", "a.PNG"), "b.jpg"}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("expected %v, got %v", want, files)
+	}
+	if ignored != 1 {
+		t.Fatalf("expected 1 ignored file, got %d", ignored)
+	}
+}
+
+func TestTitleFromSlug(t *testing.T) {
+	if got := titleFromSlug("summer-roadtrip-2019"); got != "Summer Roadtrip 2019" {
+		t.Fatalf("expected Summer Roadtrip 2019, got %q", got)
+	}
+}
+
+func TestRunImportDryRunChangesNothing(t *testing.T) {
+	dbPath, uploads := setupCommandEnv(t)
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "beach.jpg"), testJPEG(t, color.RGBA{R: 200, A: 255}))
+
+	if code := runImport([]string{"--album", "summer-roadtrip", "--create", "--dry-run", dir}); code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+
+	store := openTestStore(t, dbPath)
+	if _, err := store.Albums().GetBySlug(context.Background(), "summer-roadtrip"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected no album to be created, got %v", err)
+	}
+	if entries, _ := os.ReadDir(uploads); len(entries) != 0 {
+		t.Fatalf("expected no files to be stored, got %d", len(entries))
+	}
+}
+
+func TestRunImportCreatesAlbum(t *testing.T) {
+	dbPath, _ := setupCommandEnv(t)
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "beach.jpg"), testJPEG(t, color.RGBA{R: 200, A: 255}))
+	writeTestFile(t, filepath.Join(dir, "day2", "dunes.jpg"), testJPEG(t, color.RGBA{G: 200, A: 255}))
+	writeTestFile(t, filepath.Join(dir, "notes.txt"), []byte("packing list"))
+
+	if code := runImport([]string{"--album", "summer-roadtrip", dir}); code != 1 {
+		t.Fatalf("expected exit code 1 without --create, got %d", code)
+	}
+	if code := runImport([]string{"--album", "summer-roadtrip", "--create", dir}); code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+	// Running the import again skips the photos it already added.
+	if code := runImport([]string{"--album", "summer-roadtrip", dir}); code != 0 {
+		t.Fatalf("expected exit code 0 when re-run, got %d", code)
+	}
+
+	ctx := context.Background()
+	store := openTestStore(t, dbPath)
+	album, err := store.Albums().GetBySlug(ctx, "summer-roadtrip")
+	if err != nil {
+		t.Fatalf("get album: %v", err)
+	}
+	if album.Title != "Summer Roadtrip" {
+		t.Fatalf("expected the title to be derived from the slug, got %q", album.Title)
+	}
+	photos, err := store.Photos().ListByAlbum(ctx, album.ID)
+	if err != nil {
+		t.Fatalf("list photos: %v", err)
+	}
+	if len(photos) != 2 {
+		t.Fatalf("expected 2 photos, got %d", len(photos))
+	}
+}
+
+// setupCommandEnv points the commands at a fresh database and uploads
+// directory and returns their paths.
+func setupCommandEnv(t *testing.T) (dbPath, uploads string) {
+	t.Helper()
+	dbPath = filepath.Join(t.TempDir(), "memories.db")
+	uploads = t.TempDir()
+	t.Setenv("MEMORIES_DB_PATH", dbPath)
+	t.Setenv("MEMORIES_UPLOADS_PATH", uploads)
+	t.Setenv("MEMORIES_STORAGE", "local")
+	t.Setenv("MEMORIES_STORAGE_LAYOUT", "album")
+	return dbPath, uploads
+}
+
+func openTestStore(t *testing.T, dbPath string) storage.Store {
+	t.Helper()
+	store, err := sqlite.Open(dbPath)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func writeTestFile(t *testing.T, path string, content []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("create dir: %v", err)
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+// testJPEG returns a small JPEG filled with fill, so photos of different
+// colors have different content.
+func testJPEG(t *testing.T, fill color.Color) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for x := 0; x < 16; x++ {
+		for y := 0; y < 16; y++ {
+			img.Set(x, y, fill)
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("encode test jpeg: %v", err)
+	}
+	return buf.Bytes()
+}
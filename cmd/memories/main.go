@@ -1,48 +1,45 @@
 package main
 
 import (
-	"log/slog"
+	"fmt"
 	"os"
-
-	"github.com/Oxyrus/memories/internal/config"
-	"github.com/Oxyrus/memories/internal/logging"
-	"github.com/Oxyrus/memories/internal/router"
-	"github.com/Oxyrus/memories/internal/storage/sqlite"
 )
 
-func main() {
-	bootstrapLogger := logging.New(slog.LevelInfo)
+const usage = `Usage: memories [command]
 
-	cfg, err := config.Load()
-	if err != nil {
-		bootstrapLogger.Error("failed to load config", "error", err)
-		os.Exit(1)
-	}
+Commands:
+  serve              start the HTTP server (default)
+  migrate status     list schema migrations and whether they are applied
+  migrate up         apply pending schema migrations
+  photos backfill    record size, dimensions and hashes for older photos
+  import             import a folder of photos into an album
+                     (memories import --album <slug> [--create] [--dry-run] <dir>)
+  blobs migrate      copy photo files to another storage backend and switch to it
+                     (memories blobs migrate --from local --to s3)
+  blobs gc           delete content-addressed photo files no photo uses
+`
 
-	logger := logging.New(cfg.LogLevel)
-
-	store, err := sqlite.Open(cfg.DBPath)
-	if err != nil {
-		logger.Error("failed to open sqlite database", "path", cfg.DBPath, "error", err)
-		os.Exit(1)
-	}
-	defer func() {
-		if err := store.Close(); err != nil {
-			logger.Error("failed to close sqlite database", "error", err)
-		}
-	}()
-
-	if err := os.MkdirAll(cfg.UploadsDir, 0o755); err != nil {
-		logger.Error("failed to ensure uploads directory", "path", cfg.UploadsDir, "error", err)
-		os.Exit(1)
+func main() {
+	args := os.Args[1:]
+	if len(args) == 0 {
+		os.Exit(runServe())
 	}
 
-	logger.Info("starting server", "addr", cfg.Addr)
-
-	r := router.New(cfg, logger, store)
-
-	if err := r.Run(cfg.Addr); err != nil {
-		logger.Error("server stopped", "error", err)
-		os.Exit(1)
+	switch args[0] {
+	case "serve":
+		os.Exit(runServe())
+	case "migrate":
+		os.Exit(runMigrate(args[1:]))
+	case "photos":
+		os.Exit(runPhotos(args[1:]))
+	case "import":
+		os.Exit(runImport(args[1:]))
+	case "blobs":
+		os.Exit(runBlobs(args[1:]))
+	case "help", "-h", "--help":
+		fmt.Fprint(os.Stdout, usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", args[0], usage)
+		os.Exit(2)
 	}
 }
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Oxyrus/memories/internal/config"
+	"github.com/Oxyrus/memories/internal/storage/sqlite"
+)
+
+func runMigrate(args []string) int {
+	if len(args) != 1 || (args[0] != "status" && args[0] != "up") {
+		fmt.Fprint(os.Stderr, "usage: memories migrate status|up\n")
+		return 2
+	}
+
+	cfg := config.LoadCommand()
+	ctx := context.Background()
+
+	store, err := sqlite.OpenUnmigrated(cfg.DBPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open %s: %v\n", cfg.DBPath, err)
+		return 1
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "close %s: %v\n", cfg.DBPath, err)
+		}
+	}()
+
+	if args[0] == "up" {
+		applied, err := store.Migrate(ctx)
+		for _, m := range applied {
+			fmt.Fprintf(os.Stdout, "applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(os.Stdout, "database is up to date")
+		}
+		return 0
+	}
+
+	statuses, err := store.MigrationStatus(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate status: %v\n", err)
+		return 1
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+	pending := 0
+	for _, st := range statuses {
+		name := st.Name
+		if !st.Known {
+			name = "(unknown to this binary)"
+		}
+		applied := "pending"
+		if st.AppliedAt != nil {
+			applied = st.AppliedAt.Format("2006-01-02 15:04:05 MST")
+		} else {
+			pending++
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", st.Version, name, applied)
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "migrate status: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stdout, "%d pending\n", pending)
+	return 0
+}
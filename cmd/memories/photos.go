@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/Oxyrus/memories/internal/blob"
+	"github.com/Oxyrus/memories/internal/config"
+	"github.com/Oxyrus/memories/internal/media"
+	"github.com/Oxyrus/memories/internal/storage"
+	"github.com/Oxyrus/memories/internal/storage/sqlite"
+)
+
+func runPhotos(args []string) int {
+	if len(args) != 1 || args[0] != "backfill" {
+		fmt.Fprint(os.Stderr, "usage: memories photos backfill\n")
+		return 2
+	}
+
+	cfg := config.LoadCommand()
+	ctx := context.Background()
+
+	store, err := sqlite.Open(cfg.DBPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open %s: %v\n", cfg.DBPath, err)
+		return 1
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "close %s: %v\n", cfg.DBPath, err)
+		}
+	}()
+
+	blobs, err := openBlobs(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open photo storage: %v\n", err)
+		return 1
+	}
+
+	updated, failed, err := backfillPhotos(ctx, store, blobs)
+	fmt.Fprintf(os.Stdout, "updated %d photos\n", updated)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "photos backfill: %v\n", err)
+		return 1
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d photos could not be read\n", failed)
+		return 1
+	}
+	return 0
+}
+
+// backfillPhotos measures every photo recorded before file details were
+// stored (those without a SHA256 or perceptual hash) and saves the result.
+// Photos whose file is missing or unreadable are reported and counted in
+// failed, keeping any details that could be measured; other photos are still
+// processed.
+func backfillPhotos(ctx context.Context, store storage.Store, blobs blob.Store) (updated, failed int, err error) {
+	albums, err := store.Albums().List(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, album := range albums {
+		photos, err := store.Photos().ListByAlbum(ctx, album.ID)
+		if err != nil {
+			return updated, failed, err
+		}
+
+		for _, photo := range photos {
+			if photo.SHA256 != "" && photo.PerceptualHash != "" {
+				continue
+			}
+
+			diskPath, cleanup, err := fetchBlob(ctx, blobs, photo.Filename)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "photo %d (%s): %v\n", photo.ID, photo.Filename, err)
+				failed++
+				continue
+			}
+			input, err := measurePhoto(photo, diskPath)
+			cleanup()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "photo %d (%s): %v\n", photo.ID, photo.Filename, err)
+				failed++
+				if input.SHA256 == nil {
+					continue
+				}
+			}
+
+			_, err = store.Photos().Update(ctx, photo.ID, input)
+			if errors.Is(err, storage.ErrConflict) {
+				// Uploaded twice before duplicates were rejected; keep both.
+				fmt.Fprintf(os.Stderr, "photo %d (%s): same content as another photo in the album, kept as a duplicate\n", photo.ID, photo.Filename)
+				allow := true
+				input.AllowDuplicate = &allow
+				_, err = store.Photos().Update(ctx, photo.ID, input)
+			}
+			if err != nil {
+				return updated, failed, err
+			}
+			updated++
+		}
+	}
+
+	return updated, failed, nil
+}
+
+// measurePhoto reads the file details missing from photo out of its file at
+// diskPath. When only the perceptual hash cannot be computed, the details
+// measured so far are returned along with the error so they can still be
+// saved.
+func measurePhoto(photo storage.Photo, diskPath string) (storage.PhotoUpdate, error) {
+	var input storage.PhotoUpdate
+
+	if photo.SHA256 == "" {
+		info, err := media.Inspect(diskPath)
+		if err != nil {
+			return input, err
+		}
+		input.Width = &info.Width
+		input.Height = &info.Height
+		input.SizeBytes = &info.Size
+		input.MimeType = &info.MIMEType
+		input.SHA256 = &info.SHA256
+	}
+
+	if photo.PerceptualHash == "" {
+		phash, err := media.ComputePerceptualHash(diskPath)
+		if err != nil {
+			return input, fmt.Errorf("perceptual hash: %w", err)
+		}
+		value := phash.String()
+		input.PerceptualHash = &value
+	}
+
+	return input, nil
+}
+
+// fetchBlob copies the blob stored under key to a temporary file for the
+// media functions, which work on paths. cleanup removes the copy.
+func fetchBlob(ctx context.Context, blobs blob.Store, key string) (diskPath string, cleanup func(), err error) {
+	rc, err := blobs.Get(ctx, key)
+	if err != nil {
+		return "", nil, err
+	}
+	defer rc.Close()
+
+	tmp, err := os.CreateTemp("", "memories-photo-*"+path.Ext(key))
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup = func() { _ = os.Remove(tmp.Name()) }
+	if _, err := io.Copy(tmp, rc); err != nil {
+		_ = tmp.Close()
+		cleanup()
+		return "", nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return tmp.Name(), cleanup, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image/color"
+	"path/filepath"
+	"testing"
+
+	"github.com/Oxyrus/memories/internal/blob"
+	"github.com/Oxyrus/memories/internal/storage"
+)
+
+func TestBackfillPhotos(t *testing.T) {
+	ctx := context.Background()
+	store := openTestStore(t, filepath.Join(t.TempDir(), "memories.db"))
+	blobs, err := blob.NewLocal(t.TempDir(), "/uploads")
+	if err != nil {
+		t.Fatalf("open blob store: %v", err)
+	}
+	album, err := store.Albums().Create(ctx, storage.AlbumCreate{Slug: "summer", Title: "Summer"})
+	if err != nil {
+		t.Fatalf("create album: %v", err)
+	}
+
+	photo := testJPEG(t, color.RGBA{R: 200, A: 255})
+	files := map[string][]byte{
+		"summer/beach.jpg": photo,
+		// The header is intact, so the file can be measured but not decoded.
+		"summer/truncated.jpg": photo[:len(photo)-10],
+	}
+	ids := make(map[string]int64)
+	for _, key := range []string{"summer/beach.jpg", "summer/truncated.jpg", "summer/missing.jpg"} {
+		if content, ok := files[key]; ok {
+			if err := blobs.Put(ctx, key, bytes.NewReader(content)); err != nil {
+				t.Fatalf("put blob: %v", err)
+			}
+		}
+		created, err := store.Photos().Create(ctx, storage.PhotoCreate{AlbumID: album.ID, Filename: key})
+		if err != nil {
+			t.Fatalf("create photo: %v", err)
+		}
+		ids[key] = created.ID
+	}
+
+	updated, failed, err := backfillPhotos(ctx, store, blobs)
+	if err != nil {
+		t.Fatalf("backfillPhotos returned error: %v", err)
+	}
+	if updated != 2 || failed != 2 {
+		t.Fatalf("expected 2 updated and 2 failed, got %d and %d", updated, failed)
+	}
+
+	beach, err := store.Photos().GetByID(ctx, ids["summer/beach.jpg"])
+	if err != nil {
+		t.Fatalf("get photo: %v", err)
+	}
+	if beach.SHA256 == "" || beach.PerceptualHash == "" || beach.Width != 16 || beach.MimeType != "image/jpeg" {
+		t.Fatalf("expected the photo to be measured, got %+v", beach)
+	}
+
+	truncated, err := store.Photos().GetByID(ctx, ids["summer/truncated.jpg"])
+	if err != nil {
+		t.Fatalf("get photo: %v", err)
+	}
+	if truncated.SHA256 == "" || truncated.SizeBytes != int64(len(photo)-10) || truncated.PerceptualHash != "" {
+		t.Fatalf("expected the file details without a perceptual hash, got %+v", truncated)
+	}
+
+	// Re-running only retries what is still missing.
+	updated, failed, err = backfillPhotos(ctx, store, blobs)
+	if err != nil || updated != 0 || failed != 2 {
+		t.Fatalf("expected nothing new on the second run, got %d updated, %d failed (%v)", updated, failed, err)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/Oxyrus/memories/internal/config"
+	"github.com/Oxyrus/memories/internal/ingest"
+	"github.com/Oxyrus/memories/internal/logging"
+	"github.com/Oxyrus/memories/internal/router"
+	"github.com/Oxyrus/memories/internal/storage/sqlite"
+	"github.com/Oxyrus/memories/internal/tus"
+	"github.com/Oxyrus/memories/internal/watch"
+)
+
+func runServe() int {
+	bootstrapLogger := logging.New(slog.LevelInfo)
+
+	cfg, err := config.Load()
+	if err != nil {
+		bootstrapLogger.Error("failed to load config", "error", err)
+		return 1
+	}
+
+	logger := logging.New(cfg.LogLevel)
+
+	store, err := sqlite.Open(cfg.DBPath)
+	if err != nil {
+		logger.Error("failed to open sqlite database", "path", cfg.DBPath, "error", err)
+		return 1
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			logger.Error("failed to close sqlite database", "error", err)
+		}
+	}()
+
+	blobs, err := openBlobs(cfg)
+	if err != nil {
+		logger.Error("failed to open photo storage", "storage", cfg.Storage, "error", err)
+		return 1
+	}
+
+	pipeline, err := newPipeline(cfg, logger, store, blobs)
+	if err != nil {
+		logger.Error("failed to set up photo pipeline", "error", err)
+		return 1
+	}
+	go collectBlobs(logger, pipeline, time.Hour)
+
+	staging, err := tus.NewStore(cfg.StagingDir, cfg.UploadExpiry)
+	if err != nil {
+		logger.Error("failed to open upload staging directory", "path", cfg.StagingDir, "error", err)
+		return 1
+	}
+	go sweepStagedUploads(logger, staging, time.Hour)
+
+	if cfg.WatchDir != "" {
+		if err := os.MkdirAll(cfg.WatchDir, 0o755); err != nil {
+			logger.Error("failed to ensure watch directory", "path", cfg.WatchDir, "error", err)
+			return 1
+		}
+		watcher := watch.New(logger, cfg.WatchDir, cfg.WatchSettle, store.Albums(), store.WatchFailures(), pipeline)
+		logger.Info("watching folder for new photos", "path", cfg.WatchDir, "interval", cfg.WatchInterval)
+		go watcher.Run(context.Background(), cfg.WatchInterval)
+	}
+
+	logger.Info("starting server", "addr", cfg.Addr)
+
+	r := router.New(cfg, logger, store, blobs, pipeline, staging)
+
+	if err := r.Run(cfg.Addr); err != nil {
+		logger.Error("server stopped", "error", err)
+		return 1
+	}
+
+	return 0
+}
+
+// sweepStagedUploads discards abandoned resumable uploads at startup and then
+// every interval.
+func sweepStagedUploads(logger *slog.Logger, staging *tus.Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := staging.Sweep()
+		if err != nil {
+			logger.Error("failed to sweep expired uploads", "error", err)
+		} else if removed > 0 {
+			logger.Info("removed expired uploads", "count", removed)
+		}
+		<-ticker.C
+	}
+}
+
+// collectBlobs deletes content-addressed files that no photo references any
+// more at startup and then every interval.
+func collectBlobs(logger *slog.Logger, pipeline *ingest.Pipeline, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := pipeline.Collect(context.Background(), time.Now().Add(-blobGracePeriod))
+		if err != nil {
+			logger.Error("failed to collect unreferenced photo files", "error", err)
+		} else if removed > 0 {
+			logger.Info("removed unreferenced photo files", "count", removed)
+		}
+		<-ticker.C
+	}
+}
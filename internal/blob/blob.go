@@ -0,0 +1,120 @@
+// Package blob stores the files behind photos: sanitized originals and their
+// resized variants. Files are addressed by slash-separated keys, the same
+// relative paths recorded in photos.filename (for example
+// "summer-roadtrip/20240702183000-ab12.jpg").
+package blob
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound indicates that no blob is stored under a key.
+	ErrNotFound = errors.New("blob: not found")
+	// ErrChecksumMismatch indicates that a copied blob does not read back
+	// with the content that was written.
+	ErrChecksumMismatch = errors.New("blob: checksum mismatch")
+)
+
+// Info describes a stored blob.
+type Info struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// Store keeps blobs. Implementations must be safe for concurrent use.
+type Store interface {
+	// Put stores the contents of r under key, replacing any existing blob.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Get opens the blob stored under key. Local blobs also implement
+	// io.Seeker so they can be served with range support.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob stored under key. Deleting a missing blob is
+	// not an error.
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (Info, error)
+	// List returns every blob whose key starts with prefix, ordered by key.
+	List(ctx context.Context, prefix string) ([]Info, error)
+	// URL returns the address browsers use to fetch the blob.
+	URL(key string) string
+}
+
+// CleanKey normalises key and rejects keys that are empty or would escape the
+// store, such as "../secret" or "/etc/passwd".
+func CleanKey(key string) (string, error) {
+	key = strings.ReplaceAll(key, "\\", "/")
+	if key == "" || strings.HasPrefix(key, "/") {
+		return "", fmt.Errorf("blob: invalid key %q", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("blob: invalid key %q", key)
+		}
+	}
+	clean := path.Clean(key)
+	if clean == "." {
+		return "", fmt.Errorf("blob: invalid key %q", key)
+	}
+	return clean, nil
+}
+
+// PutFile stores the file at src under key.
+func PutFile(ctx context.Context, store Store, key, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return store.Put(ctx, key, f)
+}
+
+// Copy streams the blob stored under key from src to dst, then reads it back
+// from dst to check that it arrived intact. It returns the hex SHA-256 of the
+// content.
+func Copy(ctx context.Context, dst, src Store, key string) (string, error) {
+	rc, err := src.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	err = dst.Put(ctx, key, io.TeeReader(rc, hash))
+	_ = rc.Close()
+	if err != nil {
+		return "", err
+	}
+
+	want := hex.EncodeToString(hash.Sum(nil))
+	got, err := Checksum(ctx, dst, key)
+	if err != nil {
+		return "", err
+	}
+	if got != want {
+		return "", fmt.Errorf("%w: %s was copied as %s but reads back as %s", ErrChecksumMismatch, key, want, got)
+	}
+	return want, nil
+}
+
+// Checksum returns the hex SHA-256 of the blob stored under key.
+func Checksum(ctx context.Context, store Store, key string) (string, error) {
+	rc, err := store.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, rc); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
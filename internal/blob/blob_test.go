@@ -0,0 +1,63 @@
+package blob_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Oxyrus/memories/internal/blob"
+)
+
+func TestCopy(t *testing.T) {
+	ctx := context.Background()
+	src := newLocal(t)
+	dst := newLocal(t)
+	if err := src.Put(ctx, "summer/beach.jpg", strings.NewReader("beach")); err != nil {
+		t.Fatalf("Put returned error: %v", err)
+	}
+
+	sum, err := blob.Copy(ctx, dst, src, "summer/beach.jpg")
+	if err != nil {
+		t.Fatalf("Copy returned error: %v", err)
+	}
+	want := sha256.Sum256([]byte("beach"))
+	if sum != hex.EncodeToString(want[:]) {
+		t.Fatalf("unexpected checksum %s", sum)
+	}
+	if got, err := blob.Checksum(ctx, dst, "summer/beach.jpg"); err != nil || got != sum {
+		t.Fatalf("expected the copy to have checksum %s, got %s (%v)", sum, got, err)
+	}
+
+	if _, err := blob.Copy(ctx, dst, src, "summer/dunes.jpg"); !errors.Is(err, blob.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a missing blob, got %v", err)
+	}
+	if _, err := blob.Copy(ctx, corruptingStore{dst}, src, "summer/beach.jpg"); !errors.Is(err, blob.ErrChecksumMismatch) {
+		t.Fatalf("expected ErrChecksumMismatch for a damaged copy, got %v", err)
+	}
+}
+
+func newLocal(t *testing.T) *blob.Local {
+	t.Helper()
+	store, err := blob.NewLocal(t.TempDir(), "/uploads")
+	if err != nil {
+		t.Fatalf("NewLocal returned error: %v", err)
+	}
+	return store
+}
+
+// corruptingStore drops the last byte of every blob it stores.
+type corruptingStore struct {
+	blob.Store
+}
+
+func (s corruptingStore) Put(ctx context.Context, key string, r io.Reader) error {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return s.Store.Put(ctx, key, strings.NewReader(string(content[:len(content)-1])))
+}
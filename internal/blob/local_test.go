@@ -0,0 +1,109 @@
+package blob_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Oxyrus/memories/internal/blob"
+)
+
+func TestLocalLifecycle(t *testing.T) {
+	dir := t.TempDir()
+	store, err := blob.NewLocal(dir, "/uploads/")
+	if err != nil {
+		t.Fatalf("NewLocal returned error: %v", err)
+	}
+	ctx := context.Background()
+
+	for key, content := range map[string]string{
+		"summer/beach.jpg":       "beach",
+		"summer/beach_thumb.jpg": "thumb",
+		"summit/peak.jpg":        "peak",
+	} {
+		if err := store.Put(ctx, key, strings.NewReader(content)); err != nil {
+			t.Fatalf("Put %s returned error: %v", key, err)
+		}
+	}
+	if err := store.Put(ctx, "summer/beach.jpg", strings.NewReader("sandy beach")); err != nil {
+		t.Fatalf("Put replacing a blob returned error: %v", err)
+	}
+
+	rc, err := store.Get(ctx, "summer/beach.jpg")
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	content, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil || string(content) != "sandy beach" {
+		t.Fatalf("expected replaced content, got %q (%v)", content, err)
+	}
+	if _, ok := rc.(io.Seeker); !ok {
+		t.Fatal("expected local blobs to be seekable")
+	}
+
+	info, err := store.Stat(ctx, "summer/beach.jpg")
+	if err != nil {
+		t.Fatalf("Stat returned error: %v", err)
+	}
+	if info.Key != "summer/beach.jpg" || info.Size != int64(len("sandy beach")) {
+		t.Fatalf("unexpected info %+v", info)
+	}
+
+	infos, err := store.List(ctx, "summer/")
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if len(infos) != 2 || infos[0].Key != "summer/beach.jpg" || infos[1].Key != "summer/beach_thumb.jpg" {
+		t.Fatalf("unexpected listing %+v", infos)
+	}
+	if infos, err := store.List(ctx, "sum"); err != nil || len(infos) != 3 {
+		t.Fatalf("expected a partial prefix to match all blobs, got %+v (%v)", infos, err)
+	}
+	if infos, err := store.List(ctx, "winter/"); err != nil || len(infos) != 0 {
+		t.Fatalf("expected no blobs for a missing prefix, got %+v (%v)", infos, err)
+	}
+
+	if got := store.URL("summer/beach.jpg"); got != "/uploads/summer/beach.jpg" {
+		t.Fatalf("unexpected URL %q", got)
+	}
+
+	for _, key := range []string{"summer/beach.jpg", "summer/beach_thumb.jpg", "summer/missing.jpg"} {
+		if err := store.Delete(ctx, key); err != nil {
+			t.Fatalf("Delete %s returned error: %v", key, err)
+		}
+	}
+	if _, err := store.Stat(ctx, "summer/beach.jpg"); !errors.Is(err, blob.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+	if _, err := store.Get(ctx, "summer/beach.jpg"); !errors.Is(err, blob.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "summer")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the emptied directory to be removed, got %v", err)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Fatalf("expected the store directory to remain: %v", err)
+	}
+}
+
+func TestLocalRejectsUnsafeKeys(t *testing.T) {
+	store, err := blob.NewLocal(t.TempDir(), "/uploads")
+	if err != nil {
+		t.Fatalf("NewLocal returned error: %v", err)
+	}
+	ctx := context.Background()
+
+	for _, key := range []string{"", "../escape.jpg", "summer/../../escape.jpg", "/etc/passwd", `..\escape.jpg`, "."} {
+		if err := store.Put(ctx, key, strings.NewReader("x")); err == nil {
+			t.Fatalf("expected Put %q to be rejected", key)
+		}
+		if err := store.Delete(ctx, key); err == nil {
+			t.Fatalf("expected Delete %q to be rejected", key)
+		}
+	}
+}
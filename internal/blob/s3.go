@@ -0,0 +1,554 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPartSize is the size of the parts large blobs are uploaded in.
+	DefaultPartSize = 16 << 20
+	// MinPartSize is the smallest part S3 accepts for all but the last part
+	// of a multipart upload.
+	MinPartSize = 5 << 20
+
+	s3Algorithm     = "AWS4-HMAC-SHA256"
+	s3Service       = "s3"
+	s3TimeFormat    = "20060102T150405Z"
+	s3DateFormat    = "20060102"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	maxURLExpiry    = 7 * 24 * time.Hour
+)
+
+var emptyPayloadHash = hex.EncodeToString(sha256.New().Sum(nil))
+
+// S3Config describes a bucket in S3 or an S3-compatible service such as
+// MinIO.
+type S3Config struct {
+	// Endpoint is the service's base URL, for example "http://minio:9000".
+	// It defaults to AWS's endpoint for Region.
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// Prefix is prepended to every key, so several sites can share a bucket.
+	Prefix string
+	// PathStyle addresses the bucket as endpoint/bucket instead of
+	// bucket.endpoint. Most self-hosted services need it.
+	PathStyle bool
+	// URLExpiry is how long the presigned URLs returned by URL stay valid.
+	URLExpiry time.Duration
+	// PartSize is the size of multipart upload parts. Blobs smaller than one
+	// part are uploaded with a single request.
+	PartSize int64
+	// Client sends the requests; http.DefaultClient is used when nil.
+	Client *http.Client
+}
+
+// S3 stores blobs as objects in an S3 bucket. Requests are signed with AWS
+// Signature Version 4 and browsers fetch blobs through presigned URLs.
+type S3 struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	prefix    string
+	pathStyle bool
+	urlExpiry time.Duration
+	partSize  int64
+	client    *http.Client
+	now       func() time.Time
+}
+
+// NewS3 returns a Store for the bucket described by cfg. It does not contact
+// the service.
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("blob: s3 bucket must be set")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("blob: s3 access key and secret must be set")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+	endpoint, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("blob: invalid s3 endpoint %q", cfg.Endpoint)
+	}
+	if cfg.URLExpiry <= 0 {
+		cfg.URLExpiry = time.Hour
+	}
+	if cfg.URLExpiry > maxURLExpiry {
+		cfg.URLExpiry = maxURLExpiry
+	}
+	if cfg.PartSize == 0 {
+		cfg.PartSize = DefaultPartSize
+	}
+	if cfg.PartSize < MinPartSize {
+		return nil, fmt.Errorf("blob: s3 part size must be at least %d bytes", MinPartSize)
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+
+	prefix := strings.Trim(cfg.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	return &S3{
+		endpoint:  endpoint,
+		region:    cfg.Region,
+		bucket:    cfg.Bucket,
+		accessKey: cfg.AccessKeyID,
+		secretKey: cfg.SecretAccessKey,
+		prefix:    prefix,
+		pathStyle: cfg.PathStyle,
+		urlExpiry: cfg.URLExpiry,
+		partSize:  cfg.PartSize,
+		client:    cfg.Client,
+		now:       time.Now,
+	}, nil
+}
+
+// Put uploads r with a single request when it fits in one part and as a
+// multipart upload otherwise, so large blobs are never held in memory whole.
+// The buffer grows with what is read, so small blobs such as thumbnails do
+// not cost a full part.
+func (s *S3) Put(ctx context.Context, key string, r io.Reader) error {
+	object, err := s.objectKey(key)
+	if err != nil {
+		return err
+	}
+
+	var first bytes.Buffer
+	_, err = io.CopyN(&first, r, s.partSize)
+	if errors.Is(err, io.EOF) {
+		resp, err := s.do(ctx, http.MethodPut, object, nil, contentHeaders(key), first.Bytes())
+		if err != nil {
+			return err
+		}
+		return drain(resp)
+	}
+	if err != nil {
+		return err
+	}
+	return s.putMultipart(ctx, key, object, r, first.Bytes())
+}
+
+type initiateMultipartUploadResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+// putMultipart uploads first, which holds a full part, and the rest of r as
+// the parts of a multipart upload. first is reused to read the later parts.
+// The upload is aborted if any step fails, so the bucket does not keep paying
+// for orphaned parts.
+func (s *S3) putMultipart(ctx context.Context, key, object string, r io.Reader, first []byte) (err error) {
+	resp, err := s.do(ctx, http.MethodPost, object, url.Values{"uploads": {""}}, contentHeaders(key), nil)
+	if err != nil {
+		return err
+	}
+	var initiated initiateMultipartUploadResult
+	if err := decodeXML(resp, &initiated); err != nil {
+		return err
+	}
+	uploadID := url.Values{"uploadId": {initiated.UploadID}}
+	defer func() {
+		if err == nil {
+			return
+		}
+		// The request's context may be the reason for the failure.
+		if resp, abortErr := s.do(context.Background(), http.MethodDelete, object, uploadID, nil, nil); abortErr == nil {
+			_ = drain(resp)
+		}
+	}()
+
+	var parts []completedPart
+	part := first
+	for number := 1; ; number++ {
+		query := url.Values{
+			"partNumber": {strconv.Itoa(number)},
+			"uploadId":   {initiated.UploadID},
+		}
+		resp, err := s.do(ctx, http.MethodPut, object, query, nil, part)
+		if err != nil {
+			return err
+		}
+		etag := resp.Header.Get("ETag")
+		if err := drain(resp); err != nil {
+			return err
+		}
+		parts = append(parts, completedPart{PartNumber: number, ETag: etag})
+
+		n, err := io.ReadFull(r, first)
+		if n == 0 && (errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)) {
+			break
+		}
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return err
+		}
+		part = first[:n]
+	}
+
+	body, err := xml.Marshal(completeMultipartUpload{Parts: parts})
+	if err != nil {
+		return err
+	}
+	resp, err = s.do(ctx, http.MethodPost, object, uploadID, nil, body)
+	if err != nil {
+		return err
+	}
+	// S3 reports some failures of a completed upload in a 200 response.
+	var result struct {
+		XMLName xml.Name
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if err := decodeXML(resp, &result); err != nil {
+		return err
+	}
+	if result.XMLName.Local == "Error" {
+		return fmt.Errorf("blob: s3 complete upload of %s: %s: %s", object, result.Code, result.Message)
+	}
+	return nil
+}
+
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	object, err := s.objectKey(key)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, http.MethodGet, object, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	object, err := s.objectKey(key)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodDelete, object, nil, nil, nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return drain(resp)
+}
+
+func (s *S3) Stat(ctx context.Context, key string) (Info, error) {
+	object, err := s.objectKey(key)
+	if err != nil {
+		return Info{}, err
+	}
+	resp, err := s.do(ctx, http.MethodHead, object, nil, nil, nil)
+	if err != nil {
+		return Info{}, err
+	}
+	defer drain(resp)
+
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return Info{
+		Key:     strings.TrimPrefix(object, s.prefix),
+		Size:    resp.ContentLength,
+		ModTime: modTime,
+	}, nil
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List pages through ListObjectsV2 until every matching object is listed.
+func (s *S3) List(ctx context.Context, prefix string) ([]Info, error) {
+	query := url.Values{
+		"list-type": {"2"},
+		"prefix":    {s.prefix + strings.ReplaceAll(prefix, "\\", "/")},
+	}
+
+	var infos []Info
+	for {
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page listBucketResult
+		if err := decodeXML(resp, &page); err != nil {
+			return nil, err
+		}
+		for _, object := range page.Contents {
+			infos = append(infos, Info{
+				Key:     strings.TrimPrefix(object.Key, s.prefix),
+				Size:    object.Size,
+				ModTime: object.LastModified,
+			})
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			break
+		}
+		query.Set("continuation-token", page.NextContinuationToken)
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Key < infos[j].Key })
+	return infos, nil
+}
+
+// URL returns a presigned GET URL for the blob. The signing time is rounded
+// down to half the expiry, so a page rendered twice within that window links
+// to the same URL and browsers can reuse their cached copy.
+func (s *S3) URL(key string) string {
+	object, err := s.objectKey(key)
+	if err != nil {
+		return ""
+	}
+	signedAt := s.now().UTC().Truncate(s.urlExpiry / 2)
+	u := s.objectURL(object)
+	query := url.Values{
+		"X-Amz-Algorithm":     {s3Algorithm},
+		"X-Amz-Credential":    {s.accessKey + "/" + s.scope(signedAt)},
+		"X-Amz-Date":          {signedAt.Format(s3TimeFormat)},
+		"X-Amz-Expires":       {strconv.Itoa(int(s.urlExpiry / time.Second))},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	u.RawQuery = canonicalQuery(query)
+	signature := s.signature(signedAt, canonicalRequest(http.MethodGet, u, http.Header{"Host": {u.Host}}, []string{"host"}, unsignedPayload))
+	u.RawQuery += "&X-Amz-Signature=" + signature
+	return u.String()
+}
+
+// objectKey maps a blob key to the object that stores it.
+func (s *S3) objectKey(key string) (string, error) {
+	clean, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return s.prefix + clean, nil
+}
+
+// objectURL returns the URL of an object, or of the bucket when object is
+// empty, with the path already escaped for signing.
+func (s *S3) objectURL(object string) *url.URL {
+	u := *s.endpoint
+	escaped := strings.TrimRight(u.EscapedPath(), "/")
+	if s.pathStyle {
+		escaped += "/" + uriEncode(s.bucket, false)
+	} else {
+		u.Host = s.bucket + "." + u.Host
+	}
+	escaped += "/" + uriEncode(object, true)
+
+	u.RawPath = escaped
+	u.Path, _ = url.PathUnescape(escaped)
+	return &u
+}
+
+// do sends a signed request and returns the response when it succeeded. A
+// 404 is reported as ErrNotFound and other failures as errors naming S3's
+// error code.
+func (s *S3) do(ctx context.Context, method, object string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	u := s.objectURL(object)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	if len(body) == 0 {
+		req.Body = http.NoBody
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	s.sign(req, body)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer drain(resp)
+
+	if resp.StatusCode == http.StatusNotFound && object != "" {
+		return nil, ErrNotFound
+	}
+	var failure struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	_ = xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&failure)
+	if failure.Code == "" {
+		failure.Code = resp.Status
+	}
+	target := s.bucket
+	if object != "" {
+		target += "/" + object
+	}
+	return nil, fmt.Errorf("blob: s3 %s %s: %s %s", method, target, failure.Code, failure.Message)
+}
+
+// sign adds the headers and Authorization of a Signature Version 4 request.
+func (s *S3) sign(req *http.Request, body []byte) {
+	now := s.now().UTC()
+	payloadHash := emptyPayloadHash
+	if len(body) > 0 {
+		sum := sha256.Sum256(body)
+		payloadHash = hex.EncodeToString(sum[:])
+	}
+	req.Header.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signed := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	signature := s.signature(now, canonicalRequest(req.Method, req.URL, req.Header, signed, payloadHash))
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.accessKey, s.scope(now), strings.Join(signed, ";"), signature))
+}
+
+func (s *S3) scope(t time.Time) string {
+	return t.Format(s3DateFormat) + "/" + s.region + "/" + s3Service + "/aws4_request"
+}
+
+func (s *S3) signature(t time.Time, canonical string) string {
+	hash := sha256.Sum256([]byte(canonical))
+	stringToSign := s3Algorithm + "\n" + t.Format(s3TimeFormat) + "\n" + s.scope(t) + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), t.Format(s3DateFormat))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, s3Service)
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// canonicalRequest builds the request description that Signature Version 4
+// signs. signed lists the lower-case names of the signed headers, sorted; the
+// host header is taken from u.
+func canonicalRequest(method string, u *url.URL, header http.Header, signed []string, payloadHash string) string {
+	var headers strings.Builder
+	for _, name := range signed {
+		value := strings.Join(header.Values(name), ",")
+		if name == "host" {
+			value = u.Host
+		}
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	return strings.Join([]string{
+		method,
+		u.EscapedPath(),
+		u.RawQuery,
+		headers.String(),
+		strings.Join(signed, ";"),
+		payloadHash,
+	}, "\n")
+}
+
+// canonicalQuery encodes query sorted by name, escaping names and values the
+// way Signature Version 4 expects.
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var pairs []string
+	for _, name := range names {
+		values := append([]string(nil), query[name]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(name, false)+"="+uriEncode(value, false))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode escapes every byte except unreserved characters, and slashes
+// when keepSlash is set.
+func uriEncode(s string, keepSlash bool) string {
+	const hexDigits = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && keepSlash:
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&0xf])
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// contentHeaders sets the Content-Type S3 serves the blob with.
+func contentHeaders(key string) http.Header {
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		return nil
+	}
+	return http.Header{"Content-Type": {contentType}}
+}
+
+func decodeXML(resp *http.Response, v any) error {
+	defer drain(resp)
+	return xml.NewDecoder(resp.Body).Decode(v)
+}
+
+// drain reads the rest of a response body so the connection can be reused.
+func drain(resp *http.Response) error {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.Body.Close()
+}
+
+var _ Store = (*S3)(nil)
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -14,27 +16,71 @@ type Config struct {
 	AdminPassword string
 	DBPath        string
 	UploadsDir    string
+	Storage       string
+	StorageLayout string
+	S3Endpoint    string
+	S3Region      string
+	S3Bucket      string
+	S3AccessKey   string
+	S3SecretKey   string
+	S3Prefix      string
+	S3PathStyle   bool
+	S3URLExpiry   time.Duration
+	StagingDir    string
+	UploadExpiry  time.Duration
+	WatchDir      string
+	WatchInterval time.Duration
+	WatchSettle   time.Duration
 	LogLevel      slog.Level
 	AdminCookie   string
+	SessionTTL    time.Duration
 }
 
+// Load reads the server configuration from the environment (and an optional
+// .env file). ADMIN_PASSWORD is required.
 func Load() (*Config, error) {
+	cfg := load()
+
+	if cfg.AdminPassword == "" {
+		return nil, fmt.Errorf("ADMIN_PASSWORD must be set")
+	}
+
+	return cfg, nil
+}
+
+// LoadCommand reads the same configuration as Load for offline commands such as
+// migrate. Those never serve the admin UI, so ADMIN_PASSWORD is optional.
+func LoadCommand() *Config {
+	return load()
+}
+
+func load() *Config {
 	_ = godotenv.Load()
 
-	cfg := &Config{
+	return &Config{
 		Addr:          getString("MEMORIES_ADDR", ":8080"),
 		AdminPassword: strings.TrimSpace(os.Getenv("ADMIN_PASSWORD")),
 		DBPath:        getString("MEMORIES_DB_PATH", "data/memories.db"),
 		UploadsDir:    getString("MEMORIES_UPLOADS_PATH", "public/uploads"),
+		Storage:       strings.ToLower(getString("MEMORIES_STORAGE", "local")),
+		StorageLayout: strings.ToLower(getString("MEMORIES_STORAGE_LAYOUT", "album")),
+		S3Endpoint:    strings.TrimSpace(os.Getenv("MEMORIES_S3_ENDPOINT")),
+		S3Region:      getString("MEMORIES_S3_REGION", "us-east-1"),
+		S3Bucket:      strings.TrimSpace(os.Getenv("MEMORIES_S3_BUCKET")),
+		S3AccessKey:   strings.TrimSpace(os.Getenv("MEMORIES_S3_ACCESS_KEY_ID")),
+		S3SecretKey:   strings.TrimSpace(os.Getenv("MEMORIES_S3_SECRET_ACCESS_KEY")),
+		S3Prefix:      strings.TrimSpace(os.Getenv("MEMORIES_S3_PREFIX")),
+		S3PathStyle:   getBool("MEMORIES_S3_PATH_STYLE", false),
+		S3URLExpiry:   getDuration("MEMORIES_S3_URL_EXPIRY", time.Hour),
+		StagingDir:    getString("MEMORIES_STAGING_PATH", "data/staging"),
+		UploadExpiry:  getDuration("MEMORIES_UPLOAD_EXPIRY", 24*time.Hour),
+		WatchDir:      strings.TrimSpace(os.Getenv("MEMORIES_WATCH_PATH")),
+		WatchInterval: getDuration("MEMORIES_WATCH_INTERVAL", time.Minute),
+		WatchSettle:   getDuration("MEMORIES_WATCH_SETTLE", 30*time.Second),
 		LogLevel:      getLogLevel("MEMORIES_LOG_LEVEL", slog.LevelInfo),
 		AdminCookie:   getString("MEMORIES_ADMIN_COOKIE", "memories_admin"),
+		SessionTTL:    getDuration("MEMORIES_SESSION_TTL", 14*24*time.Hour),
 	}
-
-	if cfg.AdminPassword == "" {
-		return nil, fmt.Errorf("ADMIN_PASSWORD must be set")
-	}
-
-	return cfg, nil
 }
 
 func getString(key, fallback string) string {
@@ -44,6 +90,26 @@ func getString(key, fallback string) string {
 	return fallback
 }
 
+func getDuration(key string, fallback time.Duration) time.Duration {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		return fallback
+	}
+	return parsed
+}
+
+func getBool(key string, fallback bool) bool {
+	parsed, err := strconv.ParseBool(strings.TrimSpace(os.Getenv(key)))
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
+
 func getLogLevel(key string, fallback slog.Level) slog.Level {
 	value := strings.TrimSpace(strings.ToLower(os.Getenv(key)))
 	switch value {
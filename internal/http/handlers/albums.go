@@ -1,49 +1,62 @@
 package handlers
 
 import (
-	"bytes"
-	"crypto/rand"
-	"encoding/hex"
+	"context"
 	"errors"
 	"fmt"
-	"image"
-	"image/jpeg"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
-	"os"
 	"path"
-	"path/filepath"
-	"regexp"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
 
-	"github.com/disintegration/imaging"
 	"github.com/gin-gonic/gin"
-	"github.com/rwcarlsen/goexif/exif"
 
+	"github.com/Oxyrus/memories/internal/blob"
 	"github.com/Oxyrus/memories/internal/http/render"
+	"github.com/Oxyrus/memories/internal/ingest"
+	"github.com/Oxyrus/memories/internal/media"
 	"github.com/Oxyrus/memories/internal/storage"
 	"github.com/Oxyrus/memories/web/pages"
 )
 
 type AlbumHandler struct {
-	logger     *slog.Logger
-	albums     storage.Albums
-	photos     storage.Photos
-	uploadsDir string
+	logger        *slog.Logger
+	albums        storage.Albums
+	photos        storage.Photos
+	watchFailures storage.WatchFailures
+	blobs         blob.Store
+	ingest        *ingest.Pipeline
 }
 
-var slugPattern = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
-
 const formDateTimeLayout = "2006-01-02T15:04"
 
-func NewAlbumHandler(logger *slog.Logger, albums storage.Albums, photos storage.Photos, uploadsDir string) *AlbumHandler {
+// The similar photos view groups photos whose perceptual hashes differ in at
+// most this many of their 64 bits by default. Admins may raise it up to
+// maxSimilarDistance; beyond that unrelated photos start to match.
+const (
+	defaultSimilarDistance = 10
+	maxSimilarDistance     = 24
+)
+
+var photoSortOptions = []pages.SelectOption{
+	{Value: string(storage.PhotoSortTakenAt), Label: "Date taken"},
+	{Value: string(storage.PhotoSortUploaded), Label: "Upload date"},
+	{Value: string(storage.PhotoSortFilename), Label: "File name"},
+	{Value: string(storage.PhotoSortManual), Label: "Manual (drag to reorder)"},
+}
+
+func NewAlbumHandler(logger *slog.Logger, albums storage.Albums, photos storage.Photos, watchFailures storage.WatchFailures, blobs blob.Store, pipeline *ingest.Pipeline) *AlbumHandler {
 	return &AlbumHandler{
-		logger:     logger,
-		albums:     albums,
-		photos:     photos,
-		uploadsDir: uploadsDir,
+		logger:        logger,
+		albums:        albums,
+		photos:        photos,
+		watchFailures: watchFailures,
+		blobs:         blobs,
+		ingest:        pipeline,
 	}
 }
 
@@ -57,12 +70,28 @@ func (h *AlbumHandler) List(c *gin.Context) {
 		return
 	}
 
+	covers, err := h.photos.ListCovers(ctx)
+	if err != nil {
+		// The albums are still useful without their covers.
+		h.logger.Warn("failed to load album covers", "error", err)
+	}
+
 	items := make([]pages.AlbumListItem, 0, len(albums))
 	for _, album := range albums {
-		items = append(items, toAlbumListItem(album))
+		item := toAlbumListItem(album)
+		if cover, ok := covers[album.ID]; ok {
+			item.CoverURL = h.blobs.URL(cover.Filename)
+		}
+		items = append(items, item)
+	}
+
+	failures, err := h.watchFailures.List(ctx)
+	if err != nil {
+		// The albums are still useful without the watch folder report.
+		h.logger.Warn("failed to list watch folder failures", "error", err)
 	}
 
-	render.HTML(c, http.StatusOK, pages.AlbumsList(items))
+	render.HTML(c, http.StatusOK, pages.AlbumsList(items, toWatchFailureItems(failures)))
 }
 
 func (h *AlbumHandler) New(c *gin.Context) {
@@ -96,33 +125,47 @@ func (h *AlbumHandler) Edit(c *gin.Context) {
 		return
 	}
 
-	photoRecords, err := h.photos.ListByAlbum(ctx, album.ID)
+	h.renderEdit(c, album, http.StatusOK, map[string]string{}, pages.UploadReport{})
+}
+
+// renderEdit renders the edit page for album with its photos. Errors keyed by
+// form field are shown next to the matching inputs.
+func (h *AlbumHandler) renderEdit(c *gin.Context, album storage.Album, status int, formErrors map[string]string, uploads pages.UploadReport) {
+	photoRecords, err := h.photos.ListByAlbum(c.Request.Context(), album.ID)
 	if err != nil {
-		h.logger.Error("failed to load album photos", "slug", slug, "error", err)
+		h.logger.Error("failed to load album photos", "slug", album.Slug, "error", err)
 		c.String(http.StatusInternalServerError, "failed to load album photos")
 		return
 	}
 
 	photos := make([]pages.AlbumPhoto, 0, len(photoRecords))
 	for _, photo := range photoRecords {
-		photos = append(photos, toAlbumPhoto(photo))
+		item := h.toAlbumPhoto(photo)
+		item.IsCover = album.CoverPhotoID != nil && *album.CoverPhotoID == photo.ID
+		photos = append(photos, item)
 	}
 
 	form := pages.AlbumForm{
-		Heading:      "Edit album",
-		Intro:        "Update the album details below.",
-		Action:       fmt.Sprintf("/albums/%s/edit", album.Slug),
-		SubmitLabel:  "Save changes",
-		Title:        album.Title,
-		Slug:         album.Slug,
-		Description:  album.Description,
-		Errors:       map[string]string{},
-		SlugEditable: false,
-		UploadAction: fmt.Sprintf("/albums/%s/photos", album.Slug),
-		Photos:       photos,
+		Heading:       "Edit album",
+		Intro:         "Update the album details below.",
+		Action:        fmt.Sprintf("/albums/%s/edit", album.Slug),
+		SubmitLabel:   "Save changes",
+		Title:         album.Title,
+		Slug:          album.Slug,
+		Description:   album.Description,
+		Errors:        formErrors,
+		SlugEditable:  false,
+		SortMode:      string(album.SortMode),
+		SortOptions:   photoSortOptions,
+		ShowPhotoInfo: album.ShowPhotoInfo,
+		AllowDownload: album.AllowDownload,
+		UploadAction:  fmt.Sprintf("/albums/%s/photos", album.Slug),
+		ImportAction:  fmt.Sprintf("/albums/%s/import", album.Slug),
+		Uploads:       uploads,
+		Photos:        photos,
 	}
 
-	render.HTML(c, http.StatusOK, pages.AlbumEdit(form))
+	render.HTML(c, status, pages.AlbumEdit(form))
 }
 
 func (h *AlbumHandler) View(c *gin.Context) {
@@ -153,7 +196,7 @@ func (h *AlbumHandler) View(c *gin.Context) {
 
 	viewPhotos := make([]pages.AlbumPhoto, 0, len(photoRecords))
 	for _, photo := range photoRecords {
-		viewPhotos = append(viewPhotos, toAlbumPhoto(photo))
+		viewPhotos = append(viewPhotos, h.toAlbumPhoto(photo))
 	}
 
 	data := pages.AlbumViewData{
@@ -195,19 +238,27 @@ func (h *AlbumHandler) Public(c *gin.Context) {
 
 	photos := make([]pages.AlbumPhoto, 0, len(photoRecords))
 	for _, photo := range photoRecords {
-		photos = append(photos, toAlbumPhoto(photo))
+		photos = append(photos, h.toAlbumPhoto(photo))
 	}
 
 	var hero pages.AlbumPhoto
-	if len(photos) > 0 {
-		hero = photos[0]
+	heroIndex := coverIndex(photoRecords, album.CoverPhotoID)
+	if heroIndex >= 0 {
+		hero = photos[heroIndex]
+	} else {
+		heroIndex = 0
 	}
 
 	data := pages.PublicAlbumViewData{
 		Title:       album.Title,
 		Description: album.Description,
 		Hero:        hero,
+		HeroIndex:   heroIndex,
 		Photos:      photos,
+		ShowInfo:    album.ShowPhotoInfo,
+	}
+	if album.AllowDownload && len(photos) > 0 {
+		data.DownloadURL = fmt.Sprintf("/a/%s/download", album.Slug)
 	}
 
 	render.HTML(c, http.StatusOK, pages.AlbumPublicView(data))
@@ -235,7 +286,7 @@ func (h *AlbumHandler) Create(c *gin.Context) {
 	var slug string
 	if form.Slug != "" {
 		manual := form.Slug
-		if !slugPattern.MatchString(strings.ToLower(manual)) {
+		if !storage.ValidSlug(strings.ToLower(manual)) {
 			form.Errors["slug"] = "Slug may only contain letters, numbers, and hyphens."
 		} else {
 			slug = slugify(manual)
@@ -298,21 +349,29 @@ func (h *AlbumHandler) Update(c *gin.Context) {
 	}
 
 	form := pages.AlbumForm{
-		Heading:      "Edit album",
-		Intro:        "Update the album details below.",
-		Action:       fmt.Sprintf("/albums/%s/edit", current.Slug),
-		SubmitLabel:  "Save changes",
-		Title:        strings.TrimSpace(c.PostForm("title")),
-		Slug:         current.Slug,
-		Description:  strings.TrimSpace(c.PostForm("description")),
-		Errors:       map[string]string{},
-		SlugEditable: false,
+		Heading:       "Edit album",
+		Intro:         "Update the album details below.",
+		Action:        fmt.Sprintf("/albums/%s/edit", current.Slug),
+		SubmitLabel:   "Save changes",
+		Title:         strings.TrimSpace(c.PostForm("title")),
+		Slug:          current.Slug,
+		Description:   strings.TrimSpace(c.PostForm("description")),
+		Errors:        map[string]string{},
+		SlugEditable:  false,
+		SortMode:      strings.TrimSpace(c.PostForm("sort_mode")),
+		SortOptions:   photoSortOptions,
+		ShowPhotoInfo: c.PostForm("show_photo_info") != "",
+		AllowDownload: c.PostForm("allow_download") != "",
 	}
 
 	if form.Title == "" {
 		form.Errors["title"] = "Title is required."
 	}
 
+	if form.SortMode != "" && !storage.PhotoSort(form.SortMode).Valid() {
+		form.Errors["sort_mode"] = "Choose one of the listed photo orders."
+	}
+
 	if len(form.Errors) > 0 {
 		render.HTML(c, http.StatusUnprocessableEntity, pages.AlbumEdit(form))
 		return
@@ -320,9 +379,17 @@ func (h *AlbumHandler) Update(c *gin.Context) {
 
 	title := form.Title
 	description := form.Description
+	showPhotoInfo := form.ShowPhotoInfo
+	allowDownload := form.AllowDownload
 	updateInput := storage.AlbumUpdate{
-		Title:       &title,
-		Description: &description,
+		Title:         &title,
+		Description:   &description,
+		ShowPhotoInfo: &showPhotoInfo,
+		AllowDownload: &allowDownload,
+	}
+	if form.SortMode != "" {
+		sortMode := storage.PhotoSort(form.SortMode)
+		updateInput.SortMode = &sortMode
 	}
 
 	updated, err := h.albums.Update(ctx, current.ID, updateInput)
@@ -341,6 +408,106 @@ func (h *AlbumHandler) Update(c *gin.Context) {
 	c.Redirect(http.StatusSeeOther, fmt.Sprintf("/albums/%s", updated.Slug))
 }
 
+func (h *AlbumHandler) ConfirmDelete(c *gin.Context) {
+	ctx := c.Request.Context()
+	slug := strings.TrimSpace(c.Param("slug"))
+	if slug == "" {
+		c.String(http.StatusNotFound, "album not found")
+		return
+	}
+
+	album, err := h.albums.GetBySlug(ctx, slug)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.String(http.StatusNotFound, "album not found")
+			return
+		}
+		h.logger.Error("failed to load album for delete", "slug", slug, "error", err)
+		c.String(http.StatusInternalServerError, "failed to load album")
+		return
+	}
+
+	photoRecords, err := h.photos.ListByAlbum(ctx, album.ID)
+	if err != nil {
+		h.logger.Error("failed to load album photos", "slug", slug, "error", err)
+		c.String(http.StatusInternalServerError, "failed to load album photos")
+		return
+	}
+
+	render.HTML(c, http.StatusOK, pages.AlbumDelete(pages.AlbumDeleteData{
+		Title:      album.Title,
+		Slug:       album.Slug,
+		PhotoCount: len(photoRecords),
+	}))
+}
+
+func (h *AlbumHandler) Delete(c *gin.Context) {
+	ctx := c.Request.Context()
+	slug := strings.TrimSpace(c.Param("slug"))
+	if slug == "" {
+		c.String(http.StatusNotFound, "album not found")
+		return
+	}
+
+	album, err := h.albums.GetBySlug(ctx, slug)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.String(http.StatusNotFound, "album not found")
+			return
+		}
+		h.logger.Error("failed to load album for delete", "slug", slug, "error", err)
+		c.String(http.StatusInternalServerError, "failed to load album")
+		return
+	}
+
+	if strings.TrimSpace(c.PostForm("confirm")) != album.Slug {
+		photoRecords, err := h.photos.ListByAlbum(ctx, album.ID)
+		if err != nil {
+			h.logger.Error("failed to load album photos", "slug", slug, "error", err)
+			c.String(http.StatusInternalServerError, "failed to load album photos")
+			return
+		}
+		render.HTML(c, http.StatusUnprocessableEntity, pages.AlbumDelete(pages.AlbumDeleteData{
+			Title:      album.Title,
+			Slug:       album.Slug,
+			PhotoCount: len(photoRecords),
+			Error:      "Type the album slug exactly to confirm deletion.",
+		}))
+		return
+	}
+
+	// The photos are loaded first because their files may be stored outside
+	// the album's directory.
+	photoRecords, err := h.photos.ListByAlbum(ctx, album.ID)
+	if err != nil {
+		h.logger.Error("failed to load album photos", "slug", slug, "error", err)
+		c.String(http.StatusInternalServerError, "failed to load album photos")
+		return
+	}
+
+	// Photo rows cascade with the album inside a single statement, so the
+	// database is never left half-deleted. Files are removed afterwards; if
+	// that fails the admin is told which directory needs manual cleanup.
+	if err := h.albums.Delete(ctx, album.ID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.String(http.StatusNotFound, "album not found")
+			return
+		}
+		h.logger.Error("failed to delete album", "albumID", album.ID, "slug", album.Slug, "error", err)
+		c.String(http.StatusInternalServerError, "failed to delete album")
+		return
+	}
+
+	if err := h.removeAlbumFiles(ctx, album.Slug, photoRecords); err != nil {
+		h.logger.Error("album deleted but uploads remain", "albumID", album.ID, "slug", album.Slug, "error", err)
+		render.HTML(c, http.StatusInternalServerError, pages.AlbumDeletePartial(album.Title, fmt.Sprintf("Could not remove uploaded files: %v", err)))
+		return
+	}
+
+	h.logger.Info("album deleted", "albumID", album.ID, "slug", album.Slug)
+	c.Redirect(http.StatusSeeOther, "/albums")
+}
+
 func (h *AlbumHandler) UploadPhoto(c *gin.Context) {
 	ctx := c.Request.Context()
 	slug := strings.TrimSpace(c.Param("slug"))
@@ -361,70 +528,456 @@ func (h *AlbumHandler) UploadPhoto(c *gin.Context) {
 		return
 	}
 
-	fileHeader, err := c.FormFile("photo")
-	if err != nil {
+	multipartForm, err := c.MultipartForm()
+	if err != nil || len(multipartForm.File["photo"]) == 0 {
 		c.String(http.StatusBadRequest, "photo file is required")
 		return
 	}
+	files := multipartForm.File["photo"]
+
+	opts := ingest.Options{
+		Caption:        strings.TrimSpace(c.PostForm("caption")),
+		AllowDuplicate: c.PostForm("allow_duplicate") != "",
+	}
+	if takenAtValue := strings.TrimSpace(c.PostForm("taken_at")); takenAtValue != "" {
+		parsed, err := time.Parse(formDateTimeLayout, takenAtValue)
+		if err != nil {
+			c.String(http.StatusBadRequest, "invalid taken_at format")
+			return
+		}
+		utc := parsed.UTC()
+		opts.TakenAt = &utc
+	}
+
+	summary := uploadSummary{Results: make([]ingest.Result, 0, len(files))}
+	for _, fileHeader := range files {
+		result := h.ingest.Ingest(ctx, album, fileHeader.Filename, func(dst string) error {
+			return saveUploadedFile(fileHeader, dst)
+		}, opts)
+		if result.Status == ingest.StatusUploaded {
+			summary.Uploaded++
+		} else {
+			summary.Failed++
+		}
+		summary.Results = append(summary.Results, result)
+	}
+	h.logger.Info("photo upload finished", "albumID", album.ID, "slug", album.Slug, "uploaded", summary.Uploaded, "failed", summary.Failed)
+
+	if c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON {
+		c.JSON(http.StatusOK, summary)
+		return
+	}
+
+	if summary.Failed == 0 {
+		c.Redirect(http.StatusSeeOther, fmt.Sprintf("/albums/%s/edit", album.Slug))
+		return
+	}
+
+	// A single file keeps the status of its failure so the form behaves like
+	// any other validation error; batches report every file on the edit page.
+	if len(summary.Results) == 1 {
+		result := summary.Results[0]
+		h.renderEdit(c, album, uploadHTTPStatus(result.Status), map[string]string{"photo": result.Error}, pages.UploadReport{})
+		return
+	}
+
+	status := http.StatusOK
+	if summary.Uploaded == 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	h.renderEdit(c, album, status, map[string]string{}, pages.UploadReport{
+		Summary: fmt.Sprintf("%d of %d photos uploaded.", summary.Uploaded, len(summary.Results)),
+		Results: toUploadResults(summary.Results),
+	})
+}
+
+// uploadHTTPStatus is the response status for a single file that ended
+// with the given ingest status.
+func uploadHTTPStatus(status string) int {
+	switch status {
+	case ingest.StatusUploaded:
+		return http.StatusOK
+	case ingest.StatusDuplicate:
+		return http.StatusConflict
+	case ingest.StatusRejected:
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// uploadSummary is the JSON body returned for upload requests.
+type uploadSummary struct {
+	Uploaded int             `json:"uploaded"`
+	Failed   int             `json:"failed"`
+	Results  []ingest.Result `json:"results"`
+}
+
+// saveUploadedFile copies an uploaded file to dst, refusing to replace an
+// existing file. A partially written dst is removed on failure.
+func saveUploadedFile(fileHeader *multipart.FileHeader, dst string) error {
+	src, err := fileHeader.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return ingest.WriteFile(dst, src)
+}
+
+func toUploadResults(results []ingest.Result) []pages.UploadResult {
+	items := make([]pages.UploadResult, 0, len(results))
+	for _, result := range results {
+		message := result.Error
+		if result.Status == ingest.StatusUploaded {
+			message = "Uploaded."
+		}
+		items = append(items, pages.UploadResult{
+			Filename: result.Filename,
+			OK:       result.Status == ingest.StatusUploaded,
+			Message:  message,
+		})
+	}
+	return items
+}
+
+func (h *AlbumHandler) DeletePhoto(c *gin.Context) {
+	ctx := c.Request.Context()
+	slug := strings.TrimSpace(c.Param("slug"))
+	if slug == "" {
+		c.String(http.StatusNotFound, "album not found")
+		return
+	}
+
+	photoID, err := strconv.ParseInt(c.Param("photoID"), 10, 64)
+	if err != nil || photoID <= 0 {
+		c.String(http.StatusNotFound, "photo not found")
+		return
+	}
 
-	filename, err := generatePhotoFilename(fileHeader.Filename)
+	album, err := h.albums.GetBySlug(ctx, slug)
 	if err != nil {
-		h.logger.Error("failed to generate photo filename", "error", err)
-		c.String(http.StatusInternalServerError, "failed to save photo")
+		if errors.Is(err, storage.ErrNotFound) {
+			c.String(http.StatusNotFound, "album not found")
+			return
+		}
+
+		h.logger.Error("failed to load album for photo deletion", "slug", slug, "error", err)
+		c.String(http.StatusInternalServerError, "failed to load album")
+		return
+	}
+
+	photo, err := h.photos.GetByID(ctx, photoID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.String(http.StatusNotFound, "photo not found")
+			return
+		}
+
+		h.logger.Error("failed to load photo for deletion", "photoID", photoID, "error", err)
+		c.String(http.StatusInternalServerError, "failed to load photo")
+		return
+	}
+
+	if photo.AlbumID != album.ID {
+		c.String(http.StatusNotFound, "photo not found")
+		return
+	}
+
+	// The row goes first: a leftover file is harmless, whereas a row without a
+	// file would render as a broken image.
+	if err := h.photos.Delete(ctx, photo.ID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.String(http.StatusNotFound, "photo not found")
+			return
+		}
+
+		h.logger.Error("failed to delete photo", "albumID", album.ID, "photoID", photo.ID, "error", err)
+		c.String(http.StatusInternalServerError, "failed to delete photo")
 		return
 	}
 
-	albumDir := filepath.Join(h.uploadsDir, album.Slug)
-	if err := os.MkdirAll(albumDir, 0o755); err != nil {
-		h.logger.Error("failed to ensure album upload directory", "dir", albumDir, "error", err)
-		c.String(http.StatusInternalServerError, "failed to save photo")
+	if err := h.removePhotoFiles(ctx, photo); err != nil {
+		h.logger.Warn("failed to remove photo files", "albumID", album.ID, "photoID", photo.ID, "filename", photo.Filename, "error", err)
+	}
+
+	h.logger.Info("photo deleted", "albumID", album.ID, "slug", album.Slug, "photoID", photo.ID, "filename", photo.Filename)
+	if c.PostForm("return_to") == "similar" {
+		target := fmt.Sprintf("/albums/%s/similar", album.Slug)
+		if distance, err := parseSimilarDistance(c.PostForm("distance")); err == nil {
+			target = fmt.Sprintf("%s?distance=%d", target, distance)
+		}
+		c.Redirect(http.StatusSeeOther, target)
 		return
 	}
+	c.Redirect(http.StatusSeeOther, fmt.Sprintf("/albums/%s/edit", album.Slug))
+}
 
-	diskPath := filepath.Join(albumDir, filename)
-	if err := c.SaveUploadedFile(fileHeader, diskPath); err != nil {
-		h.logger.Error("failed to save uploaded file", "path", diskPath, "error", err)
-		c.String(http.StatusInternalServerError, "failed to save photo")
+// Similar lists groups of visually similar photos in an album so near
+// duplicates can be pruned. The optional distance query parameter sets how
+// many perceptual hash bits may differ between neighbours in a group.
+func (h *AlbumHandler) Similar(c *gin.Context) {
+	ctx := c.Request.Context()
+	slug := strings.TrimSpace(c.Param("slug"))
+	if slug == "" {
+		c.String(http.StatusNotFound, "album not found")
 		return
 	}
 
-	if err := sanitizePhoto(diskPath); err != nil {
-		h.logger.Warn("failed to sanitize photo metadata", "path", diskPath, "error", err)
+	distance, err := parseSimilarDistance(c.Query("distance"))
+	if err != nil {
+		c.String(http.StatusBadRequest, fmt.Sprintf("distance must be a number from 0 to %d", maxSimilarDistance))
+		return
+	}
+
+	album, err := h.albums.GetBySlug(ctx, slug)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.String(http.StatusNotFound, "album not found")
+			return
+		}
+		h.logger.Error("failed to load album", "slug", slug, "error", err)
+		c.String(http.StatusInternalServerError, "failed to load album")
+		return
+	}
+
+	photoRecords, err := h.photos.ListByAlbum(ctx, album.ID)
+	if err != nil {
+		h.logger.Error("failed to load album photos", "slug", slug, "error", err)
+		c.String(http.StatusInternalServerError, "failed to load album photos")
+		return
+	}
+
+	hashed := make([]storage.Photo, 0, len(photoRecords))
+	hashes := make([]media.PerceptualHash, 0, len(photoRecords))
+	unhashed := 0
+	for _, photo := range photoRecords {
+		hash, err := media.ParsePerceptualHash(photo.PerceptualHash)
+		if err != nil {
+			unhashed++
+			continue
+		}
+		hashed = append(hashed, photo)
+		hashes = append(hashes, hash)
+	}
+
+	var groups [][]pages.AlbumPhoto
+	for _, members := range media.GroupSimilar(hashes, distance) {
+		group := make([]pages.AlbumPhoto, 0, len(members))
+		for _, idx := range members {
+			item := h.toAlbumPhoto(hashed[idx])
+			item.IsCover = album.CoverPhotoID != nil && *album.CoverPhotoID == hashed[idx].ID
+			group = append(group, item)
+		}
+		groups = append(groups, group)
+	}
+
+	render.HTML(c, http.StatusOK, pages.SimilarPhotos(pages.SimilarPhotosData{
+		Title:       album.Title,
+		Slug:        album.Slug,
+		Distance:    distance,
+		MaxDistance: maxSimilarDistance,
+		Groups:      groups,
+		Unhashed:    unhashed,
+	}))
+}
+
+func (h *AlbumHandler) UpdatePhoto(c *gin.Context) {
+	ctx := c.Request.Context()
+	slug := strings.TrimSpace(c.Param("slug"))
+	if slug == "" {
+		c.String(http.StatusNotFound, "album not found")
+		return
+	}
+
+	photoID, err := strconv.ParseInt(c.Param("photoID"), 10, 64)
+	if err != nil || photoID <= 0 {
+		c.String(http.StatusNotFound, "photo not found")
+		return
+	}
+
+	album, err := h.albums.GetBySlug(ctx, slug)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.String(http.StatusNotFound, "album not found")
+			return
+		}
+
+		h.logger.Error("failed to load album for photo update", "slug", slug, "error", err)
+		c.String(http.StatusInternalServerError, "failed to load album")
+		return
+	}
+
+	photo, err := h.photos.GetByID(ctx, photoID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.String(http.StatusNotFound, "photo not found")
+			return
+		}
+
+		h.logger.Error("failed to load photo for update", "photoID", photoID, "error", err)
+		c.String(http.StatusInternalServerError, "failed to load photo")
+		return
+	}
+
+	if photo.AlbumID != album.ID {
+		c.String(http.StatusNotFound, "photo not found")
+		return
 	}
 
 	caption := strings.TrimSpace(c.PostForm("caption"))
+	updateInput := storage.PhotoUpdate{Caption: &caption}
+
+	// The inline form always posts taken_at prefilled with the current value,
+	// so an unchanged value keeps the recorded time and its source.
 	takenAtValue := strings.TrimSpace(c.PostForm("taken_at"))
-	var takenAt *time.Time
-	if takenAtValue != "" {
+	switch {
+	case takenAtValue == "":
+		updateInput.ClearTakenAt = true
+	case photo.TakenAt != nil && takenAtValue == photo.TakenAt.UTC().Format(formDateTimeLayout):
+	default:
 		parsed, parseErr := time.Parse(formDateTimeLayout, takenAtValue)
 		if parseErr != nil {
-			_ = os.Remove(diskPath)
 			c.String(http.StatusBadRequest, "invalid taken_at format")
 			return
 		}
 		utc := parsed.UTC()
-		takenAt = &utc
+		manual := storage.TakenAtSourceManual
+		updateInput.TakenAt = &utc
+		updateInput.TakenAtSource = &manual
 	}
 
-	storedPath := path.Join(album.Slug, filename)
+	if _, err := h.photos.Update(ctx, photo.ID, updateInput); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.String(http.StatusNotFound, "photo not found")
+			return
+		}
 
-	_, err = h.photos.Create(ctx, storage.PhotoCreate{
-		AlbumID:  album.ID,
-		Filename: storedPath,
-		Caption:  caption,
-		TakenAt:  takenAt,
-	})
+		h.logger.Error("failed to update photo", "albumID", album.ID, "photoID", photo.ID, "error", err)
+		c.String(http.StatusInternalServerError, "failed to update photo")
+		return
+	}
+
+	h.logger.Info("photo updated", "albumID", album.ID, "slug", album.Slug, "photoID", photo.ID)
+	c.Redirect(http.StatusSeeOther, fmt.Sprintf("/albums/%s/edit", album.Slug))
+}
+
+func (h *AlbumHandler) ReorderPhotos(c *gin.Context) {
+	ctx := c.Request.Context()
+	slug := strings.TrimSpace(c.Param("slug"))
+	if slug == "" {
+		c.String(http.StatusNotFound, "album not found")
+		return
+	}
+
+	album, err := h.albums.GetBySlug(ctx, slug)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.String(http.StatusNotFound, "album not found")
+			return
+		}
+
+		h.logger.Error("failed to load album for reorder", "slug", slug, "error", err)
+		c.String(http.StatusInternalServerError, "failed to load album")
+		return
+	}
+
+	var photoIDs []int64
+	for _, raw := range strings.Split(c.PostForm("order"), ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id <= 0 {
+			c.String(http.StatusBadRequest, "invalid photo order")
+			return
+		}
+		photoIDs = append(photoIDs, id)
+	}
+
+	if err := h.photos.Reorder(ctx, album.ID, photoIDs); err != nil {
+		if errors.Is(err, storage.ErrConflict) {
+			c.String(http.StatusConflict, "photo order is out of date; reload the page and try again")
+			return
+		}
+
+		h.logger.Error("failed to reorder photos", "albumID", album.ID, "error", err)
+		c.String(http.StatusInternalServerError, "failed to reorder photos")
+		return
+	}
+
+	// Saving a hand-made order only makes sense if the album displays it.
+	if album.SortMode != storage.PhotoSortManual {
+		manual := storage.PhotoSortManual
+		if _, err := h.albums.Update(ctx, album.ID, storage.AlbumUpdate{SortMode: &manual}); err != nil {
+			h.logger.Error("failed to switch album to manual order", "albumID", album.ID, "error", err)
+			c.String(http.StatusInternalServerError, "failed to reorder photos")
+			return
+		}
+	}
+
+	h.logger.Info("photos reordered", "albumID", album.ID, "slug", album.Slug, "count", len(photoIDs))
+	c.Redirect(http.StatusSeeOther, fmt.Sprintf("/albums/%s/edit", album.Slug))
+}
+
+func (h *AlbumHandler) SetCover(c *gin.Context) {
+	ctx := c.Request.Context()
+	slug := strings.TrimSpace(c.Param("slug"))
+	if slug == "" {
+		c.String(http.StatusNotFound, "album not found")
+		return
+	}
+
+	photoID, err := strconv.ParseInt(c.Param("photoID"), 10, 64)
+	if err != nil || photoID <= 0 {
+		c.String(http.StatusNotFound, "photo not found")
+		return
+	}
+
+	album, err := h.albums.GetBySlug(ctx, slug)
 	if err != nil {
-		_ = os.Remove(diskPath)
-		h.logger.Error("failed to persist photo metadata", "albumID", album.ID, "error", err)
-		c.String(http.StatusInternalServerError, "failed to save photo")
+		if errors.Is(err, storage.ErrNotFound) {
+			c.String(http.StatusNotFound, "album not found")
+			return
+		}
+
+		h.logger.Error("failed to load album for cover update", "slug", slug, "error", err)
+		c.String(http.StatusInternalServerError, "failed to load album")
 		return
 	}
 
-	h.logger.Info("photo uploaded", "albumID", album.ID, "slug", album.Slug, "filename", storedPath)
+	if err := h.albums.SetCoverPhoto(ctx, album.ID, photoID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.String(http.StatusNotFound, "photo not found")
+			return
+		}
+
+		h.logger.Error("failed to set cover photo", "albumID", album.ID, "photoID", photoID, "error", err)
+		c.String(http.StatusInternalServerError, "failed to set cover photo")
+		return
+	}
+
+	h.logger.Info("album cover updated", "albumID", album.ID, "slug", album.Slug, "photoID", photoID)
 	c.Redirect(http.StatusSeeOther, fmt.Sprintf("/albums/%s/edit", album.Slug))
 }
 
+// removePhotoFiles deletes the stored file for a photo and its resized
+// variants, unless other photos share them. Files that are already gone are
+// not treated as errors; removal continues past failures and the first one
+// is returned.
+func (h *AlbumHandler) removePhotoFiles(ctx context.Context, photo storage.Photo) error {
+	return h.ingest.RemovePhotoFiles(ctx, photo)
+}
+
+// removeAlbumFiles deletes every file stored under an album's slug and the
+// files of its photos. An album without files is not treated as an error.
+func (h *AlbumHandler) removeAlbumFiles(ctx context.Context, slug string, photos []storage.Photo) error {
+	if !storage.ValidSlug(slug) {
+		return fmt.Errorf("refusing to remove uploads for unsafe slug %q", slug)
+	}
+	return h.ingest.RemoveAlbumFiles(ctx, slug, photos)
+}
+
 func toAlbumListItem(album storage.Album) pages.AlbumListItem {
 	meta := ""
 	if ts := formatTimestamp(album.UpdatedAt); ts != "" {
@@ -439,23 +992,102 @@ func toAlbumListItem(album storage.Album) pages.AlbumListItem {
 	}
 }
 
-func toAlbumPhoto(photo storage.Photo) pages.AlbumPhoto {
+func (h *AlbumHandler) toAlbumPhoto(photo storage.Photo) pages.AlbumPhoto {
 	caption := strings.TrimSpace(photo.Caption)
 	if caption == "" {
 		caption = path.Base(strings.ReplaceAll(photo.Filename, "\\", "/"))
 	}
 	item := pages.AlbumPhoto{
-		ID:       photo.ID,
-		Filename: path.Base(strings.ReplaceAll(photo.Filename, "\\", "/")),
-		Caption:  caption,
-		URL:      photoURL(photo.Filename),
+		ID:           photo.ID,
+		Filename:     path.Base(strings.ReplaceAll(photo.Filename, "\\", "/")),
+		Caption:      caption,
+		CaptionInput: photo.Caption,
+		URL:          h.blobs.URL(photo.Filename),
+		Width:        photo.Width,
+		Height:       photo.Height,
+		FileDetails:  fileDetails(photo),
 	}
+	item.ThumbURL, item.MediumURL, item.LargeURL = item.URL, item.URL, item.URL
+	srcset := make([]string, 0, len(photo.Variants))
+	for _, variant := range photo.Variants {
+		variantURL := h.blobs.URL(variant.Filename)
+		switch variant.Name {
+		case "thumb":
+			item.ThumbURL = variantURL
+		case "medium":
+			item.MediumURL = variantURL
+		case "large":
+			item.LargeURL = variantURL
+		}
+		srcset = append(srcset, fmt.Sprintf("%s %dw", variantURL, variant.Width))
+	}
+	item.SrcSet = strings.Join(srcset, ", ")
+	item.Info = photoInfo(photo.Metadata)
 	if photo.TakenAt != nil {
 		item.TakenAt = formatTimestamp(*photo.TakenAt)
+		item.TakenAtInput = photo.TakenAt.UTC().Format(formDateTimeLayout)
+		switch photo.TakenAtSource {
+		case storage.TakenAtSourceCamera:
+			item.TakenAtSource = "from camera"
+		case storage.TakenAtSourceManual:
+			item.TakenAtSource = "entered manually"
+		}
 	}
 	return item
 }
 
+// coverIndex returns the position of the album cover within photos, falling
+// back to the first photo when no cover is set or it is not in the list. It
+// returns -1 when photos is empty.
+func coverIndex(photos []storage.Photo, coverID *int64) int {
+	if len(photos) == 0 {
+		return -1
+	}
+	if coverID != nil {
+		for idx, photo := range photos {
+			if photo.ID == *coverID {
+				return idx
+			}
+		}
+	}
+	return 0
+}
+
+// photoInfo summarises camera details for the public viewer, for example
+// "Fujifilm X-T4 · XF35mmF1.4 R · 35 mm · f/2.8 · 1/250 s · ISO 400".
+func photoInfo(meta *storage.PhotoMetadata) string {
+	if meta == nil {
+		return ""
+	}
+
+	var parts []string
+	camera := meta.CameraModel
+	// Many cameras repeat the make in the model name ("Canon EOS R5").
+	if meta.CameraMake != "" && !strings.HasPrefix(strings.ToLower(camera), strings.ToLower(meta.CameraMake)) {
+		camera = strings.TrimSpace(meta.CameraMake + " " + camera)
+	}
+	if camera != "" {
+		parts = append(parts, camera)
+	}
+	if meta.Lens != "" {
+		parts = append(parts, meta.Lens)
+	}
+	if meta.FocalLength > 0 {
+		parts = append(parts, strconv.FormatFloat(meta.FocalLength, 'f', -1, 64)+" mm")
+	}
+	if meta.Aperture > 0 {
+		parts = append(parts, "f/"+strconv.FormatFloat(meta.Aperture, 'f', -1, 64))
+	}
+	if meta.ExposureTime != "" {
+		parts = append(parts, meta.ExposureTime+" s")
+	}
+	if meta.ISO > 0 {
+		parts = append(parts, fmt.Sprintf("ISO %d", meta.ISO))
+	}
+
+	return strings.Join(parts, " · ")
+}
+
 func slugify(value string) string {
 	value = strings.TrimSpace(value)
 	if value == "" {
@@ -500,101 +1132,47 @@ func formatTimestamp(t time.Time) string {
 	return t.UTC().Format("Jan 2, 2006 15:04 MST")
 }
 
-func sanitizePhoto(path string) error {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return err
-	}
-
-	if !isJPEG(data) {
-		return nil
-	}
-
-	img, err := jpeg.Decode(bytes.NewReader(data))
-	if err != nil {
-		return nil
+// parseSimilarDistance reads the distance parameter of the similar photos
+// view, falling back to defaultSimilarDistance when it is empty.
+func parseSimilarDistance(raw string) (int, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return defaultSimilarDistance, nil
 	}
-
-	orientation := readOrientation(data)
-	img = normalizeOrientation(img, orientation)
-
-	tmp, err := os.CreateTemp(filepath.Dir(path), "photo-*")
+	distance, err := strconv.Atoi(raw)
 	if err != nil {
-		return err
-	}
-	defer func() {
-		_ = tmp.Close()
-		_ = os.Remove(tmp.Name())
-	}()
-
-	if err := jpeg.Encode(tmp, img, &jpeg.Options{Quality: 95}); err != nil {
-		return err
+		return 0, err
 	}
-
-	if err := tmp.Close(); err != nil {
-		return err
+	if distance < 0 || distance > maxSimilarDistance {
+		return 0, fmt.Errorf("distance %d out of range", distance)
 	}
-
-	return os.Rename(tmp.Name(), path)
-}
-
-func isJPEG(data []byte) bool {
-	return len(data) > 2 && data[0] == 0xff && data[1] == 0xd8
+	return distance, nil
 }
 
-func readOrientation(data []byte) int {
-	ex, err := exif.Decode(bytes.NewReader(data))
-	if err != nil {
-		return 1
+// fileDetails summarises the stored file's dimensions and size, omitting
+// whatever has not been measured.
+func fileDetails(photo storage.Photo) string {
+	var parts []string
+	if photo.Width > 0 && photo.Height > 0 {
+		parts = append(parts, fmt.Sprintf("%d × %d", photo.Width, photo.Height))
 	}
-
-	tag, err := ex.Get(exif.Orientation)
-	if err != nil {
-		return 1
-	}
-
-	val, err := tag.Int(0)
-	if err != nil {
-		return 1
-	}
-
-	return val
-}
-
-func normalizeOrientation(img image.Image, orientation int) image.Image {
-	switch orientation {
-	case 2:
-		return imaging.FlipH(img)
-	case 3:
-		return imaging.Rotate180(img)
-	case 4:
-		return imaging.FlipV(img)
-	case 5:
-		return imaging.FlipH(imaging.Rotate90(img))
-	case 6:
-		return imaging.Rotate90(img)
-	case 7:
-		return imaging.FlipV(imaging.Rotate90(img))
-	case 8:
-		return imaging.Rotate270(img)
-	default:
-		return img
+	if photo.SizeBytes > 0 {
+		parts = append(parts, formatBytes(photo.SizeBytes))
 	}
+	return strings.Join(parts, " · ")
 }
 
-func generatePhotoFilename(original string) (string, error) {
-	ext := strings.ToLower(filepath.Ext(original))
-	const tokenSize = 12
-	buf := make([]byte, tokenSize)
-	if _, err := rand.Read(buf); err != nil {
-		return "", err
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
 	}
-	token := hex.EncodeToString(buf)
-	timestamp := time.Now().UTC().Format("20060102150405")
-	return fmt.Sprintf("%s-%s%s", timestamp, token, ext), nil
-}
-
-func photoURL(rel string) string {
-	clean := strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(rel, "\\", "/")), "/")
-	return "/uploads/" + clean
+	value, suffix := float64(n)/unit, "KB"
+	for _, next := range []string{"MB", "GB", "TB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, next
+	}
+	return fmt.Sprintf("%.1f %s", value, suffix)
 }
@@ -3,7 +3,13 @@ package handlers_test
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
 	"io"
 	"log/slog"
 	"net/http"
@@ -19,7 +25,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/Oxyrus/memories/internal/blob"
 	"github.com/Oxyrus/memories/internal/http/handlers"
+	"github.com/Oxyrus/memories/internal/ingest"
 	"github.com/Oxyrus/memories/internal/storage"
 )
 
@@ -46,7 +54,11 @@ func TestAlbumHandlerListSuccess(t *testing.T) {
 		},
 	}
 
-	photos := &stubPhotos{}
+	photos := &stubPhotos{
+		covers: map[int64]storage.Photo{
+			1: {ID: 11, AlbumID: 1, Filename: "summer-roadtrip/cover.jpg"},
+		},
+	}
 	handler := newAlbumHandler(t, albums, photos, t.TempDir())
 
 	handler.List(ctx)
@@ -62,6 +74,27 @@ func TestAlbumHandlerListSuccess(t *testing.T) {
 	if !strings.Contains(body, "/albums/summer-roadtrip") {
 		t.Fatalf("response body missing album link: %s", body)
 	}
+	if !strings.Contains(body, "/uploads/summer-roadtrip/cover.jpg") {
+		t.Fatalf("response body missing album cover: %s", body)
+	}
+}
+
+func TestAlbumHandlerListWithoutCovers(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/albums", nil)
+
+	albums := &stubAlbums{list: []storage.Album{{ID: 1, Title: "Summer Roadtrip", Slug: "summer-roadtrip"}}}
+	photos := &stubPhotos{coversErr: errors.New("boom")}
+	handler := newAlbumHandler(t, albums, photos, t.TempDir())
+	handler.List(ctx)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "Summer Roadtrip") {
+		t.Fatalf("expected albums to be listed without covers: %s", rec.Body.String())
+	}
 }
 
 func TestAlbumHandlerListError(t *testing.T) {
@@ -344,7 +377,14 @@ func TestAlbumHandlerEditSuccess(t *testing.T) {
 		},
 	}
 
-	handler := newAlbumHandler(t, albums, &stubPhotos{}, t.TempDir())
+	takenAt := time.Date(2024, 7, 14, 16, 30, 0, 0, time.UTC)
+	photos := &stubPhotos{
+		listByAlbum: map[int64][]storage.Photo{
+			1: {{ID: 10, AlbumID: 1, Filename: "summer-roadtrip/beach.jpg", TakenAt: &takenAt, TakenAtSource: storage.TakenAtSourceCamera}},
+		},
+	}
+
+	handler := newAlbumHandler(t, albums, photos, t.TempDir())
 	handler.Edit(ctx)
 
 	if rec.Code != http.StatusOK {
@@ -357,6 +397,9 @@ func TestAlbumHandlerEditSuccess(t *testing.T) {
 	if !strings.Contains(body, `value="Summer Roadtrip"`) {
 		t.Fatalf("expected title value in form, got %s", body)
 	}
+	if !strings.Contains(body, "from camera") {
+		t.Fatalf("expected taken_at source on the edit page, got %s", body)
+	}
 }
 
 func TestAlbumHandlerEditNotFound(t *testing.T) {
@@ -384,6 +427,8 @@ func TestAlbumHandlerUpdateSuccess(t *testing.T) {
 	form := make(url.Values)
 	form.Set("title", "Updated Title")
 	form.Set("description", "Updated description")
+	form.Set("show_photo_info", "1")
+	form.Set("allow_download", "1")
 
 	req := httptest.NewRequest(http.MethodPost, "/albums/summer-roadtrip/edit", strings.NewReader(form.Encode()))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
@@ -425,6 +470,12 @@ func TestAlbumHandlerUpdateSuccess(t *testing.T) {
 	if albums.lastUpdateTitle != "Updated Title" {
 		t.Fatalf("expected update title 'Updated Title', got %q", albums.lastUpdateTitle)
 	}
+	if albums.lastUpdate.ShowPhotoInfo == nil || !*albums.lastUpdate.ShowPhotoInfo {
+		t.Fatalf("expected show_photo_info to be enabled, got %v", albums.lastUpdate.ShowPhotoInfo)
+	}
+	if albums.lastUpdate.AllowDownload == nil || !*albums.lastUpdate.AllowDownload {
+		t.Fatalf("expected allow_download to be enabled, got %v", albums.lastUpdate.AllowDownload)
+	}
 }
 
 func TestAlbumHandlerUpdateValidationError(t *testing.T) {
@@ -570,7 +621,7 @@ func TestAlbumHandlerUploadPhotoSuccess(t *testing.T) {
 	if err != nil {
 		t.Fatalf("create form file: %v", err)
 	}
-	if _, err := fileWriter.Write([]byte("fake image")); err != nil {
+	if _, err := fileWriter.Write(testJPEG(t)); err != nil {
 		t.Fatalf("write photo bytes: %v", err)
 	}
 	if err := writer.WriteField("caption", "Sunset"); err != nil {
@@ -610,6 +661,9 @@ func TestAlbumHandlerUploadPhotoSuccess(t *testing.T) {
 	if photos.lastCreate.TakenAt == nil || !photos.lastCreate.TakenAt.Equal(expectedTime) {
 		t.Fatalf("expected taken_at %v, got %v", expectedTime, photos.lastCreate.TakenAt)
 	}
+	if photos.lastCreate.TakenAtSource != storage.TakenAtSourceManual {
+		t.Fatalf("expected taken_at source manual, got %q", photos.lastCreate.TakenAtSource)
+	}
 	if photos.lastCreate.Filename == "" {
 		t.Fatalf("expected filename to be set")
 	}
@@ -617,9 +671,87 @@ func TestAlbumHandlerUploadPhotoSuccess(t *testing.T) {
 		t.Fatalf("expected filename to be namespaced under slug, got %q", photos.lastCreate.Filename)
 	}
 	diskPath := filepath.Join(uploadsDir, photos.lastCreate.Filename)
-	if _, err := os.Stat(diskPath); err != nil {
+	stored, err := os.ReadFile(diskPath)
+	if err != nil {
 		t.Fatalf("expected photo on disk at %s: %v", diskPath, err)
 	}
+
+	sum := sha256.Sum256(stored)
+	if got := photos.lastCreate; got.Width != 8 || got.Height != 8 || got.MimeType != "image/jpeg" {
+		t.Fatalf("expected 8x8 image/jpeg, got %dx%d %q", got.Width, got.Height, got.MimeType)
+	}
+	if photos.lastCreate.SizeBytes != int64(len(stored)) {
+		t.Fatalf("expected size %d of the sanitized file, got %d", len(stored), photos.lastCreate.SizeBytes)
+	}
+	if photos.lastCreate.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Fatalf("expected sha256 of the sanitized file, got %q", photos.lastCreate.SHA256)
+	}
+	if len(photos.lastCreate.PerceptualHash) != 16 {
+		t.Fatalf("expected a 64-bit perceptual hash, got %q", photos.lastCreate.PerceptualHash)
+	}
+}
+
+func TestAlbumHandlerUploadPhotoUsesCameraTime(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+
+	slug := "summer-roadtrip"
+	uploadsDir := t.TempDir()
+	albums := &stubAlbums{
+		getBySlug: map[string]storage.Album{
+			slug: {ID: 1, Slug: slug, Title: "Summer Roadtrip"},
+		},
+	}
+	photos := &stubPhotos{}
+	handler := newAlbumHandler(t, albums, photos, uploadsDir)
+
+	photo, err := os.ReadFile(filepath.Join("..", "..", "media", "testdata", "camera.jpg"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	fileWriter, err := writer.CreateFormFile("photo", "camera.jpg")
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	if _, err := fileWriter.Write(photo); err != nil {
+		t.Fatalf("write photo bytes: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("close writer: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/albums/"+slug+"/photos", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	ctx.Request = req
+	ctx.Params = gin.Params{{Key: "slug", Value: slug}}
+
+	handler.UploadPhoto(ctx)
+	ctx.Writer.WriteHeaderNow()
+
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("expected redirect status, got %d", rec.Code)
+	}
+	expectedTime := time.Date(2024, 7, 14, 16, 30, 5, 250_000_000, time.UTC)
+	if photos.lastCreate.TakenAt == nil || !photos.lastCreate.TakenAt.Equal(expectedTime) {
+		t.Fatalf("expected camera taken_at %v, got %v", expectedTime, photos.lastCreate.TakenAt)
+	}
+	if photos.lastCreate.TakenAtSource != storage.TakenAtSourceCamera {
+		t.Fatalf("expected taken_at source camera, got %q", photos.lastCreate.TakenAtSource)
+	}
+	if meta := photos.lastCreate.Metadata; meta == nil || meta.CameraModel != "X-T4" || meta.ISO != 400 {
+		t.Fatalf("expected camera metadata to be persisted, got %+v", meta)
+	}
+
+	stored, err := os.ReadFile(filepath.Join(uploadsDir, photos.lastCreate.Filename))
+	if err != nil {
+		t.Fatalf("read stored photo: %v", err)
+	}
+	if bytes.Contains(stored, []byte("X-T4")) {
+		t.Fatalf("expected published file to be stripped of camera metadata")
+	}
 }
 
 func TestAlbumHandlerUploadPhotoMissingFile(t *testing.T) {
@@ -682,7 +814,7 @@ func TestAlbumHandlerUploadPhotoInvalidTakenAt(t *testing.T) {
 	if err != nil {
 		t.Fatalf("create form file: %v", err)
 	}
-	if _, err := fileWriter.Write([]byte("fake image")); err != nil {
+	if _, err := fileWriter.Write(testJPEG(t)); err != nil {
 		t.Fatalf("write photo bytes: %v", err)
 	}
 	if err := writer.WriteField("taken_at", "invalid"); err != nil {
@@ -729,7 +861,7 @@ func TestAlbumHandlerUploadPhotoCreateError(t *testing.T) {
 	if err != nil {
 		t.Fatalf("create form file: %v", err)
 	}
-	if _, err := fileWriter.Write([]byte("fake image")); err != nil {
+	if _, err := fileWriter.Write(testJPEG(t)); err != nil {
 		t.Fatalf("write photo bytes: %v", err)
 	}
 	if err := writer.Close(); err != nil {
@@ -753,23 +885,99 @@ func TestAlbumHandlerUploadPhotoCreateError(t *testing.T) {
 	assertAlbumDirEmpty(t, uploadsDir, slug)
 }
 
-func TestAlbumHandlerUploadPhotoAlbumNotFound(t *testing.T) {
+func TestAlbumHandlerUploadPhotoRejectsUnsanitizable(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  []byte
+		message  string
+	}{
+		{
+			name:     "corrupt jpeg",
+			filename: "sunset.jpg",
+			content:  append([]byte{0xff, 0xd8, 0xff, 0xe1}, "Exif\x00\x00GPS"...),
+			message:  "This photo could not be read",
+		},
+		{
+			name:     "unsupported format",
+			filename: "notes.txt",
+			content:  []byte("fake image"),
+			message:  "This file type is not supported",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(rec)
+
+			slug := "summer-roadtrip"
+			uploadsDir := t.TempDir()
+			albums := &stubAlbums{
+				getBySlug: map[string]storage.Album{
+					slug: {ID: 1, Slug: slug, Title: "Summer Roadtrip"},
+				},
+			}
+			photos := &stubPhotos{}
+			handler := newAlbumHandler(t, albums, photos, uploadsDir)
+
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			fileWriter, err := writer.CreateFormFile("photo", tc.filename)
+			if err != nil {
+				t.Fatalf("create form file: %v", err)
+			}
+			if _, err := fileWriter.Write(tc.content); err != nil {
+				t.Fatalf("write photo bytes: %v", err)
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatalf("close writer: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/albums/"+slug+"/photos", body)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			ctx.Request = req
+			ctx.Params = gin.Params{{Key: "slug", Value: slug}}
+
+			handler.UploadPhoto(ctx)
+
+			if rec.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("expected status 422, got %d", rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tc.message) {
+				t.Fatalf("expected edit page to explain the rejection, got %s", rec.Body.String())
+			}
+			if photos.createCalled {
+				t.Fatalf("photo Create should not be called")
+			}
+			assertAlbumDirEmpty(t, uploadsDir, slug)
+		})
+	}
+}
+
+func TestAlbumHandlerUploadPhotoSkipsDuplicates(t *testing.T) {
 	rec := httptest.NewRecorder()
 	ctx, _ := gin.CreateTestContext(rec)
 
-	slug := "missing"
+	slug := "summer-roadtrip"
 	uploadsDir := t.TempDir()
-	albums := &stubAlbums{getBySlugErr: storage.ErrNotFound}
-	photos := &stubPhotos{}
+	albums := &stubAlbums{
+		getBySlug: map[string]storage.Album{
+			slug: {ID: 1, Slug: slug, Title: "Summer Roadtrip"},
+		},
+	}
+	photos := &stubPhotos{
+		duplicate: &storage.Photo{ID: 7, AlbumID: 1, Filename: slug + "/a1b2.jpg", OriginalFilename: "IMG_0042.jpg"},
+	}
 	handler := newAlbumHandler(t, albums, photos, uploadsDir)
 
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
-	fileWriter, err := writer.CreateFormFile("photo", "sunset.jpg")
+	fileWriter, err := writer.CreateFormFile("photo", "IMG_0042.jpg")
 	if err != nil {
 		t.Fatalf("create form file: %v", err)
 	}
-	if _, err := fileWriter.Write([]byte("fake image")); err != nil {
+	if _, err := fileWriter.Write(testJPEG(t)); err != nil {
 		t.Fatalf("write photo bytes: %v", err)
 	}
 	if err := writer.Close(); err != nil {
@@ -782,10 +990,15 @@ func TestAlbumHandlerUploadPhotoAlbumNotFound(t *testing.T) {
 	ctx.Params = gin.Params{{Key: "slug", Value: slug}}
 
 	handler.UploadPhoto(ctx)
-	ctx.Writer.WriteHeaderNow()
 
-	if rec.Code != http.StatusNotFound {
-		t.Fatalf("expected status 404, got %d", rec.Code)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected status 409, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "already in the album as “IMG_0042.jpg”") {
+		t.Fatalf("expected edit page to name the existing photo, got %s", rec.Body.String())
+	}
+	if len(photos.lastFindHash) != 64 {
+		t.Fatalf("expected lookup by SHA-256, got %q", photos.lastFindHash)
 	}
 	if photos.createCalled {
 		t.Fatalf("photo Create should not be called")
@@ -793,140 +1006,1052 @@ func TestAlbumHandlerUploadPhotoAlbumNotFound(t *testing.T) {
 	assertAlbumDirEmpty(t, uploadsDir, slug)
 }
 
-func assertAlbumDirEmpty(t *testing.T, baseDir, slug string) {
-	t.Helper()
-	albumDir := filepath.Join(baseDir, slug)
-	entries, err := os.ReadDir(albumDir)
-	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return
-		}
-		t.Fatalf("read album dir: %v", err)
+func TestAlbumHandlerUploadPhotoAllowsIntentionalDuplicates(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+
+	slug := "summer-roadtrip"
+	albums := &stubAlbums{
+		getBySlug: map[string]storage.Album{
+			slug: {ID: 1, Slug: slug, Title: "Summer Roadtrip"},
+		},
 	}
-	if len(entries) > 0 {
-		t.Fatalf("expected album dir %s to be empty, found %d entries", albumDir, len(entries))
+	photos := &stubPhotos{
+		duplicate: &storage.Photo{ID: 7, AlbumID: 1, Filename: slug + "/a1b2.jpg"},
 	}
-}
-
-type stubAlbums struct {
-	list            []storage.Album
-	listErr         error
-	getBySlug       map[string]storage.Album
-	getBySlugErr    error
-	createResp      storage.Album
-	createErr       error
-	createCalled    bool
-	lastCreate      storage.AlbumCreate
-	updateResp      storage.Album
-	updateErr       error
-	updateCalled    bool
-	lastUpdateID    int64
-	lastUpdate      storage.AlbumUpdate
-	lastUpdateTitle string
-	lastUpdateDesc  string
-}
+	handler := newAlbumHandler(t, albums, photos, t.TempDir())
 
-func (s *stubAlbums) Create(_ context.Context, input storage.AlbumCreate) (storage.Album, error) {
-	s.createCalled = true
-	s.lastCreate = input
-	if s.createErr != nil {
-		return storage.Album{}, s.createErr
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	fileWriter, err := writer.CreateFormFile("photo", "IMG_0042.jpg")
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
 	}
-	return s.createResp, nil
-}
-
-func (s *stubAlbums) GetByID(context.Context, int64) (storage.Album, error) {
-	panic("unexpected call to GetByID")
-}
-
-func (s *stubAlbums) GetBySlug(_ context.Context, slug string) (storage.Album, error) {
-	if s.getBySlugErr != nil {
-		return storage.Album{}, s.getBySlugErr
+	if _, err := fileWriter.Write(testJPEG(t)); err != nil {
+		t.Fatalf("write photo bytes: %v", err)
 	}
-	if s.getBySlug != nil {
-		if album, ok := s.getBySlug[slug]; ok {
-			return album, nil
-		}
+	if err := writer.WriteField("allow_duplicate", "1"); err != nil {
+		t.Fatalf("write allow_duplicate: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("close writer: %v", err)
 	}
-	return storage.Album{}, storage.ErrNotFound
-}
 
-func (s *stubAlbums) List(context.Context) ([]storage.Album, error) {
-	return s.list, s.listErr
-}
+	req := httptest.NewRequest(http.MethodPost, "/albums/"+slug+"/photos", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	ctx.Request = req
+	ctx.Params = gin.Params{{Key: "slug", Value: slug}}
 
-func (s *stubAlbums) Update(_ context.Context, id int64, input storage.AlbumUpdate) (storage.Album, error) {
-	s.updateCalled = true
-	s.lastUpdateID = id
-	s.lastUpdate = input
-	if input.Title != nil {
-		s.lastUpdateTitle = *input.Title
-	}
-	if input.Description != nil {
-		s.lastUpdateDesc = *input.Description
-	}
-	if s.updateErr != nil {
-		return storage.Album{}, s.updateErr
+	handler.UploadPhoto(ctx)
+	ctx.Writer.WriteHeaderNow()
+
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("expected redirect status, got %d", rec.Code)
 	}
-	if s.updateResp.ID == 0 {
-		s.updateResp.ID = id
+	if !photos.createCalled || !photos.lastCreate.AllowDuplicate {
+		t.Fatalf("expected photo to be created as an allowed duplicate, got %+v", photos.lastCreate)
 	}
-	return s.updateResp, nil
 }
 
-func (s *stubAlbums) Delete(context.Context, int64) error {
-	panic("unexpected call to Delete")
-}
+func TestAlbumHandlerUploadPhotoBatch(t *testing.T) {
+	files := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "first.jpg", content: testJPEG(t)},
+		{name: "notes.txt", content: []byte("fake image")},
+		{name: "second.jpg", content: testJPEG(t)},
+	}
 
-func (s *stubAlbums) SetCoverPhoto(context.Context, int64, int64) error {
-	panic("unexpected call to SetCoverPhoto")
-}
+	newRequest := func(t *testing.T, accept string) (*gin.Context, *httptest.ResponseRecorder, *stubPhotos, string) {
+		t.Helper()
 
-func (s *stubAlbums) ClearCoverPhoto(context.Context, int64) error {
-	panic("unexpected call to ClearCoverPhoto")
-}
+		rec := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rec)
 
-func newTestLogger() *slog.Logger {
-	return slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError}))
-}
+		slug := "summer-roadtrip"
+		uploadsDir := t.TempDir()
+		albums := &stubAlbums{
+			getBySlug: map[string]storage.Album{
+				slug: {ID: 1, Slug: slug, Title: "Summer Roadtrip"},
+			},
+		}
+		photos := &stubPhotos{}
+		handler := newAlbumHandler(t, albums, photos, uploadsDir)
+
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		for _, file := range files {
+			fileWriter, err := writer.CreateFormFile("photo", file.name)
+			if err != nil {
+				t.Fatalf("create form file: %v", err)
+			}
+			if _, err := fileWriter.Write(file.content); err != nil {
+				t.Fatalf("write photo bytes: %v", err)
+			}
+		}
+		if err := writer.WriteField("caption", "Roadtrip"); err != nil {
+			t.Fatalf("write caption: %v", err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("close writer: %v", err)
+		}
 
-type stubPhotos struct {
-	listByAlbum  map[int64][]storage.Photo
-	listErr      error
-	createResp   storage.Photo
-	createErr    error
-	createCalled bool
-	lastCreate   storage.PhotoCreate
-}
+		req := httptest.NewRequest(http.MethodPost, "/albums/"+slug+"/photos", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		ctx.Request = req
+		ctx.Params = gin.Params{{Key: "slug", Value: slug}}
 
-func (s *stubPhotos) Create(_ context.Context, input storage.PhotoCreate) (storage.Photo, error) {
-	s.createCalled = true
-	s.lastCreate = input
-	if s.createErr != nil {
-		return storage.Photo{}, s.createErr
+		handler.UploadPhoto(ctx)
+		return ctx, rec, photos, uploadsDir
 	}
-	return s.createResp, nil
-}
 
-func (s *stubPhotos) GetByID(context.Context, int64) (storage.Photo, error) {
-	panic("unexpected call to GetByID")
-}
+	t.Run("json", func(t *testing.T) {
+		_, rec, photos, _ := newRequest(t, "application/json")
 
-func (s *stubPhotos) ListByAlbum(_ context.Context, albumID int64) ([]storage.Photo, error) {
-	if s.listErr != nil {
-		return nil, s.listErr
-	}
-	if s.listByAlbum == nil {
-		return nil, nil
-	}
-	return append([]storage.Photo(nil), s.listByAlbum[albumID]...), nil
-}
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
 
-func (s *stubPhotos) Delete(context.Context, int64) error {
-	panic("unexpected call to Delete")
-}
+		var summary struct {
+			Uploaded int `json:"uploaded"`
+			Failed   int `json:"failed"`
+			Results  []struct {
+				Filename string `json:"filename"`
+				Status   string `json:"status"`
+				PhotoID  int64  `json:"photo_id"`
+				Error    string `json:"error"`
+			} `json:"results"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &summary); err != nil {
+			t.Fatalf("decode summary: %v (%s)", err, rec.Body.String())
+		}
+		if summary.Uploaded != 2 || summary.Failed != 1 || len(summary.Results) != 3 {
+			t.Fatalf("expected 2 uploaded and 1 failed, got %+v", summary)
+		}
 
-func newAlbumHandler(t *testing.T, albums storage.Albums, photos storage.Photos, uploadsDir string) *handlers.AlbumHandler {
+		wantStatus := []string{"uploaded", "rejected", "uploaded"}
+		for i, result := range summary.Results {
+			if result.Filename != files[i].name || result.Status != wantStatus[i] {
+				t.Fatalf("result %d = %+v, want %s %s", i, result, files[i].name, wantStatus[i])
+			}
+		}
+		if summary.Results[0].PhotoID == 0 || summary.Results[2].PhotoID == 0 {
+			t.Fatalf("expected uploaded files to report their photo IDs, got %+v", summary.Results)
+		}
+		if !strings.Contains(summary.Results[1].Error, "not supported") {
+			t.Fatalf("expected rejected file to explain why, got %q", summary.Results[1].Error)
+		}
+
+		if len(photos.created) != 2 {
+			t.Fatalf("expected 2 photos to be created, got %d", len(photos.created))
+		}
+		for _, created := range photos.created {
+			if created.Caption != "Roadtrip" {
+				t.Fatalf("expected caption to apply to every file, got %q", created.Caption)
+			}
+		}
+	})
+
+	t.Run("html", func(t *testing.T) {
+		_, rec, photos, uploadsDir := newRequest(t, "")
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+		body := rec.Body.String()
+		for _, want := range []string{"first.jpg", "second.jpg", "Uploaded.", "notes.txt", "This file type is not supported"} {
+			if !strings.Contains(body, want) {
+				t.Fatalf("expected upload summary to mention %q, got %s", want, body)
+			}
+		}
+
+		entries, err := os.ReadDir(filepath.Join(uploadsDir, "summer-roadtrip"))
+		if err != nil {
+			t.Fatalf("read album dir: %v", err)
+		}
+		if len(entries) != len(photos.created) {
+			t.Fatalf("expected only uploaded photos on disk, found %d files for %d photos", len(entries), len(photos.created))
+		}
+	})
+}
+
+func TestAlbumHandlerUploadPhotoAlbumNotFound(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+
+	slug := "missing"
+	uploadsDir := t.TempDir()
+	albums := &stubAlbums{getBySlugErr: storage.ErrNotFound}
+	photos := &stubPhotos{}
+	handler := newAlbumHandler(t, albums, photos, uploadsDir)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	fileWriter, err := writer.CreateFormFile("photo", "sunset.jpg")
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	if _, err := fileWriter.Write([]byte("fake image")); err != nil {
+		t.Fatalf("write photo bytes: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("close writer: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/albums/"+slug+"/photos", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	ctx.Request = req
+	ctx.Params = gin.Params{{Key: "slug", Value: slug}}
+
+	handler.UploadPhoto(ctx)
+	ctx.Writer.WriteHeaderNow()
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", rec.Code)
+	}
+	if photos.createCalled {
+		t.Fatalf("photo Create should not be called")
+	}
+	assertAlbumDirEmpty(t, uploadsDir, slug)
+}
+
+func TestAlbumHandlerDeletePhotoSuccess(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+
+	slug := "summer-roadtrip"
+	uploadsDir := t.TempDir()
+	albumDir := filepath.Join(uploadsDir, slug)
+	if err := os.MkdirAll(albumDir, 0o755); err != nil {
+		t.Fatalf("create album dir: %v", err)
+	}
+	diskPath := filepath.Join(albumDir, "photo.jpg")
+	if err := os.WriteFile(diskPath, []byte("fake image"), 0o644); err != nil {
+		t.Fatalf("write photo: %v", err)
+	}
+
+	albums := &stubAlbums{
+		getBySlug: map[string]storage.Album{
+			slug: {ID: 1, Slug: slug, Title: "Summer Roadtrip"},
+		},
+	}
+	photos := &stubPhotos{
+		getByID: map[int64]storage.Photo{
+			10: {ID: 10, AlbumID: 1, Filename: slug + "/photo.jpg"},
+		},
+	}
+	handler := newAlbumHandler(t, albums, photos, uploadsDir)
+
+	req := httptest.NewRequest(http.MethodPost, "/albums/"+slug+"/photos/10/delete", nil)
+	ctx.Request = req
+	ctx.Params = gin.Params{{Key: "slug", Value: slug}, {Key: "photoID", Value: "10"}}
+
+	handler.DeletePhoto(ctx)
+	ctx.Writer.WriteHeaderNow()
+
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("expected redirect status, got %d", rec.Code)
+	}
+	if location := rec.Header().Get("Location"); location != "/albums/"+slug+"/edit" {
+		t.Fatalf("expected redirect to edit page, got %q", location)
+	}
+	if !photos.deleteCalled || photos.lastDeleteID != 10 {
+		t.Fatalf("expected photo 10 to be deleted, got called=%v id=%d", photos.deleteCalled, photos.lastDeleteID)
+	}
+	if _, err := os.Stat(diskPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected photo file to be removed, stat err: %v", err)
+	}
+}
+
+func TestAlbumHandlerDeletePhotoReturnsToSimilar(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+
+	slug := "summer-roadtrip"
+	albums := &stubAlbums{
+		getBySlug: map[string]storage.Album{
+			slug: {ID: 1, Slug: slug, Title: "Summer Roadtrip"},
+		},
+	}
+	photos := &stubPhotos{
+		getByID: map[int64]storage.Photo{
+			10: {ID: 10, AlbumID: 1, Filename: slug + "/photo.jpg"},
+		},
+	}
+	handler := newAlbumHandler(t, albums, photos, t.TempDir())
+
+	form := url.Values{}
+	form.Set("return_to", "similar")
+	form.Set("distance", "6")
+	req := httptest.NewRequest(http.MethodPost, "/albums/"+slug+"/photos/10/delete", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ctx.Request = req
+	ctx.Params = gin.Params{{Key: "slug", Value: slug}, {Key: "photoID", Value: "10"}}
+
+	handler.DeletePhoto(ctx)
+	ctx.Writer.WriteHeaderNow()
+
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("expected redirect status, got %d", rec.Code)
+	}
+	if location := rec.Header().Get("Location"); location != "/albums/"+slug+"/similar?distance=6" {
+		t.Fatalf("expected redirect back to similar photos, got %q", location)
+	}
+}
+
+func TestAlbumHandlerSimilar(t *testing.T) {
+	slug := "summer-roadtrip"
+	newHandler := func() *handlers.AlbumHandler {
+		albums := &stubAlbums{
+			getBySlug: map[string]storage.Album{
+				slug: {ID: 1, Slug: slug, Title: "Summer Roadtrip"},
+			},
+		}
+		photos := &stubPhotos{
+			listByAlbum: map[int64][]storage.Photo{
+				1: {
+					{ID: 10, AlbumID: 1, Filename: slug + "/burst-1.jpg", Caption: "Burst one", PerceptualHash: "f0f0f0f0f0f0f0f0"},
+					{ID: 11, AlbumID: 1, Filename: slug + "/beach.jpg", Caption: "Beach", PerceptualHash: "0123456789abcdef"},
+					{ID: 12, AlbumID: 1, Filename: slug + "/burst-2.jpg", Caption: "Burst two", PerceptualHash: "f0f0f0f0f0f0f0f3", Width: 4000, Height: 3000, SizeBytes: 2_516_582},
+					{ID: 13, AlbumID: 1, Filename: slug + "/old.jpg", Caption: "Old upload"},
+				},
+			},
+		}
+		return newAlbumHandler(t, albums, photos, t.TempDir())
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		status   int
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "default distance",
+			status:   http.StatusOK,
+			contains: []string{"Group 1 · 2 photos", "Burst one", "Burst two", "4000 × 3000 · 2.4 MB", "1 photo has no fingerprint yet"},
+			excludes: []string{"Beach", "Group 2"},
+		},
+		{
+			name:     "strict distance",
+			query:    "?distance=1",
+			status:   http.StatusOK,
+			contains: []string{"No similar photos found."},
+			excludes: []string{"Burst one"},
+		},
+		{
+			name:   "invalid distance",
+			query:  "?distance=65",
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(rec)
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/albums/"+slug+"/similar"+tc.query, nil)
+			ctx.Params = gin.Params{{Key: "slug", Value: slug}}
+
+			newHandler().Similar(ctx)
+
+			if rec.Code != tc.status {
+				t.Fatalf("expected status %d, got %d", tc.status, rec.Code)
+			}
+			body := rec.Body.String()
+			for _, want := range tc.contains {
+				if !strings.Contains(body, want) {
+					t.Fatalf("expected body to contain %q, got %s", want, body)
+				}
+			}
+			for _, unwanted := range tc.excludes {
+				if strings.Contains(body, unwanted) {
+					t.Fatalf("expected body not to contain %q, got %s", unwanted, body)
+				}
+			}
+		})
+	}
+}
+
+func TestAlbumHandlerDeletePhotoFromOtherAlbum(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+
+	slug := "summer-roadtrip"
+	albums := &stubAlbums{
+		getBySlug: map[string]storage.Album{
+			slug: {ID: 1, Slug: slug, Title: "Summer Roadtrip"},
+		},
+	}
+	photos := &stubPhotos{
+		getByID: map[int64]storage.Photo{
+			10: {ID: 10, AlbumID: 2, Filename: "other-album/photo.jpg"},
+		},
+	}
+	handler := newAlbumHandler(t, albums, photos, t.TempDir())
+
+	req := httptest.NewRequest(http.MethodPost, "/albums/"+slug+"/photos/10/delete", nil)
+	ctx.Request = req
+	ctx.Params = gin.Params{{Key: "slug", Value: slug}, {Key: "photoID", Value: "10"}}
+
+	handler.DeletePhoto(ctx)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", rec.Code)
+	}
+	if photos.deleteCalled {
+		t.Fatalf("photo Delete should not be called for a photo in another album")
+	}
+}
+
+func TestAlbumHandlerDeleteSuccess(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+
+	slug := "summer-roadtrip"
+	uploadsDir := t.TempDir()
+	albumDir := filepath.Join(uploadsDir, slug)
+	if err := os.MkdirAll(albumDir, 0o755); err != nil {
+		t.Fatalf("create album dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(albumDir, "photo.jpg"), []byte("fake image"), 0o644); err != nil {
+		t.Fatalf("write photo: %v", err)
+	}
+
+	albums := &stubAlbums{
+		getBySlug: map[string]storage.Album{
+			slug: {ID: 1, Slug: slug, Title: "Summer Roadtrip"},
+		},
+	}
+	handler := newAlbumHandler(t, albums, &stubPhotos{}, uploadsDir)
+
+	form := make(url.Values)
+	form.Set("confirm", slug)
+	req := httptest.NewRequest(http.MethodPost, "/albums/"+slug+"/delete", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ctx.Request = req
+	ctx.Params = gin.Params{{Key: "slug", Value: slug}}
+
+	handler.Delete(ctx)
+	ctx.Writer.WriteHeaderNow()
+
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("expected redirect status, got %d", rec.Code)
+	}
+	if location := rec.Header().Get("Location"); location != "/albums" {
+		t.Fatalf("expected redirect to /albums, got %q", location)
+	}
+	if !albums.deleteCalled || albums.lastDeleteID != 1 {
+		t.Fatalf("expected album 1 to be deleted, got called=%v id=%d", albums.deleteCalled, albums.lastDeleteID)
+	}
+	if _, err := os.Stat(albumDir); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected album dir to be removed, stat err: %v", err)
+	}
+}
+
+func TestAlbumHandlerDeleteRequiresConfirmation(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+
+	slug := "summer-roadtrip"
+	albums := &stubAlbums{
+		getBySlug: map[string]storage.Album{
+			slug: {ID: 1, Slug: slug, Title: "Summer Roadtrip"},
+		},
+	}
+	handler := newAlbumHandler(t, albums, &stubPhotos{}, t.TempDir())
+
+	form := make(url.Values)
+	form.Set("confirm", "summer")
+	req := httptest.NewRequest(http.MethodPost, "/albums/"+slug+"/delete", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ctx.Request = req
+	ctx.Params = gin.Params{{Key: "slug", Value: slug}}
+
+	handler.Delete(ctx)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", rec.Code)
+	}
+	if albums.deleteCalled {
+		t.Fatalf("album Delete should not be called without confirmation")
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "Type the album slug exactly") {
+		t.Fatalf("expected confirmation error, got %s", body)
+	}
+}
+
+func TestAlbumHandlerPublicUsesCoverAsHero(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+
+	slug := "summer-roadtrip"
+	coverID := int64(11)
+	albums := &stubAlbums{
+		getBySlug: map[string]storage.Album{
+			slug: {ID: 1, Slug: slug, Title: "Summer Roadtrip", CoverPhotoID: &coverID},
+		},
+	}
+	photos := &stubPhotos{
+		listByAlbum: map[int64][]storage.Photo{
+			1: {
+				{ID: 10, AlbumID: 1, Filename: slug + "/first.jpg", Caption: "First"},
+				{ID: 11, AlbumID: 1, Filename: slug + "/cover.jpg", Caption: "Cover"},
+			},
+		},
+	}
+	handler := newAlbumHandler(t, albums, photos, t.TempDir())
+
+	req := httptest.NewRequest(http.MethodGet, "/a/"+slug, nil)
+	ctx.Request = req
+	ctx.Params = gin.Params{{Key: "slug", Value: slug}}
+
+	handler.Public(ctx)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `data-hero-start="1"`) {
+		t.Fatalf("expected hero to start at the cover photo, got %s", body)
+	}
+	if !strings.Contains(body, `data-fullscreen-trigger src="/uploads/summer-roadtrip/cover.jpg"`) {
+		t.Fatalf("expected cover photo as hero image, got %s", body)
+	}
+	if !strings.Contains(body, "Photo 2 of 2") {
+		t.Fatalf("expected hero position to match cover, got %s", body)
+	}
+}
+
+func TestAlbumHandlerPublicServesVariants(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+
+	slug := "summer-roadtrip"
+	albums := &stubAlbums{
+		getBySlug: map[string]storage.Album{
+			slug: {ID: 1, Slug: slug, Title: "Summer Roadtrip"},
+		},
+	}
+	photos := &stubPhotos{
+		listByAlbum: map[int64][]storage.Photo{
+			1: {
+				{
+					ID:       10,
+					AlbumID:  1,
+					Filename: slug + "/beach.jpg",
+					Width:    4000,
+					Height:   3000,
+					Variants: []storage.PhotoVariant{
+						{Name: "thumb", Filename: slug + "/beach_thumb.jpg", Width: 320, Height: 240},
+						{Name: "medium", Filename: slug + "/beach_medium.jpg", Width: 1280, Height: 960},
+					},
+				},
+				{ID: 11, AlbumID: 1, Filename: slug + "/dunes.jpg"},
+			},
+		},
+	}
+	handler := newAlbumHandler(t, albums, photos, t.TempDir())
+
+	req := httptest.NewRequest(http.MethodGet, "/a/"+slug, nil)
+	ctx.Request = req
+	ctx.Params = gin.Params{{Key: "slug", Value: slug}}
+
+	handler.Public(ctx)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `src="/uploads/summer-roadtrip/beach_medium.jpg" srcset="/uploads/summer-roadtrip/beach_thumb.jpg 320w, /uploads/summer-roadtrip/beach_medium.jpg 1280w"`) {
+		t.Fatalf("expected hero to use the medium variant with a srcset, got %s", body)
+	}
+	if !strings.Contains(body, `data-photo-large="/uploads/summer-roadtrip/beach.jpg"`) {
+		t.Fatalf("expected missing large variant to fall back to the original, got %s", body)
+	}
+	if !strings.Contains(body, `<img src="/uploads/summer-roadtrip/dunes.jpg"`) {
+		t.Fatalf("expected photo without variants to use the original, got %s", body)
+	}
+	if !strings.Contains(body, `sizes="(max-width: 1400px) 100vw, 1400px" width="4000" height="3000"`) {
+		t.Fatalf("expected hero to declare its dimensions, got %s", body)
+	}
+	if strings.Count(body, `data-photo-width=`) != 1 {
+		t.Fatalf("expected only measured photos to carry dimensions, got %s", body)
+	}
+}
+
+func TestAlbumHandlerPublicPhotoInfo(t *testing.T) {
+	for _, show := range []bool{true, false} {
+		t.Run(fmt.Sprintf("show=%t", show), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(rec)
+
+			slug := "summer-roadtrip"
+			albums := &stubAlbums{
+				getBySlug: map[string]storage.Album{
+					slug: {ID: 1, Slug: slug, Title: "Summer Roadtrip", ShowPhotoInfo: show},
+				},
+			}
+			photos := &stubPhotos{
+				listByAlbum: map[int64][]storage.Photo{
+					1: {
+						{
+							ID:       10,
+							AlbumID:  1,
+							Filename: slug + "/beach.jpg",
+							Metadata: &storage.PhotoMetadata{
+								CameraMake:   "Canon",
+								CameraModel:  "Canon EOS R5",
+								FocalLength:  50,
+								Aperture:     1.8,
+								ExposureTime: "1/500",
+								ISO:          100,
+							},
+						},
+						{ID: 11, AlbumID: 1, Filename: slug + "/dunes.jpg"},
+					},
+				},
+			}
+			handler := newAlbumHandler(t, albums, photos, t.TempDir())
+
+			req := httptest.NewRequest(http.MethodGet, "/a/"+slug, nil)
+			ctx.Request = req
+			ctx.Params = gin.Params{{Key: "slug", Value: slug}}
+
+			handler.Public(ctx)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", rec.Code)
+			}
+			body := rec.Body.String()
+			info := "Canon EOS R5 · 50 mm · f/1.8 · 1/500 s · ISO 100"
+			if got := strings.Contains(body, info); got != show {
+				t.Fatalf("expected camera details shown=%t, got %s", show, body)
+			}
+			if got := strings.Contains(body, `aria-controls="lightbox-info"`); got != show {
+				t.Fatalf("expected info toggle shown=%t, got %s", show, body)
+			}
+		})
+	}
+}
+
+func TestAlbumHandlerSetCover(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+
+	slug := "summer-roadtrip"
+	albums := &stubAlbums{
+		getBySlug: map[string]storage.Album{
+			slug: {ID: 1, Slug: slug, Title: "Summer Roadtrip"},
+		},
+	}
+	handler := newAlbumHandler(t, albums, &stubPhotos{}, t.TempDir())
+
+	req := httptest.NewRequest(http.MethodPost, "/albums/"+slug+"/photos/11/cover", nil)
+	ctx.Request = req
+	ctx.Params = gin.Params{{Key: "slug", Value: slug}, {Key: "photoID", Value: "11"}}
+
+	handler.SetCover(ctx)
+	ctx.Writer.WriteHeaderNow()
+
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("expected redirect status, got %d", rec.Code)
+	}
+	if !albums.setCoverCalled || albums.lastCoverPhoto != 11 {
+		t.Fatalf("expected cover to be set to photo 11, got called=%v id=%d", albums.setCoverCalled, albums.lastCoverPhoto)
+	}
+}
+
+func TestAlbumHandlerUpdatePhoto(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+
+	slug := "summer-roadtrip"
+	albums := &stubAlbums{
+		getBySlug: map[string]storage.Album{
+			slug: {ID: 1, Slug: slug, Title: "Summer Roadtrip"},
+		},
+	}
+	photos := &stubPhotos{
+		getByID: map[int64]storage.Photo{
+			10: {ID: 10, AlbumID: 1, Filename: slug + "/photo.jpg", Caption: "Sunest"},
+		},
+	}
+	handler := newAlbumHandler(t, albums, photos, t.TempDir())
+
+	form := make(url.Values)
+	form.Set("caption", " Sunset ")
+	form.Set("taken_at", "2025-02-14T18:00")
+	req := httptest.NewRequest(http.MethodPost, "/albums/"+slug+"/photos/10/edit", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ctx.Request = req
+	ctx.Params = gin.Params{{Key: "slug", Value: slug}, {Key: "photoID", Value: "10"}}
+
+	handler.UpdatePhoto(ctx)
+	ctx.Writer.WriteHeaderNow()
+
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("expected redirect status, got %d", rec.Code)
+	}
+	if !photos.updateCalled {
+		t.Fatalf("expected photo Update to be called")
+	}
+	if photos.lastUpdate.Caption == nil || *photos.lastUpdate.Caption != "Sunset" {
+		t.Fatalf("expected caption 'Sunset', got %v", photos.lastUpdate.Caption)
+	}
+	expectedTime := time.Date(2025, 2, 14, 18, 0, 0, 0, time.UTC)
+	if photos.lastUpdate.TakenAt == nil || !photos.lastUpdate.TakenAt.Equal(expectedTime) {
+		t.Fatalf("expected taken_at %v, got %v", expectedTime, photos.lastUpdate.TakenAt)
+	}
+	if photos.lastUpdate.TakenAtSource == nil || *photos.lastUpdate.TakenAtSource != storage.TakenAtSourceManual {
+		t.Fatalf("expected edited taken_at to be marked manual, got %v", photos.lastUpdate.TakenAtSource)
+	}
+}
+
+func TestAlbumHandlerUpdatePhotoKeepsCameraTime(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+
+	slug := "summer-roadtrip"
+	takenAt := time.Date(2024, 7, 14, 16, 30, 5, 250_000_000, time.UTC)
+	albums := &stubAlbums{
+		getBySlug: map[string]storage.Album{
+			slug: {ID: 1, Slug: slug, Title: "Summer Roadtrip"},
+		},
+	}
+	photos := &stubPhotos{
+		getByID: map[int64]storage.Photo{
+			10: {ID: 10, AlbumID: 1, Filename: slug + "/photo.jpg", TakenAt: &takenAt, TakenAtSource: storage.TakenAtSourceCamera},
+		},
+	}
+	handler := newAlbumHandler(t, albums, photos, t.TempDir())
+
+	form := make(url.Values)
+	form.Set("caption", "Sunset")
+	form.Set("taken_at", "2024-07-14T16:30")
+	req := httptest.NewRequest(http.MethodPost, "/albums/"+slug+"/photos/10/edit", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ctx.Request = req
+	ctx.Params = gin.Params{{Key: "slug", Value: slug}, {Key: "photoID", Value: "10"}}
+
+	handler.UpdatePhoto(ctx)
+	ctx.Writer.WriteHeaderNow()
+
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("expected redirect status, got %d", rec.Code)
+	}
+	if photos.lastUpdate.TakenAt != nil || photos.lastUpdate.TakenAtSource != nil || photos.lastUpdate.ClearTakenAt {
+		t.Fatalf("expected unchanged taken_at to be left alone, got %+v", photos.lastUpdate)
+	}
+}
+
+func TestAlbumHandlerReorderPhotos(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+
+	slug := "summer-roadtrip"
+	albums := &stubAlbums{
+		getBySlug: map[string]storage.Album{
+			slug: {ID: 1, Slug: slug, Title: "Summer Roadtrip", SortMode: storage.PhotoSortTakenAt},
+		},
+	}
+	photos := &stubPhotos{}
+	handler := newAlbumHandler(t, albums, photos, t.TempDir())
+
+	form := make(url.Values)
+	form.Set("order", "12,10,11")
+	req := httptest.NewRequest(http.MethodPost, "/albums/"+slug+"/photos/order", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ctx.Request = req
+	ctx.Params = gin.Params{{Key: "slug", Value: slug}}
+
+	handler.ReorderPhotos(ctx)
+	ctx.Writer.WriteHeaderNow()
+
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("expected redirect status, got %d", rec.Code)
+	}
+	if got := photos.lastReorder; len(got) != 3 || got[0] != 12 || got[1] != 10 || got[2] != 11 {
+		t.Fatalf("expected reorder [12 10 11], got %v", got)
+	}
+	if albums.lastUpdate.SortMode == nil || *albums.lastUpdate.SortMode != storage.PhotoSortManual {
+		t.Fatalf("expected album to switch to manual order, got %v", albums.lastUpdate.SortMode)
+	}
+}
+
+func TestAlbumHandlerReorderPhotosStale(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+
+	slug := "summer-roadtrip"
+	albums := &stubAlbums{
+		getBySlug: map[string]storage.Album{
+			slug: {ID: 1, Slug: slug, Title: "Summer Roadtrip", SortMode: storage.PhotoSortManual},
+		},
+	}
+	photos := &stubPhotos{reorderErr: storage.ErrConflict}
+	handler := newAlbumHandler(t, albums, photos, t.TempDir())
+
+	form := make(url.Values)
+	form.Set("order", "10")
+	req := httptest.NewRequest(http.MethodPost, "/albums/"+slug+"/photos/order", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ctx.Request = req
+	ctx.Params = gin.Params{{Key: "slug", Value: slug}}
+
+	handler.ReorderPhotos(ctx)
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected status 409, got %d", rec.Code)
+	}
+	if albums.updateCalled {
+		t.Fatalf("album should not be updated when the order is rejected")
+	}
+}
+
+func assertAlbumDirEmpty(t *testing.T, baseDir, slug string) {
+	t.Helper()
+	albumDir := filepath.Join(baseDir, slug)
+	entries, err := os.ReadDir(albumDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return
+		}
+		t.Fatalf("read album dir: %v", err)
+	}
+	if len(entries) > 0 {
+		t.Fatalf("expected album dir %s to be empty, found %d entries", albumDir, len(entries))
+	}
+}
+
+type stubAlbums struct {
+	list            []storage.Album
+	listErr         error
+	getBySlug       map[string]storage.Album
+	getBySlugErr    error
+	createResp      storage.Album
+	createErr       error
+	createCalled    bool
+	lastCreate      storage.AlbumCreate
+	updateResp      storage.Album
+	updateErr       error
+	updateCalled    bool
+	lastUpdateID    int64
+	lastUpdate      storage.AlbumUpdate
+	lastUpdateTitle string
+	lastUpdateDesc  string
+	deleteErr       error
+	deleteCalled    bool
+	lastDeleteID    int64
+	setCoverErr     error
+	setCoverCalled  bool
+	lastCoverPhoto  int64
+}
+
+func (s *stubAlbums) Create(_ context.Context, input storage.AlbumCreate) (storage.Album, error) {
+	s.createCalled = true
+	s.lastCreate = input
+	if s.createErr != nil {
+		return storage.Album{}, s.createErr
+	}
+	return s.createResp, nil
+}
+
+func (s *stubAlbums) GetByID(context.Context, int64) (storage.Album, error) {
+	panic("unexpected call to GetByID")
+}
+
+func (s *stubAlbums) GetBySlug(_ context.Context, slug string) (storage.Album, error) {
+	if s.getBySlugErr != nil {
+		return storage.Album{}, s.getBySlugErr
+	}
+	if s.getBySlug != nil {
+		if album, ok := s.getBySlug[slug]; ok {
+			return album, nil
+		}
+	}
+	return storage.Album{}, storage.ErrNotFound
+}
+
+func (s *stubAlbums) List(context.Context) ([]storage.Album, error) {
+	return s.list, s.listErr
+}
+
+func (s *stubAlbums) Update(_ context.Context, id int64, input storage.AlbumUpdate) (storage.Album, error) {
+	s.updateCalled = true
+	s.lastUpdateID = id
+	s.lastUpdate = input
+	if input.Title != nil {
+		s.lastUpdateTitle = *input.Title
+	}
+	if input.Description != nil {
+		s.lastUpdateDesc = *input.Description
+	}
+	if s.updateErr != nil {
+		return storage.Album{}, s.updateErr
+	}
+	if s.updateResp.ID == 0 {
+		s.updateResp.ID = id
+	}
+	return s.updateResp, nil
+}
+
+func (s *stubAlbums) Delete(_ context.Context, id int64) error {
+	s.deleteCalled = true
+	s.lastDeleteID = id
+	return s.deleteErr
+}
+
+func (s *stubAlbums) SetCoverPhoto(_ context.Context, _ int64, photoID int64) error {
+	s.setCoverCalled = true
+	s.lastCoverPhoto = photoID
+	return s.setCoverErr
+}
+
+func (s *stubAlbums) ClearCoverPhoto(context.Context, int64) error {
+	panic("unexpected call to ClearCoverPhoto")
+}
+
+func newTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError}))
+}
+
+type stubPhotos struct {
+	listByAlbum  map[int64][]storage.Photo
+	listErr      error
+	covers       map[int64]storage.Photo
+	coversErr    error
+	getByID      map[int64]storage.Photo
+	createResp   storage.Photo
+	createErr    error
+	createCalled bool
+	lastCreate   storage.PhotoCreate
+	created      []storage.PhotoCreate
+	updateErr    error
+	updateCalled bool
+	lastUpdate   storage.PhotoUpdate
+	deleteErr    error
+	deleteCalled bool
+	lastDeleteID int64
+	reorderErr   error
+	lastReorder  []int64
+	duplicate    *storage.Photo
+	lastFindHash string
+}
+
+func (s *stubPhotos) Create(_ context.Context, input storage.PhotoCreate) (storage.Photo, error) {
+	s.createCalled = true
+	s.lastCreate = input
+	if s.createErr != nil {
+		return storage.Photo{}, s.createErr
+	}
+	s.created = append(s.created, input)
+	if s.createResp.ID == 0 {
+		return storage.Photo{ID: int64(len(s.created)), AlbumID: input.AlbumID, Filename: input.Filename}, nil
+	}
+	return s.createResp, nil
+}
+
+func (s *stubPhotos) GetByID(_ context.Context, id int64) (storage.Photo, error) {
+	if s.getByID == nil {
+		panic("unexpected call to GetByID")
+	}
+	if photo, ok := s.getByID[id]; ok {
+		return photo, nil
+	}
+	return storage.Photo{}, storage.ErrNotFound
+}
+
+func (s *stubPhotos) FindByHash(_ context.Context, _ int64, sha256 string) (storage.Photo, error) {
+	s.lastFindHash = sha256
+	if s.duplicate == nil {
+		return storage.Photo{}, storage.ErrNotFound
+	}
+	return *s.duplicate, nil
+}
+
+func (s *stubPhotos) ListByAlbum(_ context.Context, albumID int64) ([]storage.Photo, error) {
+	if s.listErr != nil {
+		return nil, s.listErr
+	}
+	if s.listByAlbum == nil {
+		return nil, nil
+	}
+	return append([]storage.Photo(nil), s.listByAlbum[albumID]...), nil
+}
+
+func (s *stubPhotos) ListCovers(_ context.Context) (map[int64]storage.Photo, error) {
+	if s.coversErr != nil {
+		return nil, s.coversErr
+	}
+	return s.covers, nil
+}
+
+func (s *stubPhotos) Update(_ context.Context, id int64, input storage.PhotoUpdate) (storage.Photo, error) {
+	s.updateCalled = true
+	s.lastUpdate = input
+	if s.updateErr != nil {
+		return storage.Photo{}, s.updateErr
+	}
+	return storage.Photo{ID: id}, nil
+}
+
+func (s *stubPhotos) Reorder(_ context.Context, _ int64, photoIDs []int64) error {
+	s.lastReorder = append([]int64(nil), photoIDs...)
+	return s.reorderErr
+}
+
+func (s *stubPhotos) Delete(_ context.Context, id int64) error {
+	s.deleteCalled = true
+	s.lastDeleteID = id
+	return s.deleteErr
+}
+
+// testJPEG returns a small, valid JPEG for upload tests.
+func testJPEG(t *testing.T) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("encode test jpeg: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func newAlbumHandler(t *testing.T, albums storage.Albums, photos storage.Photos, uploadsDir string) *handlers.AlbumHandler {
+	t.Helper()
+	blobs := newTestBlobs(t, uploadsDir)
+	return handlers.NewAlbumHandler(newTestLogger(), albums, photos, &stubWatchFailures{}, blobs, newTestPipeline(photos, blobs))
+}
+
+func newTestPipeline(photos storage.Photos, blobs blob.Store) *ingest.Pipeline {
+	return ingest.New(newTestLogger(), photos, stubBlobRecords{}, blobs, ingest.LayoutAlbum)
+}
+
+// stubBlobRecords tracks no files, as for photos stored under their album.
+type stubBlobRecords struct{}
+
+func (stubBlobRecords) Claim(context.Context, string) (bool, error)     { return false, nil }
+func (stubBlobRecords) Register(context.Context, string, int64) error   { return nil }
+func (stubBlobRecords) Delete(context.Context, string, time.Time) error { return storage.ErrNotFound }
+func (stubBlobRecords) Get(context.Context, string) (storage.Blob, error) {
+	return storage.Blob{}, storage.ErrNotFound
+}
+func (stubBlobRecords) ListUnreferenced(context.Context, time.Time) ([]storage.Blob, error) {
+	return nil, nil
+}
+
+func newTestBlobs(t *testing.T, uploadsDir string) blob.Store {
 	t.Helper()
-	return handlers.NewAlbumHandler(newTestLogger(), albums, photos, uploadsDir)
+	blobs, err := blob.NewLocal(uploadsDir, "/uploads")
+	if err != nil {
+		t.Fatalf("open blob store: %v", err)
+	}
+	return blobs
 }
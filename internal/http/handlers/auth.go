@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"log/slog"
 	"net/http"
 	"strings"
@@ -8,21 +11,27 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/Oxyrus/memories/internal/http/middleware"
 	"github.com/Oxyrus/memories/internal/http/render"
+	"github.com/Oxyrus/memories/internal/storage"
 	"github.com/Oxyrus/memories/web/pages"
 )
 
 type AuthHandler struct {
 	logger     *slog.Logger
+	sessions   storage.Sessions
 	passcode   string
 	cookieName string
+	sessionTTL time.Duration
 }
 
-func NewAuthHandler(logger *slog.Logger, passcode, cookieName string) *AuthHandler {
+func NewAuthHandler(logger *slog.Logger, sessions storage.Sessions, passcode, cookieName string, sessionTTL time.Duration) *AuthHandler {
 	return &AuthHandler{
 		logger:     logger,
+		sessions:   sessions,
 		passcode:   passcode,
 		cookieName: cookieName,
+		sessionTTL: sessionTTL,
 	}
 }
 
@@ -56,10 +65,57 @@ func (h *AuthHandler) SubmitLogin(c *gin.Context) {
 		redirectTo = "/albums"
 	}
 
-	maxAge := int((14 * 24 * time.Hour).Seconds())
-	secure := c.Request.TLS != nil
-	c.SetCookie(h.cookieName, "1", maxAge, "/", "", secure, true)
+	ctx := c.Request.Context()
+
+	if removed, err := h.sessions.DeleteExpired(ctx, time.Now()); err != nil {
+		h.logger.Warn("failed to prune expired sessions", "error", err)
+	} else if removed > 0 {
+		h.logger.Debug("pruned expired sessions", "count", removed)
+	}
+
+	sessionID, err := generateSessionID()
+	if err != nil {
+		h.logger.Error("failed to generate session id", "error", err)
+		c.String(http.StatusInternalServerError, "failed to sign in")
+		return
+	}
+
+	session, err := h.sessions.Create(ctx, storage.SessionCreate{
+		ID:        sessionID,
+		ExpiresAt: time.Now().Add(h.sessionTTL),
+	})
+	if err != nil {
+		h.logger.Error("failed to create session", "error", err)
+		c.String(http.StatusInternalServerError, "failed to sign in")
+		return
+	}
+
+	middleware.SetSessionCookie(c, h.cookieName, session.ID, h.sessionTTL)
 
 	h.logger.Info("admin login successful", "ip", c.ClientIP())
 	c.Redirect(http.StatusFound, redirectTo)
 }
+
+func (h *AuthHandler) Logout(c *gin.Context) {
+	if sessionID, err := c.Cookie(h.cookieName); err == nil && sessionID != "" {
+		if err := h.sessions.Delete(c.Request.Context(), sessionID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			h.logger.Error("failed to delete session", "error", err)
+			c.String(http.StatusInternalServerError, "failed to sign out")
+			return
+		}
+	}
+
+	middleware.ClearSessionCookie(c, h.cookieName)
+
+	h.logger.Info("admin logout", "ip", c.ClientIP())
+	c.Redirect(http.StatusSeeOther, "/login")
+}
+
+func generateSessionID() (string, error) {
+	const tokenSize = 32
+	buf := make([]byte, tokenSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,119 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Oxyrus/memories/internal/http/handlers"
+	"github.com/Oxyrus/memories/internal/http/middleware"
+	"github.com/Oxyrus/memories/internal/storage"
+	"github.com/Oxyrus/memories/internal/storage/sqlite"
+)
+
+const testCookieName = "memories_admin"
+
+func TestAuthHandlerLoginAndLogout(t *testing.T) {
+	engine, store := newAuthEngine(t)
+	ctx := context.Background()
+
+	rec := submitLogin(engine, "wrong", "")
+	if rec.Code != http.StatusUnauthorized || findCookie(rec, testCookieName) != nil {
+		t.Fatalf("expected a wrong passcode to be refused without a cookie, got %d %v", rec.Code, rec.Header())
+	}
+
+	rec = submitLogin(engine, "secret", "/albums/summer")
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/albums/summer" {
+		t.Fatalf("expected a redirect to the next page, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	cookie := findCookie(rec, testCookieName)
+	if cookie == nil || cookie.Value == "" {
+		t.Fatalf("expected a session cookie, got %v", rec.Header())
+	}
+	if !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+		t.Fatalf("expected an HttpOnly, SameSite=Lax cookie, got %+v", cookie)
+	}
+	session, err := store.Sessions().Get(ctx, cookie.Value)
+	if err != nil {
+		t.Fatalf("expected a session row for the cookie: %v", err)
+	}
+	if !session.ExpiresAt.After(time.Now().Add(23 * time.Hour)) {
+		t.Fatalf("expected the session to last the configured TTL, expires at %v", session.ExpiresAt)
+	}
+
+	if rec := serveWithCookie(engine, http.MethodGet, "/albums", cookie.Value); rec.Code != http.StatusOK {
+		t.Fatalf("expected the session to grant access, got %d", rec.Code)
+	}
+
+	rec = serveWithCookie(engine, http.MethodPost, "/logout", cookie.Value)
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/login" {
+		t.Fatalf("expected a redirect to the login page, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if cleared := findCookie(rec, testCookieName); cleared == nil || cleared.MaxAge >= 0 {
+		t.Fatalf("expected the session cookie to be cleared, got %+v", cleared)
+	}
+	if _, err := store.Sessions().Get(ctx, cookie.Value); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected the session row to be deleted, got %v", err)
+	}
+
+	// Replaying the old cookie no longer grants access.
+	rec = serveWithCookie(engine, http.MethodGet, "/albums", cookie.Value)
+	if rec.Code != http.StatusFound || !strings.HasPrefix(rec.Header().Get("Location"), "/login") {
+		t.Fatalf("expected the replayed cookie to be redirected to login, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func newAuthEngine(t *testing.T) (*gin.Engine, storage.Store) {
+	t.Helper()
+
+	store, err := sqlite.Open(filepath.Join(t.TempDir(), "memories.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	const ttl = 24 * time.Hour
+	logger := newTestLogger()
+	handler := handlers.NewAuthHandler(logger, store.Sessions(), "secret", testCookieName, ttl)
+
+	engine := gin.New()
+	engine.POST("/login", handler.SubmitLogin)
+	engine.POST("/logout", handler.Logout)
+	admin := engine.Group("/", middleware.RequireAdmin(logger, store.Sessions(), testCookieName, ttl))
+	admin.GET("/albums", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return engine, store
+}
+
+func submitLogin(engine *gin.Engine, passcode, next string) *httptest.ResponseRecorder {
+	form := url.Values{"passcode": {passcode}, "next": {next}}
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec
+}
+
+func serveWithCookie(engine *gin.Engine, method, target, sessionID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	req.AddCookie(&http.Cookie{Name: testCookieName, Value: sessionID})
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec
+}
+
+func findCookie(rec *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Oxyrus/memories/internal/storage"
+	"github.com/Oxyrus/memories/internal/zipstream"
+)
+
+// archiveCaptionsFile lists every photo's caption and capture date next to
+// the photos in album downloads.
+const archiveCaptionsFile = "captions.txt"
+
+// maxArchiveNameStem keeps caption-based file names in downloads readable.
+const maxArchiveNameStem = 60
+
+// Download sends the whole album as a ZIP archive to admins.
+func (h *AlbumHandler) Download(c *gin.Context) {
+	h.download(c, false)
+}
+
+// PublicDownload sends the whole album as a ZIP archive to public viewers of
+// albums that allow it.
+func (h *AlbumHandler) PublicDownload(c *gin.Context) {
+	h.download(c, true)
+}
+
+// download streams the album's published files as an uncompressed ZIP.
+// Files are named by position and caption, e.g. "003-sunset-at-the-beach.jpg",
+// in the album's sort order, with a captions.txt manifest. The archive layout
+// is computed from the file sizes up front, so responses carry a
+// Content-Length and honour single Range requests for resumed downloads.
+func (h *AlbumHandler) download(c *gin.Context, public bool) {
+	ctx := c.Request.Context()
+	slug := strings.TrimSpace(c.Param("slug"))
+
+	album, err := h.albums.GetBySlug(ctx, slug)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.String(http.StatusNotFound, "album not found")
+			return
+		}
+		h.logger.Error("failed to load album for download", "slug", slug, "error", err)
+		c.String(http.StatusInternalServerError, "failed to load album")
+		return
+	}
+	if public && !album.AllowDownload {
+		c.String(http.StatusNotFound, "album not found")
+		return
+	}
+
+	entries, etag, err := h.albumArchiveEntries(ctx, album)
+	if err != nil {
+		h.logger.Error("failed to prepare album download", "albumID", album.ID, "error", err)
+		c.String(http.StatusInternalServerError, "failed to prepare download")
+		return
+	}
+	archive := zipstream.New(entries)
+	size := archive.Size()
+
+	c.Header("Accept-Ranges", "bytes")
+	c.Header("ETag", etag)
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, album.Slug))
+
+	start, end := int64(0), size
+	status := http.StatusOK
+	// Multiple ranges are answered with the whole archive, which RFC 9110
+	// allows.
+	rangeHeader := c.GetHeader("Range")
+	if rangeHeader != "" && !strings.Contains(rangeHeader, ",") && ifRangeMatches(c.GetHeader("If-Range"), etag) {
+		var ok bool
+		start, end, ok = parseByteRange(rangeHeader, size)
+		if !ok {
+			c.Header("Content-Range", fmt.Sprintf("bytes */%d", size))
+			c.Status(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		status = http.StatusPartialContent
+		c.Header("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end-1, size))
+	}
+
+	c.Header("Content-Length", strconv.FormatInt(end-start, 10))
+	c.Status(status)
+	if c.Request.Method == http.MethodHead {
+		return
+	}
+
+	if err := archive.WriteRange(c.Writer, start, end); err != nil {
+		// Headers are gone; the client sees a truncated body and can resume.
+		h.logger.Warn("album download interrupted", "albumID", album.ID, "start", start, "end", end, "error", err)
+		return
+	}
+	if start == 0 {
+		h.logger.Info("album downloaded", "albumID", album.ID, "slug", album.Slug, "public", public, "bytes", end-start)
+	}
+}
+
+// albumArchiveEntries lists the files of an album download and an ETag that
+// changes whenever the archive would. Photos whose file is missing are left
+// out.
+func (h *AlbumHandler) albumArchiveEntries(ctx context.Context, album storage.Album) ([]zipstream.Entry, string, error) {
+	photos, err := h.photos.ListByAlbum(ctx, album.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	folder := album.Slug + "/"
+	digits := max(len(strconv.Itoa(len(photos))), 3)
+
+	tag := sha256.New()
+	entries := make([]zipstream.Entry, 0, len(photos)+1)
+	var captions strings.Builder
+	captions.WriteString(album.Title + "\n")
+	if album.Description != "" {
+		captions.WriteString(album.Description + "\n")
+	}
+	captions.WriteString("\n")
+
+	for i, photo := range photos {
+		info, err := h.blobs.Stat(ctx, photo.Filename)
+		if err != nil {
+			h.logger.Warn("skipping missing photo in download", "photoID", photo.ID, "filename", photo.Filename, "error", err)
+			continue
+		}
+
+		name := archivePhotoName(i+1, digits, photo)
+		modified := photo.CreatedAt
+		if photo.TakenAt != nil {
+			modified = *photo.TakenAt
+		}
+		entries = append(entries, zipstream.Entry{
+			Name:     folder + name,
+			Size:     info.Size,
+			Modified: modified,
+			Open: func() (io.ReadCloser, error) {
+				return h.blobs.Get(ctx, info.Key)
+			},
+		})
+		fmt.Fprintf(tag, "%s\x00%d\x00%d\x00", name, info.Size, info.ModTime.UnixNano())
+
+		captions.WriteString(name)
+		if caption := strings.TrimSpace(photo.Caption); caption != "" {
+			captions.WriteString(": " + caption)
+		}
+		if photo.TakenAt != nil {
+			captions.WriteString(" (taken " + formatTimestamp(*photo.TakenAt) + ")")
+		}
+		captions.WriteString("\n")
+	}
+
+	manifest := captions.String()
+	tag.Write([]byte(manifest))
+	entries = append(entries, zipstream.Entry{
+		Name:     folder + archiveCaptionsFile,
+		Size:     int64(len(manifest)),
+		Modified: album.UpdatedAt,
+		Open: func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(manifest)), nil
+		},
+	})
+
+	return entries, `"` + hex.EncodeToString(tag.Sum(nil)[:16]) + `"`, nil
+}
+
+// archivePhotoName names a photo in album downloads after its position and
+// caption, falling back to the original file name.
+func archivePhotoName(position, digits int, photo storage.Photo) string {
+	stem := slugify(photo.Caption)
+	if stem == "" {
+		original := photo.OriginalFilename
+		stem = slugify(strings.TrimSuffix(original, path.Ext(original)))
+	}
+	if len(stem) > maxArchiveNameStem {
+		stem = strings.TrimRight(stem[:maxArchiveNameStem], "-")
+	}
+
+	name := fmt.Sprintf("%0*d", digits, position)
+	if stem != "" {
+		name += "-" + stem
+	}
+	return name + strings.ToLower(path.Ext(photo.Filename))
+}
+
+// ifRangeMatches reports whether a Range request should be honoured given
+// its If-Range header. Only entity tags are compared; dates never match
+// because the archive has no single modification time.
+func ifRangeMatches(ifRange, etag string) bool {
+	return ifRange == "" || ifRange == etag
+}
+
+// parseByteRange parses a single "bytes=" range against a resource of size
+// bytes and returns the half-open interval it selects.
+func parseByteRange(header string, size int64) (start, end int64, ok bool) {
+	spec, found := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !found {
+		return 0, 0, false
+	}
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, 0, false
+	}
+
+	if first == "" {
+		suffix, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || suffix <= 0 {
+			return 0, 0, false
+		}
+		return max(size-suffix, 0), size, size > 0
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, false
+	}
+	end = size
+	if last != "" {
+		lastByte, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || lastByte < start {
+			return 0, 0, false
+		}
+		end = min(lastByte+1, size)
+	}
+	return start, end, true
+}
@@ -0,0 +1,175 @@
+package handlers_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Oxyrus/memories/internal/storage"
+)
+
+func TestAlbumHandlerDownload(t *testing.T) {
+	engine := newDownloadEngine(t, false)
+
+	rec := serveDownload(engine, "/albums/summer-roadtrip/download", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d (%s)", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Length"); got != strconv.Itoa(rec.Body.Len()) {
+		t.Fatalf("expected Content-Length %d, got %q", rec.Body.Len(), got)
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="summer-roadtrip.zip"` {
+		t.Fatalf("unexpected Content-Disposition %q", got)
+	}
+	if rec.Header().Get("Accept-Ranges") != "bytes" || rec.Header().Get("ETag") == "" {
+		t.Fatalf("expected range support headers, got %v", rec.Header())
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	contents := make(map[string]string)
+	var names []string
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", file.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", file.Name, err)
+		}
+		names = append(names, file.Name)
+		contents[file.Name] = string(data)
+	}
+
+	wantNames := []string{
+		"summer-roadtrip/001-sunset-at-the-beach.jpg",
+		"summer-roadtrip/002-img-1234.png",
+		"summer-roadtrip/captions.txt",
+	}
+	if strings.Join(names, ",") != strings.Join(wantNames, ",") {
+		t.Fatalf("expected entries %v, got %v", wantNames, names)
+	}
+	if contents[wantNames[0]] != "sunset bytes" || contents[wantNames[1]] != "png bytes" {
+		t.Fatalf("unexpected photo contents %v", contents)
+	}
+	captions := contents["summer-roadtrip/captions.txt"]
+	for _, want := range []string{"Summer Roadtrip", "001-sunset-at-the-beach.jpg: Sunset at the beach (taken Jul 2, 2024 18:30 UTC)", "002-img-1234.png"} {
+		if !strings.Contains(captions, want) {
+			t.Fatalf("expected captions.txt to contain %q, got %q", want, captions)
+		}
+	}
+}
+
+func TestAlbumHandlerDownloadRange(t *testing.T) {
+	engine := newDownloadEngine(t, false)
+
+	full := serveDownload(engine, "/albums/summer-roadtrip/download", nil)
+	size := full.Body.Len()
+	etag := full.Header().Get("ETag")
+
+	rec := serveDownload(engine, "/albums/summer-roadtrip/download", map[string]string{"Range": "bytes=100-", "If-Range": etag})
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("expected status 206, got %d", rec.Code)
+	}
+	if got, want := rec.Header().Get("Content-Range"), "bytes 100-"+strconv.Itoa(size-1)+"/"+strconv.Itoa(size); got != want {
+		t.Fatalf("expected Content-Range %q, got %q", want, got)
+	}
+	if !bytes.Equal(rec.Body.Bytes(), full.Body.Bytes()[100:]) {
+		t.Fatal("expected the partial body to match the tail of the full archive")
+	}
+
+	rec = serveDownload(engine, "/albums/summer-roadtrip/download", map[string]string{"Range": "bytes=100-", "If-Range": `"stale"`})
+	if rec.Code != http.StatusOK || rec.Body.Len() != size {
+		t.Fatalf("expected a stale If-Range to return the whole archive, got %d with %d bytes", rec.Code, rec.Body.Len())
+	}
+
+	rec = serveDownload(engine, "/albums/summer-roadtrip/download", map[string]string{"Range": "bytes=" + strconv.Itoa(size) + "-"})
+	if rec.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Fatalf("expected status 416, got %d", rec.Code)
+	}
+}
+
+func TestAlbumHandlerPublicDownload(t *testing.T) {
+	for _, allow := range []bool{false, true} {
+		t.Run(strconv.FormatBool(allow), func(t *testing.T) {
+			engine := newDownloadEngine(t, allow)
+
+			rec := serveDownload(engine, "/a/summer-roadtrip/download", nil)
+			want := http.StatusNotFound
+			if allow {
+				want = http.StatusOK
+			}
+			if rec.Code != want {
+				t.Fatalf("expected status %d, got %d", want, rec.Code)
+			}
+
+			rec = serveDownload(engine, "/a/summer-roadtrip", nil)
+			if got := strings.Contains(rec.Body.String(), `href="/a/summer-roadtrip/download"`); got != allow {
+				t.Fatalf("expected download link to be shown=%v, got %v", allow, got)
+			}
+		})
+	}
+}
+
+func newDownloadEngine(t *testing.T, allowDownload bool) *gin.Engine {
+	t.Helper()
+
+	uploadsDir := t.TempDir()
+	albumDir := filepath.Join(uploadsDir, "summer-roadtrip")
+	if err := os.MkdirAll(albumDir, 0o755); err != nil {
+		t.Fatalf("create album dir: %v", err)
+	}
+	for name, content := range map[string]string{"a.jpg": "sunset bytes", "b.png": "png bytes"} {
+		if err := os.WriteFile(filepath.Join(albumDir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write photo: %v", err)
+		}
+	}
+
+	takenAt := time.Date(2024, 7, 2, 18, 30, 0, 0, time.UTC)
+	albums := &stubAlbums{
+		getBySlug: map[string]storage.Album{
+			"summer-roadtrip": {ID: 1, Slug: "summer-roadtrip", Title: "Summer Roadtrip", AllowDownload: allowDownload},
+		},
+	}
+	photos := &stubPhotos{
+		listByAlbum: map[int64][]storage.Photo{
+			1: {
+				{ID: 1, AlbumID: 1, Filename: "summer-roadtrip/a.jpg", OriginalFilename: "DSC0001.JPG", Caption: "Sunset at the beach", TakenAt: &takenAt},
+				{ID: 2, AlbumID: 1, Filename: "summer-roadtrip/b.png", OriginalFilename: "IMG_1234.png"},
+				// Missing files are left out of the archive.
+				{ID: 3, AlbumID: 1, Filename: "summer-roadtrip/gone.jpg", OriginalFilename: "gone.jpg"},
+			},
+		},
+	}
+	handler := newAlbumHandler(t, albums, photos, uploadsDir)
+
+	engine := gin.New()
+	engine.GET("/albums/:slug/download", handler.Download)
+	engine.GET("/a/:slug", handler.Public)
+	engine.GET("/a/:slug/download", handler.PublicDownload)
+	return engine
+}
+
+func serveDownload(engine *gin.Engine, target string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec
+}
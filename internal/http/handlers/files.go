@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Oxyrus/memories/internal/blob"
+)
+
+// FileHandler serves stored photo files under /uploads.
+type FileHandler struct {
+	logger *slog.Logger
+	blobs  blob.Store
+}
+
+func NewFileHandler(logger *slog.Logger, blobs blob.Store) *FileHandler {
+	return &FileHandler{
+		logger: logger,
+		blobs:  blobs,
+	}
+}
+
+// Serve sends the blob named by the filepath parameter. Stores whose URLs
+// point elsewhere, such as S3's presigned URLs, are redirected to instead of
+// proxied. HEAD requests are answered from the blob's details without reading
+// it. Seekable blobs get conditional and range request support from
+// http.ServeContent; other blobs are always sent whole.
+func (h *FileHandler) Serve(c *gin.Context) {
+	ctx := c.Request.Context()
+	key := strings.TrimPrefix(c.Param("filepath"), "/")
+
+	if u := h.blobs.URL(key); u != "" && !strings.HasPrefix(u, "/") {
+		c.Redirect(http.StatusFound, u)
+		return
+	}
+
+	info, err := h.blobs.Stat(ctx, key)
+	if err != nil {
+		h.notFoundOrError(c, key, err)
+		return
+	}
+	if contentType := mime.TypeByExtension(path.Ext(info.Key)); contentType != "" {
+		c.Header("Content-Type", contentType)
+	}
+	if c.Request.Method == http.MethodHead {
+		c.Header("Content-Length", strconv.FormatInt(info.Size, 10))
+		c.Header("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+		c.Status(http.StatusOK)
+		return
+	}
+
+	rc, err := h.blobs.Get(ctx, key)
+	if err != nil {
+		h.notFoundOrError(c, key, err)
+		return
+	}
+	defer rc.Close()
+
+	if rs, ok := rc.(io.ReadSeeker); ok {
+		http.ServeContent(c.Writer, c.Request, path.Base(info.Key), info.ModTime, rs)
+		return
+	}
+
+	c.Header("Accept-Ranges", "none")
+	c.Header("Content-Length", strconv.FormatInt(info.Size, 10))
+	c.Header("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusOK)
+	if _, err := io.Copy(c.Writer, rc); err != nil {
+		h.logger.Warn("failed to send photo file", "key", key, "error", err)
+	}
+}
+
+func (h *FileHandler) notFoundOrError(c *gin.Context, key string, err error) {
+	if errors.Is(err, blob.ErrNotFound) {
+		c.String(http.StatusNotFound, "not found")
+		return
+	}
+	if _, keyErr := blob.CleanKey(key); keyErr != nil {
+		c.String(http.StatusNotFound, "not found")
+		return
+	}
+	h.logger.Error("failed to load photo file", "key", key, "error", err)
+	c.String(http.StatusInternalServerError, "failed to load file")
+}
@@ -0,0 +1,123 @@
+package handlers_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Oxyrus/memories/internal/blob"
+	"github.com/Oxyrus/memories/internal/http/handlers"
+)
+
+func TestFileHandlerServe(t *testing.T) {
+	blobs := newTestBlobs(t, t.TempDir())
+	if err := blobs.Put(context.Background(), "summer-roadtrip/beach.jpg", strings.NewReader("beach photo")); err != nil {
+		t.Fatalf("put blob: %v", err)
+	}
+
+	engine := gin.New()
+	handler := handlers.NewFileHandler(newTestLogger(), blobs)
+	engine.GET("/uploads/*filepath", handler.Serve)
+
+	tests := []struct {
+		name       string
+		path       string
+		rangeValue string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "file", path: "/uploads/summer-roadtrip/beach.jpg", wantStatus: http.StatusOK, wantBody: "beach photo"},
+		{name: "range", path: "/uploads/summer-roadtrip/beach.jpg", rangeValue: "bytes=6-", wantStatus: http.StatusPartialContent, wantBody: "photo"},
+		{name: "missing", path: "/uploads/summer-roadtrip/dunes.jpg", wantStatus: http.StatusNotFound},
+		{name: "directory", path: "/uploads/summer-roadtrip", wantStatus: http.StatusNotFound},
+		{name: "traversal", path: "/uploads/summer-roadtrip/%2e%2e/%2e%2e/secret.jpg", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.rangeValue != "" {
+				req.Header.Set("Range", tt.rangeValue)
+			}
+			engine.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d (%s)", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Fatalf("expected body %q, got %q", tt.wantBody, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK {
+				if got := rec.Header().Get("Content-Type"); got != "image/jpeg" {
+					t.Fatalf("expected image/jpeg, got %q", got)
+				}
+			}
+		})
+	}
+}
+
+func TestFileHandlerHeadDoesNotReadBlob(t *testing.T) {
+	blobs := &countingBlobs{Store: newTestBlobs(t, t.TempDir())}
+	if err := blobs.Put(context.Background(), "summer-roadtrip/beach.jpg", strings.NewReader("beach photo")); err != nil {
+		t.Fatalf("put blob: %v", err)
+	}
+
+	engine := gin.New()
+	engine.HEAD("/uploads/*filepath", handlers.NewFileHandler(newTestLogger(), blobs).Serve)
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/uploads/summer-roadtrip/beach.jpg", nil))
+
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Length") != "11" || rec.Header().Get("Content-Type") != "image/jpeg" {
+		t.Fatalf("expected the file's details, got %d %v", rec.Code, rec.Header())
+	}
+	if blobs.gets != 0 {
+		t.Fatalf("expected HEAD not to open the blob, got %d reads", blobs.gets)
+	}
+}
+
+func TestFileHandlerRedirectsToExternalURL(t *testing.T) {
+	blobs := &countingBlobs{Store: newTestBlobs(t, t.TempDir()), baseURL: "https://bucket.example.com/"}
+
+	engine := gin.New()
+	engine.GET("/uploads/*filepath", handlers.NewFileHandler(newTestLogger(), blobs).Serve)
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/uploads/summer-roadtrip/beach.jpg", nil))
+
+	if rec.Code != http.StatusFound {
+		t.Fatalf("expected a redirect, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Location"); got != "https://bucket.example.com/summer-roadtrip/beach.jpg" {
+		t.Fatalf("expected a redirect to the store's URL, got %q", got)
+	}
+	if blobs.gets != 0 {
+		t.Fatalf("expected the blob not to be proxied, got %d reads", blobs.gets)
+	}
+}
+
+// countingBlobs counts reads of the blobs it wraps and, when baseURL is set,
+// serves them from there like a remote store.
+type countingBlobs struct {
+	blob.Store
+	baseURL string
+	gets    int
+}
+
+func (b *countingBlobs) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	b.gets++
+	return b.Store.Get(ctx, key)
+}
+
+func (b *countingBlobs) URL(key string) string {
+	if b.baseURL != "" {
+		return b.baseURL + key
+	}
+	return b.Store.URL(key)
+}
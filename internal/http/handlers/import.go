@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Oxyrus/memories/internal/ingest"
+	"github.com/Oxyrus/memories/internal/storage"
+	"github.com/Oxyrus/memories/web/pages"
+)
+
+// Limits that keep a hostile or corrupt archive from exhausting the disk.
+// Entry sizes are enforced while copying, so archives that understate them in
+// their headers are cut off as well.
+const (
+	maxArchiveEntries = 10000
+	// maxArchiveEntrySize caps the uncompressed size of a single photo.
+	maxArchiveEntrySize int64 = 512 << 20
+	// maxArchiveTotalSize caps the uncompressed size of all imported photos.
+	maxArchiveTotalSize int64 = 8 << 30
+	// maxArchiveCompressionRatio flags entries that inflate far more than any
+	// photo format does. Small entries are exempt.
+	maxArchiveCompressionRatio = 100
+	archiveRatioMinSize        = 1 << 20
+)
+
+// uploadStatusSkipped marks archive entries that are not imported because
+// they are not photos or break one of the archive limits.
+const uploadStatusSkipped = "skipped"
+
+var errArchiveEntryTooLarge = errors.New("archive entry exceeds the size limit")
+
+// importSummary is the JSON body returned for archive imports.
+type importSummary struct {
+	Imported int             `json:"imported"`
+	Skipped  int             `json:"skipped"`
+	Results  []ingest.Result `json:"results"`
+}
+
+// ImportArchive imports every photo in an uploaded ZIP archive into the album.
+// Entries are extracted one at a time into the pipeline's scratch directory
+// and go through the same pipeline as UploadPhoto, so capture dates come from
+// EXIF data and files are published only once they become photos.
+// Directories, macOS metadata (__MACOSX/, ._ files) and hidden files are
+// ignored; other files that are not photos are reported as skipped.
+func (h *AlbumHandler) ImportArchive(c *gin.Context) {
+	ctx := c.Request.Context()
+	slug := strings.TrimSpace(c.Param("slug"))
+
+	album, err := h.albums.GetBySlug(ctx, slug)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.String(http.StatusNotFound, "album not found")
+			return
+		}
+		h.logger.Error("failed to fetch album for import", "slug", slug, "error", err)
+		c.String(http.StatusInternalServerError, "failed to load album")
+		return
+	}
+
+	fileHeader, err := c.FormFile("archive")
+	if err != nil {
+		c.String(http.StatusBadRequest, "archive file is required")
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		h.logger.Error("failed to open uploaded archive", "error", err)
+		c.String(http.StatusInternalServerError, "failed to read archive")
+		return
+	}
+	defer file.Close()
+
+	archive, err := zip.NewReader(file, fileHeader.Size)
+	if err != nil {
+		h.logger.Warn("rejected invalid zip archive", "albumID", album.ID, "filename", fileHeader.Filename, "error", err)
+		h.renderEdit(c, album, http.StatusUnprocessableEntity, map[string]string{"archive": "The file is not a valid ZIP archive."}, pages.UploadReport{})
+		return
+	}
+	if len(archive.File) > maxArchiveEntries {
+		h.renderEdit(c, album, http.StatusUnprocessableEntity, map[string]string{"archive": fmt.Sprintf("The archive has more than %d entries.", maxArchiveEntries)}, pages.UploadReport{})
+		return
+	}
+
+	opts := ingest.Options{AllowDuplicate: c.PostForm("allow_duplicate") != ""}
+	summary := importSummary{Results: []ingest.Result{}}
+	budget := maxArchiveTotalSize
+
+	for _, entry := range archive.File {
+		name, ok := archiveEntryName(entry)
+		if !ok {
+			continue
+		}
+
+		var result ingest.Result
+		if reason := h.checkArchiveEntry(entry, budget); reason != "" {
+			result = ingest.Result{Filename: name, Status: uploadStatusSkipped, Error: reason}
+		} else {
+			result = h.ingest.Ingest(ctx, album, name, func(dst string) error {
+				written, err := extractArchiveEntry(entry, dst, min(maxArchiveEntrySize, budget))
+				budget -= written
+				return err
+			}, opts)
+		}
+
+		if result.Status == ingest.StatusUploaded {
+			summary.Imported++
+		} else {
+			summary.Skipped++
+		}
+		summary.Results = append(summary.Results, result)
+	}
+	h.logger.Info("zip import finished", "albumID", album.ID, "slug", album.Slug, "imported", summary.Imported, "skipped", summary.Skipped)
+
+	if c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON {
+		c.JSON(http.StatusOK, summary)
+		return
+	}
+
+	h.renderEdit(c, album, http.StatusOK, map[string]string{}, pages.UploadReport{
+		Summary: fmt.Sprintf("%d imported, %d skipped.", summary.Imported, summary.Skipped),
+		Results: toUploadResults(summary.Results),
+	})
+}
+
+// archiveEntryName returns the base name of a file entry, or false for
+// entries that are ignored outright: directories, macOS metadata and hidden
+// files.
+func archiveEntryName(entry *zip.File) (string, bool) {
+	name := strings.ReplaceAll(entry.Name, "\\", "/")
+	if entry.FileInfo().IsDir() || strings.HasSuffix(name, "/") {
+		return "", false
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "__MACOSX" || (strings.HasPrefix(segment, ".") && segment != "." && segment != "..") {
+			return "", false
+		}
+	}
+	return path.Base(name), true
+}
+
+// checkArchiveEntry returns why an entry must be skipped, or "" when it may
+// be imported with budget bytes left for the whole archive.
+func (h *AlbumHandler) checkArchiveEntry(entry *zip.File, budget int64) string {
+	name := strings.ReplaceAll(entry.Name, "\\", "/")
+	if path.IsAbs(name) || strings.Contains(name, ":") || containsDotDot(name) {
+		h.logger.Warn("skipped zip entry with unsafe path", "entry", entry.Name)
+		return "Skipped: the path points outside the archive."
+	}
+	if !ingest.IsImageFile(name) {
+		return "Skipped: not a photo."
+	}
+	if entry.Mode()&os.ModeType != 0 {
+		return "Skipped: not a regular file."
+	}
+
+	size := entry.UncompressedSize64
+	if size > uint64(maxArchiveEntrySize) {
+		return fmt.Sprintf("Skipped: larger than %s.", formatBytes(maxArchiveEntrySize))
+	}
+	if size > uint64(budget) {
+		return fmt.Sprintf("Skipped: the archive exceeds the %s import limit.", formatBytes(maxArchiveTotalSize))
+	}
+	if size > archiveRatioMinSize && (entry.CompressedSize64 == 0 || size/entry.CompressedSize64 > maxArchiveCompressionRatio) {
+		h.logger.Warn("skipped zip entry with suspicious compression ratio", "entry", entry.Name, "compressed", entry.CompressedSize64, "uncompressed", size)
+		return "Skipped: the file is compressed suspiciously well for a photo."
+	}
+	return ""
+}
+
+func containsDotDot(name string) bool {
+	for _, segment := range strings.Split(name, "/") {
+		if segment == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// extractArchiveEntry copies at most limit uncompressed bytes of entry to dst,
+// refusing to replace an existing file. It reports how many bytes were
+// written; dst is removed when the entry is larger than limit or unreadable.
+func extractArchiveEntry(entry *zip.File, dst string, limit int64) (int64, error) {
+	src, err := entry.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return 0, err
+	}
+
+	written, err := io.Copy(out, io.LimitReader(src, limit+1))
+	if err == nil && written > limit {
+		err = errArchiveEntryTooLarge
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(dst)
+	}
+	return written, err
+}
@@ -0,0 +1,180 @@
+package handlers_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Oxyrus/memories/internal/storage"
+)
+
+func TestAlbumHandlerImportArchive(t *testing.T) {
+	archive := testArchive(t, []archiveEntry{
+		{name: "trip/"},
+		{name: "trip/beach.jpg", content: testJPEG(t)},
+		{name: "__MACOSX/trip/._beach.jpg", content: []byte("resource fork")},
+		{name: "trip/.DS_Store", content: []byte("finder")},
+		{name: "trip/notes.txt", content: []byte("packing list")},
+		{name: "../escape.jpg", content: testJPEG(t)},
+		{name: "trip/bomb.jpg", content: make([]byte, 4<<20)},
+		{name: "trip/broken.jpg", content: []byte("not really a jpeg")},
+	})
+
+	t.Run("json", func(t *testing.T) {
+		rec, photos, uploadsDir := serveImport(t, archive, "application/json")
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d (%s)", rec.Code, rec.Body.String())
+		}
+
+		var summary struct {
+			Imported int `json:"imported"`
+			Skipped  int `json:"skipped"`
+			Results  []struct {
+				Filename string `json:"filename"`
+				Status   string `json:"status"`
+				Error    string `json:"error"`
+			} `json:"results"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &summary); err != nil {
+			t.Fatalf("decode summary: %v (%s)", err, rec.Body.String())
+		}
+		if summary.Imported != 1 || summary.Skipped != 4 {
+			t.Fatalf("expected 1 imported and 4 skipped, got %+v", summary)
+		}
+
+		want := map[string]string{
+			"beach.jpg":  "uploaded",
+			"notes.txt":  "skipped",
+			"escape.jpg": "skipped",
+			"bomb.jpg":   "skipped",
+			"broken.jpg": "rejected",
+		}
+		if len(summary.Results) != len(want) {
+			t.Fatalf("expected %d results, got %+v", len(want), summary.Results)
+		}
+		for _, result := range summary.Results {
+			if want[result.Filename] != result.Status {
+				t.Fatalf("expected %s to be %s, got %+v", result.Filename, want[result.Filename], result)
+			}
+		}
+
+		if len(photos.created) != 1 || photos.created[0].OriginalFilename != "beach.jpg" {
+			t.Fatalf("expected only beach.jpg to be created, got %+v", photos.created)
+		}
+		entries, err := os.ReadDir(filepath.Join(uploadsDir, "summer-roadtrip"))
+		if err != nil {
+			t.Fatalf("read album dir: %v", err)
+		}
+		if len(entries) != 1 {
+			t.Fatalf("expected only the imported photo on disk, found %d files", len(entries))
+		}
+	})
+
+	t.Run("html", func(t *testing.T) {
+		rec, _, _ := serveImport(t, archive, "")
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+		body := rec.Body.String()
+		for _, want := range []string{"1 imported, 4 skipped.", "notes.txt", "Skipped: not a photo."} {
+			if !strings.Contains(body, want) {
+				t.Fatalf("expected import report to contain %q, got %s", want, body)
+			}
+		}
+		if strings.Contains(body, "DS_Store") || strings.Contains(body, "__MACOSX") {
+			t.Fatalf("expected macOS metadata to be ignored silently, got %s", body)
+		}
+	})
+}
+
+func TestAlbumHandlerImportArchiveInvalid(t *testing.T) {
+	rec, photos, uploadsDir := serveImport(t, []byte("definitely not a zip"), "")
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "not a valid ZIP archive") {
+		t.Fatalf("expected archive error on the edit page, got %s", rec.Body.String())
+	}
+	if photos.createCalled {
+		t.Fatal("expected no photos to be created")
+	}
+	assertAlbumDirEmpty(t, uploadsDir, "summer-roadtrip")
+}
+
+type archiveEntry struct {
+	name    string
+	content []byte
+}
+
+func testArchive(t *testing.T, entries []archiveEntry) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	writer := zip.NewWriter(buf)
+	for _, entry := range entries {
+		w, err := writer.Create(entry.name)
+		if err != nil {
+			t.Fatalf("create zip entry %s: %v", entry.name, err)
+		}
+		if _, err := w.Write(entry.content); err != nil {
+			t.Fatalf("write zip entry %s: %v", entry.name, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func serveImport(t *testing.T, archive []byte, accept string) (*httptest.ResponseRecorder, *stubPhotos, string) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+
+	slug := "summer-roadtrip"
+	uploadsDir := t.TempDir()
+	albums := &stubAlbums{
+		getBySlug: map[string]storage.Album{
+			slug: {ID: 1, Slug: slug, Title: "Summer Roadtrip"},
+		},
+	}
+	photos := &stubPhotos{}
+	handler := newAlbumHandler(t, albums, photos, uploadsDir)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	fileWriter, err := writer.CreateFormFile("archive", "photos.zip")
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	if _, err := fileWriter.Write(archive); err != nil {
+		t.Fatalf("write archive: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("close writer: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/albums/"+slug+"/import", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	ctx.Request = req
+	ctx.Params = gin.Params{{Key: "slug", Value: slug}}
+
+	handler.ImportArchive(ctx)
+	return rec, photos, uploadsDir
+}
@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Oxyrus/memories/internal/ingest"
+	"github.com/Oxyrus/memories/internal/storage"
+	"github.com/Oxyrus/memories/internal/tus"
+)
+
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,expiration,termination"
+	// tusMaxSize caps the declared length of a resumable upload.
+	tusMaxSize int64 = 4 << 30
+	// tusChunkContentType is the only body type accepted by PATCH requests.
+	tusChunkContentType = "application/offset+octet-stream"
+)
+
+// ResumableUploadHandler serves the tus 1.0 resumable upload protocol under
+// /albums/:slug/uploads. Chunks are staged until the upload is complete, then
+// the file goes through the same sanitize-and-persist path as UploadPhoto.
+//
+// Clients describe the photo with Upload-Metadata keys: filename (or name),
+// caption, taken_at (YYYY-MM-DDTHH:MM) and allow_duplicate.
+type ResumableUploadHandler struct {
+	logger  *slog.Logger
+	albums  *AlbumHandler
+	staging *tus.Store
+}
+
+func NewResumableUploadHandler(logger *slog.Logger, albums *AlbumHandler, staging *tus.Store) *ResumableUploadHandler {
+	return &ResumableUploadHandler{
+		logger:  logger,
+		albums:  albums,
+		staging: staging,
+	}
+}
+
+// Options advertises the protocol version and supported extensions.
+func (h *ResumableUploadHandler) Options(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Tus-Version", tusVersion)
+	c.Header("Tus-Extension", tusExtensions)
+	c.Header("Tus-Max-Size", strconv.FormatInt(tusMaxSize, 10))
+	c.Status(http.StatusNoContent)
+}
+
+// Create starts a new upload for the album and returns its URL in Location.
+func (h *ResumableUploadHandler) Create(c *gin.Context) {
+	if !h.checkVersion(c) {
+		return
+	}
+
+	album, ok := h.loadAlbum(c)
+	if !ok {
+		return
+	}
+
+	lengthValue := c.GetHeader("Upload-Length")
+	if lengthValue == "" {
+		if c.GetHeader("Upload-Defer-Length") != "" {
+			c.String(http.StatusBadRequest, "deferred upload length is not supported")
+			return
+		}
+		c.String(http.StatusBadRequest, "Upload-Length is required")
+		return
+	}
+	length, err := strconv.ParseInt(lengthValue, 10, 64)
+	if err != nil || length <= 0 {
+		c.String(http.StatusBadRequest, "invalid Upload-Length")
+		return
+	}
+	if length > tusMaxSize {
+		c.String(http.StatusRequestEntityTooLarge, "upload exceeds the maximum size")
+		return
+	}
+
+	metadata, err := parseUploadMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "invalid Upload-Metadata")
+		return
+	}
+	if _, err := uploadOptionsFromMetadata(metadata); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	upload, err := h.staging.Create(album.ID, length, metadata)
+	if err != nil {
+		h.logger.Error("failed to create resumable upload", "albumID", album.ID, "error", err)
+		c.String(http.StatusInternalServerError, "failed to create upload")
+		return
+	}
+
+	h.logger.Info("resumable upload created", "albumID", album.ID, "uploadID", upload.ID, "length", length)
+	c.Header("Location", fmt.Sprintf("/albums/%s/uploads/%s", album.Slug, upload.ID))
+	c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusCreated)
+}
+
+// Head reports how many bytes of the upload the server has received.
+func (h *ResumableUploadHandler) Head(c *gin.Context) {
+	if !h.checkVersion(c) {
+		return
+	}
+
+	_, upload, ok := h.loadUpload(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	if len(upload.Metadata) > 0 {
+		c.Header("Upload-Metadata", formatUploadMetadata(upload.Metadata))
+	}
+	c.Status(http.StatusOK)
+}
+
+// Patch appends a chunk at Upload-Offset. The request that delivers the last
+// byte publishes the photo; if the photo is rejected, the response carries the
+// same per-file JSON result as a batch upload.
+func (h *ResumableUploadHandler) Patch(c *gin.Context) {
+	if !h.checkVersion(c) {
+		return
+	}
+
+	if c.ContentType() != tusChunkContentType {
+		c.String(http.StatusUnsupportedMediaType, "Content-Type must be "+tusChunkContentType)
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.String(http.StatusBadRequest, "invalid Upload-Offset")
+		return
+	}
+
+	album, upload, ok := h.loadUpload(c)
+	if !ok {
+		return
+	}
+
+	upload, err = h.staging.Append(upload.ID, offset, c.Request.Body)
+	switch {
+	case err == nil:
+	case errors.Is(err, tus.ErrOffsetMismatch):
+		c.String(http.StatusConflict, "Upload-Offset does not match the current offset")
+		return
+	case errors.Is(err, tus.ErrTooLarge):
+		c.String(http.StatusRequestEntityTooLarge, "chunk exceeds the upload length")
+		return
+	default:
+		if !h.writeUploadError(c, err) {
+			// The client usually went away mid-chunk; what arrived is kept.
+			h.logger.Warn("resumable upload chunk interrupted", "uploadID", upload.ID, "offset", upload.Offset, "error", err)
+			c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+			c.String(http.StatusInternalServerError, "failed to store chunk")
+		}
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	if !upload.Complete() {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	// The photo is processed even if the client goes away meanwhile; when
+	// processing fails the staged file is kept, so a retried empty PATCH at
+	// the final offset processes it again instead of starting over.
+	ctx := context.WithoutCancel(c.Request.Context())
+	var result ingest.Result
+	err = h.staging.Finish(upload.ID, func(upload tus.Upload, stagedPath string) error {
+		opts, err := uploadOptionsFromMetadata(upload.Metadata)
+		if err != nil {
+			return err
+		}
+		name := upload.Metadata["filename"]
+		if name == "" {
+			name = upload.Metadata["name"]
+		}
+		if name == "" {
+			name = upload.ID
+		}
+		result = h.albums.ingest.Ingest(ctx, album, name, func(dst string) error {
+			return linkOrCopyFile(stagedPath, dst)
+		}, opts)
+		if result.Status == ingest.StatusFailed {
+			return errIngestFailed
+		}
+		return nil
+	})
+	if errors.Is(err, errIngestFailed) {
+		h.logger.Warn("resumable upload kept after processing failed", "albumID", album.ID, "uploadID", upload.ID)
+		c.JSON(uploadHTTPStatus(result.Status), result)
+		return
+	}
+	if err != nil {
+		if !h.writeUploadError(c, err) {
+			h.logger.Error("failed to finish resumable upload", "uploadID", upload.ID, "error", err)
+			c.String(http.StatusInternalServerError, "failed to save photo")
+		}
+		return
+	}
+
+	h.logger.Info("resumable upload finished", "albumID", album.ID, "uploadID", upload.ID, "status", result.Status)
+	if result.Status != ingest.StatusUploaded {
+		c.JSON(uploadHTTPStatus(result.Status), result)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Terminate discards an unfinished upload.
+func (h *ResumableUploadHandler) Terminate(c *gin.Context) {
+	if !h.checkVersion(c) {
+		return
+	}
+
+	_, upload, ok := h.loadUpload(c)
+	if !ok {
+		return
+	}
+
+	if err := h.staging.Remove(upload.ID); err != nil {
+		if !h.writeUploadError(c, err) {
+			h.logger.Error("failed to remove resumable upload", "uploadID", upload.ID, "error", err)
+			c.String(http.StatusInternalServerError, "failed to remove upload")
+		}
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// checkVersion sets the Tus-Resumable response header and rejects requests
+// made for another protocol version.
+func (h *ResumableUploadHandler) checkVersion(c *gin.Context) bool {
+	c.Header("Tus-Resumable", tusVersion)
+	if c.GetHeader("Tus-Resumable") != tusVersion {
+		c.Header("Tus-Version", tusVersion)
+		c.String(http.StatusPreconditionFailed, "unsupported tus version")
+		return false
+	}
+	return true
+}
+
+func (h *ResumableUploadHandler) loadAlbum(c *gin.Context) (storage.Album, bool) {
+	slug := strings.TrimSpace(c.Param("slug"))
+	album, err := h.albums.albums.GetBySlug(c.Request.Context(), slug)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.String(http.StatusNotFound, "album not found")
+			return storage.Album{}, false
+		}
+		h.logger.Error("failed to load album", "slug", slug, "error", err)
+		c.String(http.StatusInternalServerError, "failed to load album")
+		return storage.Album{}, false
+	}
+	return album, true
+}
+
+// loadUpload resolves the album and the upload named in the URL. Uploads are
+// only visible under the album they were created for.
+func (h *ResumableUploadHandler) loadUpload(c *gin.Context) (storage.Album, tus.Upload, bool) {
+	album, ok := h.loadAlbum(c)
+	if !ok {
+		return storage.Album{}, tus.Upload{}, false
+	}
+
+	upload, err := h.staging.Get(c.Param("uploadID"))
+	if err == nil && upload.AlbumID != album.ID {
+		err = tus.ErrNotFound
+	}
+	if err != nil {
+		if !h.writeUploadError(c, err) {
+			h.logger.Error("failed to load resumable upload", "uploadID", c.Param("uploadID"), "error", err)
+			c.String(http.StatusInternalServerError, "failed to load upload")
+		}
+		return storage.Album{}, tus.Upload{}, false
+	}
+	return album, upload, true
+}
+
+// writeUploadError answers the errors every upload request can run into. It
+// reports false for anything else so the caller can log and respond.
+func (h *ResumableUploadHandler) writeUploadError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, tus.ErrNotFound):
+		c.String(http.StatusNotFound, "upload not found")
+	case errors.Is(err, tus.ErrExpired):
+		c.String(http.StatusGone, "upload expired")
+	case errors.Is(err, tus.ErrLocked):
+		c.String(http.StatusLocked, "upload is in use by another request")
+	default:
+		return false
+	}
+	return true
+}
+
+// errIngestFailed keeps a finished upload staged when its photo could not be
+// processed for a reason that may go away, such as a storage error.
+var errIngestFailed = errors.New("resumable upload could not be processed")
+
+// uploadOptionsFromMetadata reads the per-photo form values from tus
+// Upload-Metadata.
+func uploadOptionsFromMetadata(metadata map[string]string) (ingest.Options, error) {
+	opts := ingest.Options{
+		Caption: strings.TrimSpace(metadata["caption"]),
+	}
+	switch strings.ToLower(strings.TrimSpace(metadata["allow_duplicate"])) {
+	case "", "0", "false", "off":
+	default:
+		opts.AllowDuplicate = true
+	}
+	if takenAtValue := strings.TrimSpace(metadata["taken_at"]); takenAtValue != "" {
+		parsed, err := time.Parse(formDateTimeLayout, takenAtValue)
+		if err != nil {
+			return ingest.Options{}, errors.New("invalid taken_at format")
+		}
+		utc := parsed.UTC()
+		opts.TakenAt = &utc
+	}
+	return opts, nil
+}
+
+// parseUploadMetadata decodes an Upload-Metadata header: comma-separated
+// pairs of a key and an optional base64-encoded value.
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("empty metadata key")
+		}
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("metadata %q: %w", key, err)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+func formatUploadMetadata(metadata map[string]string) string {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+" "+base64.StdEncoding.EncodeToString([]byte(metadata[key])))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// linkOrCopyFile publishes a staged file at dst, refusing to replace an
+// existing file. It hard-links when both paths share a filesystem.
+func linkOrCopyFile(src, dst string) error {
+	err := os.Link(src, dst)
+	if err == nil {
+		// Staged files are private; published ones are served to everyone.
+		return os.Chmod(dst, 0o644)
+	}
+	if errors.Is(err, os.ErrExist) {
+		return err
+	}
+	return ingest.CopyFile(src)(dst)
+}
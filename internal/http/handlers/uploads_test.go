@@ -0,0 +1,278 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Oxyrus/memories/internal/http/handlers"
+	"github.com/Oxyrus/memories/internal/storage"
+	"github.com/Oxyrus/memories/internal/tus"
+)
+
+func TestResumableUploadLifecycle(t *testing.T) {
+	engine, photos, uploadsDir, stagingDir := newResumableUploadEngine(t)
+	photo := testJPEG(t)
+
+	rec := serveTus(engine, http.MethodOptions, "/albums/summer-roadtrip/uploads", nil, nil)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Tus-Version") != "1.0.0" {
+		t.Fatalf("expected OPTIONS to advertise tus 1.0.0, got %d %v", rec.Code, rec.Header())
+	}
+
+	rec = serveTus(engine, http.MethodPost, "/albums/summer-roadtrip/uploads", map[string]string{
+		"Upload-Length":   strconv.Itoa(len(photo)),
+		"Upload-Metadata": "filename " + base64.StdEncoding.EncodeToString([]byte("beach.jpg")) + ",caption " + base64.StdEncoding.EncodeToString([]byte("Sunset")),
+	}, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d (%s)", rec.Code, rec.Body.String())
+	}
+	location := rec.Header().Get("Location")
+	if location == "" || rec.Header().Get("Upload-Expires") == "" {
+		t.Fatalf("expected Location and Upload-Expires headers, got %v", rec.Header())
+	}
+
+	half := len(photo) / 2
+	rec = serveTus(engine, http.MethodPatch, location, map[string]string{"Upload-Offset": "0"}, photo[:half])
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Upload-Offset") != strconv.Itoa(half) {
+		t.Fatalf("expected first chunk to be accepted, got %d offset %q", rec.Code, rec.Header().Get("Upload-Offset"))
+	}
+	if photos.createCalled {
+		t.Fatal("expected no photo before the upload is complete")
+	}
+
+	rec = serveTus(engine, http.MethodHead, location, nil, nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Upload-Offset") != strconv.Itoa(half) || rec.Header().Get("Upload-Length") != strconv.Itoa(len(photo)) {
+		t.Fatalf("expected HEAD to report offset %d, got %d %v", half, rec.Code, rec.Header())
+	}
+
+	rec = serveTus(engine, http.MethodPatch, location, map[string]string{"Upload-Offset": "0"}, photo)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected a stale offset to be rejected with 409, got %d", rec.Code)
+	}
+
+	rec = serveTus(engine, http.MethodPatch, location, map[string]string{"Upload-Offset": strconv.Itoa(half)}, photo[half:])
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected final chunk to be accepted, got %d (%s)", rec.Code, rec.Body.String())
+	}
+
+	if !photos.createCalled {
+		t.Fatal("expected the finished upload to be recorded as a photo")
+	}
+	if photos.lastCreate.OriginalFilename != "beach.jpg" || photos.lastCreate.Caption != "Sunset" {
+		t.Fatalf("expected metadata to be applied, got %+v", photos.lastCreate)
+	}
+	if photos.lastCreate.MimeType != "image/jpeg" || photos.lastCreate.Width != 8 {
+		t.Fatalf("expected the file to be inspected, got %+v", photos.lastCreate)
+	}
+	if _, err := os.Stat(filepath.Join(uploadsDir, filepath.FromSlash(photos.lastCreate.Filename))); err != nil {
+		t.Fatalf("expected published file: %v", err)
+	}
+
+	entries, err := os.ReadDir(stagingDir)
+	if err != nil {
+		t.Fatalf("read staging dir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected staging dir to be empty, found %d entries", len(entries))
+	}
+
+	rec = serveTus(engine, http.MethodHead, location, nil, nil)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected finished upload to be gone, got %d", rec.Code)
+	}
+}
+
+func TestResumableUploadKeepsFailedUpload(t *testing.T) {
+	engine, photos, _, _ := newResumableUploadEngine(t)
+	photo := testJPEG(t)
+
+	rec := serveTus(engine, http.MethodPost, "/albums/summer-roadtrip/uploads", map[string]string{
+		"Upload-Length":   strconv.Itoa(len(photo)),
+		"Upload-Metadata": "filename " + base64.StdEncoding.EncodeToString([]byte("beach.jpg")),
+	}, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", rec.Code)
+	}
+	location := rec.Header().Get("Location")
+	full := strconv.Itoa(len(photo))
+
+	// The client goes away while the final chunk is processed and the
+	// database is briefly unavailable.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	photos.createErr = errors.New("database is locked")
+	rec = serveTusContext(ctx, engine, http.MethodPatch, location, map[string]string{"Upload-Offset": "0"}, photo)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d (%s)", rec.Code, rec.Body.String())
+	}
+
+	rec = serveTus(engine, http.MethodHead, location, nil, nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Upload-Offset") != full {
+		t.Fatalf("expected HEAD to report the full offset %s, got %d %v", full, rec.Code, rec.Header())
+	}
+
+	// An empty PATCH at the final offset processes the upload again, even
+	// when the client does not wait for the response.
+	photos.createErr = nil
+	rec = serveTusContext(ctx, engine, http.MethodPatch, location, map[string]string{"Upload-Offset": full}, nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected the retried upload to be accepted, got %d (%s)", rec.Code, rec.Body.String())
+	}
+	if len(photos.created) != 1 || photos.lastCreate.OriginalFilename != "beach.jpg" {
+		t.Fatalf("expected the photo to be recorded once, got %+v", photos.created)
+	}
+	if rec = serveTus(engine, http.MethodHead, location, nil, nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected finished upload to be gone, got %d", rec.Code)
+	}
+}
+
+func TestResumableUploadRejectsUnsupportedFile(t *testing.T) {
+	engine, photos, uploadsDir, _ := newResumableUploadEngine(t)
+	content := []byte("not an image")
+
+	rec := serveTus(engine, http.MethodPost, "/albums/summer-roadtrip/uploads", map[string]string{
+		"Upload-Length":   strconv.Itoa(len(content)),
+		"Upload-Metadata": "filename " + base64.StdEncoding.EncodeToString([]byte("notes.txt")),
+	}, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", rec.Code)
+	}
+
+	rec = serveTus(engine, http.MethodPatch, rec.Header().Get("Location"), map[string]string{"Upload-Offset": "0"}, content)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", rec.Code)
+	}
+
+	var result struct {
+		Filename string `json:"filename"`
+		Status   string `json:"status"`
+		Error    string `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("decode result: %v (%s)", err, rec.Body.String())
+	}
+	if result.Filename != "notes.txt" || result.Status != "rejected" || result.Error == "" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if photos.createCalled {
+		t.Fatal("expected no photo to be created")
+	}
+	assertAlbumDirEmpty(t, uploadsDir, "summer-roadtrip")
+}
+
+func TestResumableUploadValidatesRequests(t *testing.T) {
+	engine, _, _, _ := newResumableUploadEngine(t)
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		headers map[string]string
+		want    int
+	}{
+		{
+			name:    "missing version",
+			method:  http.MethodPost,
+			path:    "/albums/summer-roadtrip/uploads",
+			headers: map[string]string{"Tus-Resumable": "", "Upload-Length": "10"},
+			want:    http.StatusPreconditionFailed,
+		},
+		{
+			name:   "missing length",
+			method: http.MethodPost,
+			path:   "/albums/summer-roadtrip/uploads",
+			want:   http.StatusBadRequest,
+		},
+		{
+			name:    "invalid taken_at",
+			method:  http.MethodPost,
+			path:    "/albums/summer-roadtrip/uploads",
+			headers: map[string]string{"Upload-Length": "10", "Upload-Metadata": "taken_at " + base64.StdEncoding.EncodeToString([]byte("yesterday"))},
+			want:    http.StatusBadRequest,
+		},
+		{
+			name:    "unknown album",
+			method:  http.MethodPost,
+			path:    "/albums/missing/uploads",
+			headers: map[string]string{"Upload-Length": "10"},
+			want:    http.StatusNotFound,
+		},
+		{
+			name:   "unknown upload",
+			method: http.MethodHead,
+			path:   "/albums/summer-roadtrip/uploads/0123456789abcdef0123456789abcdef",
+			want:   http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveTus(engine, tt.method, tt.path, tt.headers, nil)
+			if rec.Code != tt.want {
+				t.Fatalf("expected status %d, got %d (%s)", tt.want, rec.Code, rec.Body.String())
+			}
+			if rec.Header().Get("Tus-Resumable") != "1.0.0" {
+				t.Fatalf("expected Tus-Resumable header on every response, got %v", rec.Header())
+			}
+		})
+	}
+}
+
+func newResumableUploadEngine(t *testing.T) (*gin.Engine, *stubPhotos, string, string) {
+	t.Helper()
+
+	uploadsDir := t.TempDir()
+	stagingDir := t.TempDir()
+	staging, err := tus.NewStore(stagingDir, time.Hour)
+	if err != nil {
+		t.Fatalf("new staging store: %v", err)
+	}
+
+	albums := &stubAlbums{
+		getBySlug: map[string]storage.Album{
+			"summer-roadtrip": {ID: 1, Slug: "summer-roadtrip", Title: "Summer Roadtrip"},
+		},
+	}
+	photos := &stubPhotos{}
+	handler := handlers.NewResumableUploadHandler(newTestLogger(), newAlbumHandler(t, albums, photos, uploadsDir), staging)
+
+	engine := gin.New()
+	engine.OPTIONS("/albums/:slug/uploads", handler.Options)
+	engine.POST("/albums/:slug/uploads", handler.Create)
+	engine.HEAD("/albums/:slug/uploads/:uploadID", handler.Head)
+	engine.PATCH("/albums/:slug/uploads/:uploadID", handler.Patch)
+	engine.DELETE("/albums/:slug/uploads/:uploadID", handler.Terminate)
+	return engine, photos, uploadsDir, stagingDir
+}
+
+// serveTus sends a tus request; Tus-Resumable defaults to 1.0.0 and PATCH
+// bodies are sent as application/offset+octet-stream.
+func serveTus(engine *gin.Engine, method, target string, headers map[string]string, body []byte) *httptest.ResponseRecorder {
+	return serveTusContext(context.Background(), engine, method, target, headers, body)
+}
+
+func serveTusContext(ctx context.Context, engine *gin.Engine, method, target string, headers map[string]string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	if method == http.MethodPatch {
+		req.Header.Set("Content-Type", "application/offset+octet-stream")
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec
+}
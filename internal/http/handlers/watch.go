@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Oxyrus/memories/internal/storage"
+	"github.com/Oxyrus/memories/web/pages"
+)
+
+// DismissWatchFailure removes a watch folder problem from the albums page.
+// The file itself is left where the watcher put it.
+func (h *AlbumHandler) DismissWatchFailure(c *gin.Context) {
+	failureID, err := strconv.ParseInt(c.Param("failureID"), 10, 64)
+	if err != nil || failureID <= 0 {
+		c.String(http.StatusNotFound, "failure not found")
+		return
+	}
+
+	if err := h.watchFailures.Delete(c.Request.Context(), failureID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.String(http.StatusNotFound, "failure not found")
+			return
+		}
+
+		h.logger.Error("failed to dismiss watch folder failure", "failureID", failureID, "error", err)
+		c.String(http.StatusInternalServerError, "failed to dismiss failure")
+		return
+	}
+
+	h.logger.Info("watch folder failure dismissed", "failureID", failureID)
+	c.Redirect(http.StatusSeeOther, "/albums")
+}
+
+func toWatchFailureItems(failures []storage.WatchFailure) []pages.WatchFailureItem {
+	items := make([]pages.WatchFailureItem, 0, len(failures))
+	for _, failure := range failures {
+		item := pages.WatchFailureItem{
+			Path:          failure.Path,
+			Message:       failure.Error,
+			SeenAt:        formatTimestamp(failure.UpdatedAt),
+			DismissAction: fmt.Sprintf("/watch/failures/%d/dismiss", failure.ID),
+		}
+		if failure.AlbumSlug != "" {
+			item.AlbumHref = fmt.Sprintf("/albums/%s/edit", failure.AlbumSlug)
+		}
+		items = append(items, item)
+	}
+	return items
+}
@@ -0,0 +1,130 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Oxyrus/memories/internal/http/handlers"
+	"github.com/Oxyrus/memories/internal/storage"
+)
+
+func TestAlbumHandlerListShowsWatchFailures(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/albums", nil)
+
+	failures := &stubWatchFailures{list: []storage.WatchFailure{
+		{
+			ID:        7,
+			Path:      "summer-roadtrip/broken.jpg",
+			AlbumSlug: "summer-roadtrip",
+			Error:     "This photo could not be read.",
+			UpdatedAt: time.Date(2025, 2, 15, 10, 30, 0, 0, time.UTC),
+		},
+		{ID: 8, Path: "winter/", AlbumSlug: "winter", Error: "No album has the slug “winter”."},
+	}}
+	blobs := newTestBlobs(t, t.TempDir())
+	handler := handlers.NewAlbumHandler(newTestLogger(), &stubAlbums{}, &stubPhotos{}, failures, blobs, newTestPipeline(&stubPhotos{}, blobs))
+
+	handler.List(ctx)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"Watch folder problems",
+		"summer-roadtrip/broken.jpg",
+		"This photo could not be read.",
+		"/albums/summer-roadtrip/edit",
+		"/watch/failures/7/dismiss",
+		"/watch/failures/8/dismiss",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected albums page to contain %q, got %s", want, body)
+		}
+	}
+}
+
+func TestAlbumHandlerListWithoutWatchFailures(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/albums", nil)
+
+	handler := newAlbumHandler(t, &stubAlbums{}, &stubPhotos{}, t.TempDir())
+	handler.List(ctx)
+
+	if strings.Contains(rec.Body.String(), "Watch folder problems") {
+		t.Fatalf("expected no watch folder section, got %s", rec.Body.String())
+	}
+}
+
+func TestAlbumHandlerDismissWatchFailure(t *testing.T) {
+	tests := []struct {
+		name       string
+		failureID  string
+		deleteErr  error
+		wantStatus int
+	}{
+		{name: "dismissed", failureID: "7", wantStatus: http.StatusSeeOther},
+		{name: "unknown", failureID: "9", deleteErr: storage.ErrNotFound, wantStatus: http.StatusNotFound},
+		{name: "invalid id", failureID: "abc", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(rec)
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/watch/failures/"+tt.failureID+"/dismiss", nil)
+			ctx.Params = gin.Params{{Key: "failureID", Value: tt.failureID}}
+
+			failures := &stubWatchFailures{deleteErr: tt.deleteErr}
+			blobs := newTestBlobs(t, t.TempDir())
+			handler := handlers.NewAlbumHandler(newTestLogger(), &stubAlbums{}, &stubPhotos{}, failures, blobs, newTestPipeline(&stubPhotos{}, blobs))
+			handler.DismissWatchFailure(ctx)
+			ctx.Writer.WriteHeaderNow()
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if tt.wantStatus == http.StatusSeeOther {
+				if got := rec.Header().Get("Location"); got != "/albums" {
+					t.Fatalf("expected redirect to /albums, got %q", got)
+				}
+				if failures.lastDeleteID != 7 {
+					t.Fatalf("expected failure 7 to be deleted, got %d", failures.lastDeleteID)
+				}
+			}
+		})
+	}
+}
+
+type stubWatchFailures struct {
+	list         []storage.WatchFailure
+	listErr      error
+	deleteErr    error
+	lastDeleteID int64
+}
+
+func (s *stubWatchFailures) Record(ctx context.Context, input storage.WatchFailureRecord) (storage.WatchFailure, error) {
+	return storage.WatchFailure{Path: input.Path, AlbumSlug: input.AlbumSlug, Error: input.Error}, nil
+}
+
+func (s *stubWatchFailures) List(ctx context.Context) ([]storage.WatchFailure, error) {
+	return s.list, s.listErr
+}
+
+func (s *stubWatchFailures) Delete(ctx context.Context, id int64) error {
+	s.lastDeleteID = id
+	return s.deleteErr
+}
+
+func (s *stubWatchFailures) DeleteByPath(ctx context.Context, path string) error {
+	return nil
+}
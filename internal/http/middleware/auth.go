@@ -1,24 +1,53 @@
 package middleware
 
 import (
+	"errors"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/gin-gonic/gin"
-)
 
-const (
-	adminCookieValue = "1"
+	"github.com/Oxyrus/memories/internal/storage"
 )
 
-// RequireAdmin ensures the incoming request has a valid admin cookie. When the cookie
-// is missing or invalid the client is redirected to the login page, preserving the
-// originally requested path so the user can be sent back after authenticating.
-func RequireAdmin(cookieName string) gin.HandlerFunc {
+// SessionIDKey is the gin context key under which RequireAdmin stores the ID of
+// the authenticated session.
+const SessionIDKey = "sessionID"
+
+// RequireAdmin ensures the incoming request carries a cookie referencing a live
+// server-side session. Valid sessions are slid forward by ttl on every request.
+// When the cookie is missing, unknown, or expired the client is redirected to the
+// login page, preserving the originally requested path so the user can be sent
+// back after authenticating.
+func RequireAdmin(logger *slog.Logger, sessions storage.Sessions, cookieName string, ttl time.Duration) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if v, err := c.Cookie(cookieName); err == nil && v == adminCookieValue {
-			c.Next()
-			return
+		ctx := c.Request.Context()
+
+		if sessionID, err := c.Cookie(cookieName); err == nil && sessionID != "" {
+			session, err := sessions.Get(ctx, sessionID)
+			switch {
+			case err == nil && time.Now().Before(session.ExpiresAt):
+				if err := sessions.Touch(ctx, session.ID, time.Now().Add(ttl)); err != nil {
+					logger.Warn("failed to extend session", "error", err)
+				} else {
+					SetSessionCookie(c, cookieName, session.ID, ttl)
+				}
+				c.Set(SessionIDKey, session.ID)
+				c.Next()
+				return
+			case err == nil:
+				if err := sessions.Delete(ctx, session.ID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+					logger.Warn("failed to delete expired session", "error", err)
+				}
+			case !errors.Is(err, storage.ErrNotFound):
+				logger.Error("failed to load session", "error", err)
+				c.String(http.StatusInternalServerError, "failed to verify session")
+				c.Abort()
+				return
+			}
+			ClearSessionCookie(c, cookieName)
 		}
 
 		target := c.Request.URL.RequestURI()
@@ -31,3 +60,16 @@ func RequireAdmin(cookieName string) gin.HandlerFunc {
 		c.Abort()
 	}
 }
+
+// SetSessionCookie writes the admin session cookie so that it lives as long as
+// the server-side session.
+func SetSessionCookie(c *gin.Context, cookieName, sessionID string, ttl time.Duration) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(cookieName, sessionID, int(ttl.Seconds()), "/", "", c.Request.TLS != nil, true)
+}
+
+// ClearSessionCookie instructs the browser to drop the admin session cookie.
+func ClearSessionCookie(c *gin.Context, cookieName string) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(cookieName, "", -1, "/", "", c.Request.TLS != nil, true)
+}
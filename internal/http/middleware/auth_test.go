@@ -0,0 +1,125 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Oxyrus/memories/internal/http/middleware"
+	"github.com/Oxyrus/memories/internal/storage"
+	"github.com/Oxyrus/memories/internal/storage/sqlite"
+)
+
+const cookieName = "memories_admin"
+
+func TestRequireAdminRejectsInvalidCookies(t *testing.T) {
+	store := newStore(t)
+	ctx := context.Background()
+	if _, err := store.Sessions().Create(ctx, storage.SessionCreate{ID: "expired-session", ExpiresAt: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		cookie      string
+		wantCleared bool
+	}{
+		{name: "no cookie"},
+		{name: "hand-set cookie", cookie: "1", wantCleared: true},
+		{name: "unknown session", cookie: "0123456789abcdef", wantCleared: true},
+		{name: "expired session", cookie: "expired-session", wantCleared: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(newEngine(store), "/albums?page=2", tt.cookie)
+
+			if rec.Code != http.StatusFound {
+				t.Fatalf("expected status 302, got %d", rec.Code)
+			}
+			if got := rec.Header().Get("Location"); got != "/login?next=%2Falbums%3Fpage%3D2" {
+				t.Fatalf("expected redirect to the login page, got %q", got)
+			}
+			cookie := findCookie(rec, cookieName)
+			if tt.wantCleared && (cookie == nil || cookie.MaxAge >= 0) {
+				t.Fatalf("expected the session cookie to be cleared, got %+v", cookie)
+			}
+		})
+	}
+
+	if _, err := store.Sessions().Get(ctx, "expired-session"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected the expired session to be deleted, got %v", err)
+	}
+}
+
+func TestRequireAdminExtendsValidSession(t *testing.T) {
+	store := newStore(t)
+	ctx := context.Background()
+	created, err := store.Sessions().Create(ctx, storage.SessionCreate{ID: "live-session", ExpiresAt: time.Now().Add(time.Minute)})
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	rec := serve(newEngine(store), "/albums", "live-session")
+
+	if rec.Code != http.StatusOK || rec.Body.String() != "live-session" {
+		t.Fatalf("expected the request to pass with its session, got %d %q", rec.Code, rec.Body.String())
+	}
+	session, err := store.Sessions().Get(ctx, "live-session")
+	if err != nil {
+		t.Fatalf("get session: %v", err)
+	}
+	if !session.ExpiresAt.After(created.ExpiresAt.Add(time.Hour)) {
+		t.Fatalf("expected the session to be extended, expires at %v (was %v)", session.ExpiresAt, created.ExpiresAt)
+	}
+	if cookie := findCookie(rec, cookieName); cookie == nil || cookie.Value != "live-session" || cookie.MaxAge <= 0 {
+		t.Fatalf("expected the session cookie to be refreshed, got %+v", cookie)
+	}
+}
+
+func newStore(t *testing.T) storage.Store {
+	t.Helper()
+	store, err := sqlite.Open(filepath.Join(t.TempDir(), "memories.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func newEngine(store storage.Store) *gin.Engine {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	engine := gin.New()
+	engine.Use(middleware.RequireAdmin(logger, store.Sessions(), cookieName, 24*time.Hour))
+	engine.GET("/albums", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(middleware.SessionIDKey))
+	})
+	return engine
+}
+
+func serve(engine *gin.Engine, target, cookie string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if cookie != "" {
+		req.AddCookie(&http.Cookie{Name: cookieName, Value: cookie})
+	}
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec
+}
+
+func findCookie(rec *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
@@ -0,0 +1,539 @@
+// Package ingest turns image files into album photos. Every way of adding
+// photos (form uploads, resumable uploads, archive and folder imports) goes
+// through the same Pipeline, so they all sanitize files, read capture dates,
+// generate variants and detect duplicates alike.
+package ingest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Oxyrus/memories/internal/blob"
+	"github.com/Oxyrus/memories/internal/media"
+	"github.com/Oxyrus/memories/internal/storage"
+)
+
+// Outcomes of ingesting a file.
+const (
+	StatusUploaded  = "uploaded"
+	StatusDuplicate = "duplicate"
+	StatusRejected  = "rejected"
+	StatusFailed    = "failed"
+)
+
+var imageExtensions = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".gif":  true,
+	".webp": true,
+	".tif":  true,
+	".tiff": true,
+}
+
+// IsImageFile reports whether name has the extension of a supported image
+// format. Callers use it to pick photos out of archives and folders before
+// ingesting them; the file contents are still checked by Ingest.
+func IsImageFile(name string) bool {
+	return imageExtensions[strings.ToLower(path.Ext(strings.ReplaceAll(name, "\\", "/")))]
+}
+
+// Options are applied to a photo as it is ingested.
+type Options struct {
+	Caption string
+	// TakenAt overrides the capture date read from the file.
+	TakenAt        *time.Time
+	AllowDuplicate bool
+}
+
+// Result is the outcome of ingesting one file. Error is a message suitable
+// for showing to the person who added the file.
+type Result struct {
+	Filename        string `json:"filename"`
+	Status          string `json:"status"`
+	PhotoID         int64  `json:"photo_id,omitempty"`
+	ExistingPhotoID int64  `json:"existing_photo_id,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// Layout selects the keys new photo files are stored under.
+type Layout string
+
+const (
+	// LayoutAlbum stores each file under its album's slug with a random
+	// name, for example "summer/20240702183000-ab12….jpg".
+	LayoutAlbum Layout = "album"
+	// LayoutContent stores each file under the SHA-256 of the sanitized
+	// original, for example "_sha256/ab/cd/abcd….jpg", so photos with the
+	// same content share their files across albums. Shared files are
+	// reference counted and removed once no photo uses them.
+	LayoutContent Layout = "content"
+)
+
+// contentPrefix holds content-addressed files. Album slugs cannot contain an
+// underscore, so it never clashes with an album's files.
+const contentPrefix = "_sha256"
+
+// Valid reports whether l is a known layout.
+func (l Layout) Valid() bool {
+	return l == LayoutAlbum || l == LayoutContent
+}
+
+// Pipeline processes files in a scratch directory and publishes them, with
+// their variants, to a blob store.
+type Pipeline struct {
+	logger  *slog.Logger
+	photos  storage.Photos
+	records storage.Blobs
+	blobs   blob.Store
+	layout  Layout
+
+	// mu keeps shared files from being collected between the moment an
+	// ingest claims them and the moment its photo references them.
+	mu sync.RWMutex
+}
+
+// New returns a pipeline that stores new files in layout. records tracks
+// content-addressed files whichever layout is used for new ones, so files
+// stored before the layout changed are still removed correctly.
+func New(logger *slog.Logger, photos storage.Photos, records storage.Blobs, blobs blob.Store, layout Layout) *Pipeline {
+	return &Pipeline{
+		logger:  logger,
+		photos:  photos,
+		records: records,
+		blobs:   blobs,
+		layout:  layout,
+	}
+}
+
+// Ingest sanitizes and records a single file named name. save writes its
+// contents to a path in a scratch directory and must not replace an existing
+// file. Nothing is published for a file that does not end up as a photo.
+func (p *Pipeline) Ingest(ctx context.Context, album storage.Album, name string, save func(dst string) error, opts Options) Result {
+	originalName := path.Base(strings.ReplaceAll(name, "\\", "/"))
+	result := Result{Filename: originalName}
+	fail := func(outcome, message string) Result {
+		result.Status = outcome
+		result.Error = message
+		return result
+	}
+	const saveFailed = "The photo could not be saved. Please try again."
+
+	filename, err := generateFilename(name)
+	if err != nil {
+		p.logger.Error("failed to generate photo filename", "error", err)
+		return fail(StatusFailed, saveFailed)
+	}
+
+	workDir, err := os.MkdirTemp("", "memories-ingest-")
+	if err != nil {
+		p.logger.Error("failed to create scratch directory", "error", err)
+		return fail(StatusFailed, saveFailed)
+	}
+	defer os.RemoveAll(workDir)
+
+	diskPath := filepath.Join(workDir, filename)
+	if err := save(diskPath); err != nil {
+		p.logger.Error("failed to save uploaded file", "path", diskPath, "error", err)
+		return fail(StatusFailed, saveFailed)
+	}
+
+	// Capture details must be read before Sanitize strips them.
+	meta, err := media.ReadMetadata(diskPath)
+	if err != nil {
+		p.logger.Warn("failed to read photo metadata", "path", diskPath, "error", err)
+	}
+
+	// File details are measured after Sanitize so they describe the
+	// published file.
+	err = media.Sanitize(diskPath)
+	var info media.FileInfo
+	if err == nil {
+		info, err = media.Inspect(diskPath)
+	}
+	if err != nil {
+		if errors.Is(err, media.ErrUnsupported) || errors.Is(err, media.ErrMalformed) {
+			p.logger.Warn("rejected photo that could not be sanitized", "albumID", album.ID, "filename", name, "error", err)
+			return fail(StatusRejected, sanitizeErrorMessage(err))
+		}
+
+		p.logger.Error("failed to sanitize photo", "path", diskPath, "error", err)
+		return fail(StatusFailed, "The photo could not be processed, so it was not uploaded. Please try again.")
+	}
+
+	if !opts.AllowDuplicate {
+		existing, err := p.photos.FindByHash(ctx, album.ID, info.SHA256)
+		if err == nil {
+			p.logger.Info("skipped duplicate photo upload", "albumID", album.ID, "existingPhotoID", existing.ID, "filename", name)
+			result.ExistingPhotoID = existing.ID
+			return fail(StatusDuplicate, duplicatePhotoMessage(existing))
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			p.logger.Error("failed to check for duplicate photo", "albumID", album.ID, "error", err)
+			return fail(StatusFailed, saveFailed)
+		}
+	}
+
+	dir := album.Slug
+	if p.layout == LayoutContent {
+		// Variants are named after the original, so they share its address.
+		dir, filename = contentKey(info.SHA256, filename)
+		renamed := filepath.Join(workDir, filename)
+		if err := os.Rename(diskPath, renamed); err != nil {
+			p.logger.Error("failed to rename photo in scratch directory", "path", diskPath, "error", err)
+			return fail(StatusFailed, saveFailed)
+		}
+		diskPath = renamed
+	}
+
+	takenAt := opts.TakenAt
+	takenAtSource := storage.TakenAtSourceNone
+	if takenAt != nil {
+		takenAtSource = storage.TakenAtSourceManual
+	} else if meta.TakenAt != nil {
+		takenAt = meta.TakenAt
+		takenAtSource = storage.TakenAtSourceCamera
+	}
+
+	generated, err := media.GenerateVariants(diskPath, media.DefaultVariants)
+	if err != nil {
+		p.logger.Warn("failed to generate photo variants", "path", diskPath, "error", err)
+	}
+
+	var perceptualHash string
+	if phash, err := media.ComputePerceptualHash(diskPath); err != nil {
+		p.logger.Warn("failed to compute perceptual hash", "path", diskPath, "error", err)
+	} else {
+		perceptualHash = phash.String()
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	storedPath := path.Join(dir, filename)
+	variants := toPhotoVariants(dir, generated)
+	published, err := p.publish(ctx, workDir, storedPath, variants)
+	if err != nil {
+		p.logger.Error("failed to store photo files", "albumID", album.ID, "filename", storedPath, "error", err)
+		return fail(StatusFailed, saveFailed)
+	}
+
+	photo, err := p.photos.Create(ctx, storage.PhotoCreate{
+		AlbumID:          album.ID,
+		Filename:         storedPath,
+		OriginalFilename: originalName,
+		Caption:          opts.Caption,
+		TakenAt:          takenAt,
+		TakenAtSource:    takenAtSource,
+		Variants:         variants,
+		Width:            info.Width,
+		Height:           info.Height,
+		SizeBytes:        info.Size,
+		MimeType:         info.MIMEType,
+		SHA256:           info.SHA256,
+		PerceptualHash:   perceptualHash,
+		AllowDuplicate:   opts.AllowDuplicate,
+		Metadata:         toPhotoMetadata(meta),
+	})
+	if err != nil {
+		p.unpublish(published)
+		if errors.Is(err, storage.ErrConflict) && !opts.AllowDuplicate {
+			// Another upload of the same file won the race since the check above.
+			if existing, findErr := p.photos.FindByHash(ctx, album.ID, info.SHA256); findErr == nil {
+				result.ExistingPhotoID = existing.ID
+				return fail(StatusDuplicate, duplicatePhotoMessage(existing))
+			}
+		}
+		p.logger.Error("failed to persist photo metadata", "albumID", album.ID, "error", err)
+		return fail(StatusFailed, saveFailed)
+	}
+
+	p.logger.Info("photo uploaded", "albumID", album.ID, "slug", album.Slug, "filename", storedPath)
+	result.Status = StatusUploaded
+	result.PhotoID = photo.ID
+	return result
+}
+
+// publish stores the original at key and its variants from workDir. Files of
+// the content layout that are already stored are claimed instead of uploaded
+// again. On failure the blobs stored so far are deleted again; otherwise their
+// keys are returned.
+func (p *Pipeline) publish(ctx context.Context, workDir, key string, variants []storage.PhotoVariant) ([]string, error) {
+	keys := make([]string, 0, len(variants)+1)
+	keys = append(keys, key)
+	for _, variant := range variants {
+		keys = append(keys, variant.Filename)
+	}
+
+	for i, k := range keys {
+		if err := p.publishFile(ctx, k, filepath.Join(workDir, path.Base(k))); err != nil {
+			p.unpublish(keys[:i])
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+func (p *Pipeline) publishFile(ctx context.Context, key, src string) error {
+	if p.layout != LayoutContent {
+		return blob.PutFile(ctx, p.blobs, key, src)
+	}
+
+	claimed, err := p.records.Claim(ctx, key)
+	if err != nil || claimed {
+		return err
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if err := blob.PutFile(ctx, p.blobs, key, src); err != nil {
+		return err
+	}
+	return p.records.Register(ctx, key, info.Size())
+}
+
+// unpublish deletes blobs of a photo that was not recorded. It does not use
+// the request's context so that cleanup still happens after a cancellation.
+// Content-addressed files may be shared, so they are left for Collect.
+func (p *Pipeline) unpublish(keys []string) {
+	if p.layout == LayoutContent {
+		return
+	}
+	for _, key := range keys {
+		if err := p.blobs.Delete(context.Background(), key); err != nil {
+			p.logger.Warn("failed to delete unused photo file", "key", key, "error", err)
+		}
+	}
+}
+
+// RemovePhotoFiles deletes the files of a photo whose row has been deleted.
+// Content-addressed files are only deleted once no other photo references
+// them. Removal continues past failures and the first one is returned.
+func (p *Pipeline) RemovePhotoFiles(ctx context.Context, photo storage.Photo) error {
+	return p.removeFiles(ctx, photoKeys(photo))
+}
+
+// RemoveAlbumFiles deletes the files of an album whose rows have been
+// deleted: its photos' files as RemovePhotoFiles does, and anything else
+// stored under its slug. An album without files is not treated as an error.
+func (p *Pipeline) RemoveAlbumFiles(ctx context.Context, slug string, photos []storage.Photo) error {
+	if _, err := blob.CleanKey(slug); err != nil || strings.Contains(slug, "/") || strings.HasPrefix(slug, contentPrefix) {
+		return fmt.Errorf("refusing to remove uploads for unsafe slug %q", slug)
+	}
+
+	var keys []string
+	for _, photo := range photos {
+		keys = append(keys, photoKeys(photo)...)
+	}
+	firstErr := p.removeFiles(ctx, keys)
+
+	files, err := p.blobs.List(ctx, slug+"/")
+	if err != nil {
+		if firstErr == nil {
+			firstErr = err
+		}
+		return firstErr
+	}
+	for _, file := range files {
+		if err := p.blobs.Delete(ctx, file.Key); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (p *Pipeline) removeFiles(ctx context.Context, keys []string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var firstErr error
+	for _, key := range keys {
+		if err := p.removeFile(ctx, key); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// removeFile deletes an untracked file outright and a content-addressed one
+// only when nothing references it any more. p.mu must be held.
+func (p *Pipeline) removeFile(ctx context.Context, key string) error {
+	record, err := p.records.Get(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return p.blobs.Delete(ctx, key)
+	}
+	if err != nil {
+		return err
+	}
+	if record.RefCount > 0 {
+		return nil
+	}
+
+	// Another process may have claimed the file since it was loaded.
+	if err := p.records.Delete(ctx, key, time.Now()); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	return p.blobs.Delete(ctx, key)
+}
+
+// Collect deletes content-addressed files that no photo references and that
+// were last claimed before cutoff, such as files left behind by uploads that
+// failed after publishing. The cutoff should leave time for uploads in other
+// processes to record their photos. It returns how many files were deleted.
+func (p *Pipeline) Collect(ctx context.Context, cutoff time.Time) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	unreferenced, err := p.records.ListUnreferenced(ctx, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	var deleted int
+	var firstErr error
+	for _, record := range unreferenced {
+		if err := p.records.Delete(ctx, record.Key, cutoff); err != nil {
+			if !errors.Is(err, storage.ErrNotFound) && firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if err := p.blobs.Delete(ctx, record.Key); err != nil {
+			p.logger.Warn("failed to delete unreferenced photo file", "key", record.Key, "error", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		deleted++
+	}
+	return deleted, firstErr
+}
+
+// CopyFile returns a save function for Ingest that copies the file at src,
+// refusing to replace an existing file. A partially written dst is removed on
+// failure.
+func CopyFile(src string) func(dst string) error {
+	return func(dst string) error {
+		in, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		return WriteFile(dst, in)
+	}
+}
+
+// WriteFile creates dst with the contents of r, refusing to replace an
+// existing file. A partially written dst is removed on failure.
+func WriteFile(dst string, r io.Reader) error {
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		_ = out.Close()
+		_ = os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(dst)
+		return err
+	}
+	return nil
+}
+
+// contentKey returns the directory and file name of a content-addressed file
+// with the given SHA-256, keeping the extension of filename.
+func contentKey(sha256, filename string) (dir, name string) {
+	return path.Join(contentPrefix, sha256[:2], sha256[2:4]), sha256 + path.Ext(filename)
+}
+
+func photoKeys(photo storage.Photo) []string {
+	keys := make([]string, 0, len(photo.Variants)+1)
+	keys = append(keys, photo.Filename)
+	for _, variant := range photo.Variants {
+		keys = append(keys, variant.Filename)
+	}
+	return keys
+}
+
+func generateFilename(original string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(original))
+	const tokenSize = 12
+	buf := make([]byte, tokenSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	timestamp := time.Now().UTC().Format("20060102150405")
+	return fmt.Sprintf("%s-%s%s", timestamp, token, ext), nil
+}
+
+func sanitizeErrorMessage(err error) string {
+	if errors.Is(err, media.ErrUnsupported) {
+		return "This file type is not supported. Upload a JPEG, PNG, GIF, WebP, or TIFF image."
+	}
+	return "This photo could not be read, so its metadata could not be removed. It was not uploaded."
+}
+
+// duplicatePhotoMessage names the photo that already holds an upload's content.
+func duplicatePhotoMessage(existing storage.Photo) string {
+	name := strings.TrimSpace(existing.Caption)
+	if name == "" {
+		name = existing.OriginalFilename
+	}
+	if name == "" {
+		name = path.Base(existing.Filename)
+	}
+	if !existing.CreatedAt.IsZero() {
+		name = fmt.Sprintf("%s (uploaded %s)", name, existing.CreatedAt.UTC().Format("Jan 2, 2006 15:04 MST"))
+	}
+	return fmt.Sprintf("This photo is already in the album as “%s”, so it was skipped. Tick “Upload anyway” to add another copy.", name)
+}
+
+func toPhotoMetadata(meta media.Metadata) *storage.PhotoMetadata {
+	if !meta.HasCamera() {
+		return nil
+	}
+	return &storage.PhotoMetadata{
+		CameraMake:   meta.CameraMake,
+		CameraModel:  meta.CameraModel,
+		Lens:         meta.Lens,
+		FocalLength:  meta.FocalLength,
+		Aperture:     meta.Aperture,
+		ExposureTime: meta.ExposureTime,
+		ISO:          meta.ISO,
+	}
+}
+
+func toPhotoVariants(slug string, variants []media.Variant) []storage.PhotoVariant {
+	if len(variants) == 0 {
+		return nil
+	}
+	result := make([]storage.PhotoVariant, 0, len(variants))
+	for _, variant := range variants {
+		result = append(result, storage.PhotoVariant{
+			Name:     variant.Name,
+			Filename: path.Join(slug, variant.Filename),
+			Width:    variant.Width,
+			Height:   variant.Height,
+		})
+	}
+	return result
+}
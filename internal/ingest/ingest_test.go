@@ -0,0 +1,384 @@
+package ingest_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Oxyrus/memories/internal/blob"
+	"github.com/Oxyrus/memories/internal/ingest"
+	"github.com/Oxyrus/memories/internal/storage"
+	"github.com/Oxyrus/memories/internal/storage/sqlite"
+)
+
+func TestIngestRecordsCameraDetails(t *testing.T) {
+	ctx := context.Background()
+	store, blobs, pipeline := newPipeline(t, ingest.LayoutAlbum)
+	summer := createAlbum(t, store, "summer")
+	content, err := os.ReadFile(filepath.Join("testdata", "camera.jpg"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+
+	result := ingestFile(t, pipeline, summer, "DSCF0001.JPG", content, ingest.Options{Caption: "Beach"})
+	if result.Status != ingest.StatusUploaded || result.Filename != "DSCF0001.JPG" {
+		t.Fatalf("expected the photo to be uploaded, got %+v", result)
+	}
+	photo, err := store.Photos().GetByID(ctx, result.PhotoID)
+	if err != nil {
+		t.Fatalf("get photo: %v", err)
+	}
+
+	takenAt := time.Date(2024, 7, 14, 16, 30, 5, 250_000_000, time.UTC)
+	if photo.TakenAt == nil || !photo.TakenAt.Equal(takenAt) || photo.TakenAtSource != storage.TakenAtSourceCamera {
+		t.Fatalf("expected the capture date from EXIF, got %v (%s)", photo.TakenAt, photo.TakenAtSource)
+	}
+	if photo.Metadata == nil || photo.Metadata.CameraModel != "X-T4" {
+		t.Fatalf("expected camera details to be recorded, got %+v", photo.Metadata)
+	}
+	if photo.Caption != "Beach" || photo.OriginalFilename != "DSCF0001.JPG" || photo.SHA256 == "" || photo.Width == 0 {
+		t.Fatalf("expected the photo's details to be recorded, got %+v", photo)
+	}
+
+	// The published file no longer carries the EXIF data it was read from.
+	r, err := blobs.Get(ctx, photo.Filename)
+	if err != nil {
+		t.Fatalf("get stored file: %v", err)
+	}
+	defer r.Close()
+	stored, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("read stored file: %v", err)
+	}
+	if bytes.Contains(stored, []byte("Fujifilm")) {
+		t.Fatal("expected camera EXIF data to be stripped from the stored file")
+	}
+}
+
+func TestIngestUsesManualCaptureDate(t *testing.T) {
+	ctx := context.Background()
+	store, _, pipeline := newPipeline(t, ingest.LayoutAlbum)
+	summer := createAlbum(t, store, "summer")
+	content, err := os.ReadFile(filepath.Join("testdata", "camera.jpg"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+
+	takenAt := time.Date(2025, 2, 14, 18, 0, 0, 0, time.UTC)
+	result := ingestFile(t, pipeline, summer, "photo.jpg", content, ingest.Options{TakenAt: &takenAt})
+	photo, err := store.Photos().GetByID(ctx, result.PhotoID)
+	if err != nil {
+		t.Fatalf("get photo: %v", err)
+	}
+	if photo.TakenAt == nil || !photo.TakenAt.Equal(takenAt) || photo.TakenAtSource != storage.TakenAtSourceManual {
+		t.Fatalf("expected the manual capture date, got %v (%s)", photo.TakenAt, photo.TakenAtSource)
+	}
+}
+
+func TestIngestGeneratesVariants(t *testing.T) {
+	ctx := context.Background()
+	store, blobs, pipeline := newPipeline(t, ingest.LayoutAlbum)
+	summer := createAlbum(t, store, "summer")
+
+	photo, err := store.Photos().GetByID(ctx, ingestPhoto(t, pipeline, summer, testJPEG(t, 400)))
+	if err != nil {
+		t.Fatalf("get photo: %v", err)
+	}
+	if len(photo.Variants) != 1 || photo.Variants[0].Name != "thumb" || photo.Variants[0].Width != 320 {
+		t.Fatalf("expected a thumbnail variant, got %+v", photo.Variants)
+	}
+	if !strings.HasPrefix(photo.Variants[0].Filename, "summer/") {
+		t.Fatalf("expected the variant next to the original, got %s", photo.Variants[0].Filename)
+	}
+	if _, err := blobs.Stat(ctx, photo.Variants[0].Filename); err != nil {
+		t.Fatalf("expected the variant to be stored: %v", err)
+	}
+
+	// Photos no wider than the smallest variant have none.
+	small, err := store.Photos().GetByID(ctx, ingestPhoto(t, pipeline, summer, testJPEG(t, 64)))
+	if err != nil {
+		t.Fatalf("get photo: %v", err)
+	}
+	if len(small.Variants) != 0 {
+		t.Fatalf("expected no variants for a small photo, got %+v", small.Variants)
+	}
+}
+
+func TestIngestRejectsUnreadableFiles(t *testing.T) {
+	ctx := context.Background()
+	store, blobs, pipeline := newPipeline(t, ingest.LayoutAlbum)
+	summer := createAlbum(t, store, "summer")
+
+	tests := map[string][]byte{
+		"notes.jpg":  []byte("not really a jpeg"),
+		"broken.jpg": testJPEG(t, 64)[:100],
+	}
+	for name, content := range tests {
+		result := ingestFile(t, pipeline, summer, name, content, ingest.Options{})
+		if result.Status != ingest.StatusRejected || result.Error == "" {
+			t.Fatalf("%s: expected the file to be rejected with a message, got %+v", name, result)
+		}
+	}
+
+	photos, err := store.Photos().ListByAlbum(ctx, summer.ID)
+	if err != nil {
+		t.Fatalf("list photos: %v", err)
+	}
+	if len(photos) != 0 {
+		t.Fatalf("expected no photos to be recorded, got %d", len(photos))
+	}
+	if infos, err := blobs.List(ctx, ""); err != nil || len(infos) != 0 {
+		t.Fatalf("expected nothing to be stored, got %+v (%v)", infos, err)
+	}
+}
+
+func TestIngestSkipsDuplicates(t *testing.T) {
+	ctx := context.Background()
+	store, _, pipeline := newPipeline(t, ingest.LayoutAlbum)
+	summer := createAlbum(t, store, "summer")
+	winter := createAlbum(t, store, "winter")
+	photo := testJPEG(t, 64)
+
+	first := ingestPhoto(t, pipeline, summer, photo)
+	result := ingestFile(t, pipeline, summer, "copy.jpg", photo, ingest.Options{})
+	if result.Status != ingest.StatusDuplicate || result.ExistingPhotoID != first {
+		t.Fatalf("expected a duplicate of photo %d, got %+v", first, result)
+	}
+	if !strings.Contains(result.Error, "photo.jpg") {
+		t.Fatalf("expected the message to name the existing photo, got %q", result.Error)
+	}
+
+	// Other albums and deliberate copies are not duplicates.
+	ingestPhoto(t, pipeline, winter, photo)
+	result = ingestFile(t, pipeline, summer, "copy.jpg", photo, ingest.Options{AllowDuplicate: true})
+	if result.Status != ingest.StatusUploaded {
+		t.Fatalf("expected the copy to be uploaded, got %+v", result)
+	}
+	photos, err := store.Photos().ListByAlbum(ctx, summer.ID)
+	if err != nil {
+		t.Fatalf("list photos: %v", err)
+	}
+	if len(photos) != 2 {
+		t.Fatalf("expected two photos, got %d", len(photos))
+	}
+}
+
+func TestContentLayoutSharesFilesAcrossAlbums(t *testing.T) {
+	ctx := context.Background()
+	store, blobs, pipeline := newPipeline(t, ingest.LayoutContent)
+
+	summer := createAlbum(t, store, "summer")
+	winter := createAlbum(t, store, "winter")
+	photo := testJPEG(t, 400)
+
+	first := ingestPhoto(t, pipeline, summer, photo)
+	second := ingestPhoto(t, pipeline, winter, photo)
+	a, err := store.Photos().GetByID(ctx, first)
+	if err != nil {
+		t.Fatalf("get photo: %v", err)
+	}
+	b, err := store.Photos().GetByID(ctx, second)
+	if err != nil {
+		t.Fatalf("get photo: %v", err)
+	}
+
+	want := "_sha256/" + a.SHA256[:2] + "/" + a.SHA256[2:4] + "/" + a.SHA256 + ".jpg"
+	if a.Filename != want || b.Filename != want {
+		t.Fatalf("expected both photos to be stored at %s, got %s and %s", want, a.Filename, b.Filename)
+	}
+	if len(a.Variants) == 0 || !strings.HasPrefix(a.Variants[0].Filename, strings.TrimSuffix(want, ".jpg")+"_") {
+		t.Fatalf("expected variants next to the original, got %+v", a.Variants)
+	}
+	infos, err := blobs.List(ctx, "")
+	if err != nil {
+		t.Fatalf("list blobs: %v", err)
+	}
+	if len(infos) != len(a.Variants)+1 {
+		t.Fatalf("expected one copy of each file, got %+v", infos)
+	}
+
+	// Deleting one photo keeps the files the other still uses.
+	if err := store.Photos().Delete(ctx, first); err != nil {
+		t.Fatalf("delete photo: %v", err)
+	}
+	if err := pipeline.RemovePhotoFiles(ctx, a); err != nil {
+		t.Fatalf("RemovePhotoFiles returned error: %v", err)
+	}
+	if _, err := blobs.Stat(ctx, want); err != nil {
+		t.Fatalf("expected the shared file to remain: %v", err)
+	}
+
+	if err := store.Albums().Delete(ctx, winter.ID); err != nil {
+		t.Fatalf("delete album: %v", err)
+	}
+	if err := pipeline.RemoveAlbumFiles(ctx, winter.Slug, []storage.Photo{b}); err != nil {
+		t.Fatalf("RemoveAlbumFiles returned error: %v", err)
+	}
+	if infos, err := blobs.List(ctx, ""); err != nil || len(infos) != 0 {
+		t.Fatalf("expected every file to be removed with the last photo, got %+v (%v)", infos, err)
+	}
+	if _, err := store.Blobs().Get(ctx, want); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected the file record to be removed, got %v", err)
+	}
+}
+
+func TestContentLayoutAllowsDuplicateInAlbum(t *testing.T) {
+	ctx := context.Background()
+	store, _, pipeline := newPipeline(t, ingest.LayoutContent)
+	summer := createAlbum(t, store, "summer")
+	photo := testJPEG(t, 64)
+
+	first := ingestPhoto(t, pipeline, summer, photo)
+	result := ingestFile(t, pipeline, summer, "photo.jpg", photo, ingest.Options{AllowDuplicate: true})
+	if result.Status != ingest.StatusUploaded {
+		t.Fatalf("expected the copy to be uploaded, got %+v", result)
+	}
+
+	photos, err := store.Photos().ListByAlbum(ctx, summer.ID)
+	if err != nil {
+		t.Fatalf("list photos: %v", err)
+	}
+	if len(photos) != 2 {
+		t.Fatalf("expected two photos, got %d", len(photos))
+	}
+	a, err := store.Photos().GetByID(ctx, first)
+	if err != nil {
+		t.Fatalf("get photo: %v", err)
+	}
+	record, err := store.Blobs().Get(ctx, a.Filename)
+	if err != nil {
+		t.Fatalf("get blob record: %v", err)
+	}
+	if record.RefCount != 2 {
+		t.Fatalf("expected ref_count 2, got %d", record.RefCount)
+	}
+}
+
+func TestCollectRemovesUnreferencedFiles(t *testing.T) {
+	ctx := context.Background()
+	store, blobs, pipeline := newPipeline(t, ingest.LayoutContent)
+	summer := createAlbum(t, store, "summer")
+
+	kept := ingestPhoto(t, pipeline, summer, testJPEG(t, 64))
+	keptPhoto, err := store.Photos().GetByID(ctx, kept)
+	if err != nil {
+		t.Fatalf("get photo: %v", err)
+	}
+
+	// A file published by an upload that never recorded its photo.
+	const orphan = "_sha256/ab/cd/abcdef.jpg"
+	if err := blobs.Put(ctx, orphan, strings.NewReader("orphan")); err != nil {
+		t.Fatalf("put blob: %v", err)
+	}
+	if err := store.Blobs().Register(ctx, orphan, 6); err != nil {
+		t.Fatalf("register blob: %v", err)
+	}
+
+	if removed, err := pipeline.Collect(ctx, time.Now().Add(-time.Hour)); err != nil || removed != 0 {
+		t.Fatalf("expected recent files to be kept, removed %d (%v)", removed, err)
+	}
+	removed, err := pipeline.Collect(ctx, time.Now().Add(time.Second))
+	if err != nil || removed != 1 {
+		t.Fatalf("expected the orphan to be removed, removed %d (%v)", removed, err)
+	}
+	if _, err := blobs.Stat(ctx, orphan); !errors.Is(err, blob.ErrNotFound) {
+		t.Fatalf("expected the orphan file to be deleted, got %v", err)
+	}
+	if _, err := blobs.Stat(ctx, keptPhoto.Filename); err != nil {
+		t.Fatalf("expected referenced files to be kept: %v", err)
+	}
+}
+
+func TestAlbumLayoutKeepsFilesUnderSlug(t *testing.T) {
+	ctx := context.Background()
+	store, blobs, pipeline := newPipeline(t, ingest.LayoutAlbum)
+	summer := createAlbum(t, store, "summer")
+
+	id := ingestPhoto(t, pipeline, summer, testJPEG(t, 64))
+	photo, err := store.Photos().GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("get photo: %v", err)
+	}
+	if !strings.HasPrefix(photo.Filename, "summer/") {
+		t.Fatalf("expected the photo under its album, got %s", photo.Filename)
+	}
+
+	if err := store.Photos().Delete(ctx, id); err != nil {
+		t.Fatalf("delete photo: %v", err)
+	}
+	if err := pipeline.RemovePhotoFiles(ctx, photo); err != nil {
+		t.Fatalf("RemovePhotoFiles returned error: %v", err)
+	}
+	if _, err := blobs.Stat(ctx, photo.Filename); !errors.Is(err, blob.ErrNotFound) {
+		t.Fatalf("expected the file to be deleted, got %v", err)
+	}
+}
+
+func newPipeline(t *testing.T, layout ingest.Layout) (storage.Store, blob.Store, *ingest.Pipeline) {
+	t.Helper()
+
+	store, err := sqlite.Open(filepath.Join(t.TempDir(), "memories.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	blobs, err := blob.NewLocal(t.TempDir(), "/uploads")
+	if err != nil {
+		t.Fatalf("open blob store: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return store, blobs, ingest.New(logger, store.Photos(), store.Blobs(), blobs, layout)
+}
+
+func createAlbum(t *testing.T, store storage.Store, slug string) storage.Album {
+	t.Helper()
+	album, err := store.Albums().Create(context.Background(), storage.AlbumCreate{Slug: slug, Title: slug})
+	if err != nil {
+		t.Fatalf("create album: %v", err)
+	}
+	return album
+}
+
+func ingestPhoto(t *testing.T, pipeline *ingest.Pipeline, album storage.Album, content []byte) int64 {
+	t.Helper()
+	result := ingestFile(t, pipeline, album, "photo.jpg", content, ingest.Options{})
+	if result.Status != ingest.StatusUploaded {
+		t.Fatalf("expected the photo to be uploaded, got %+v", result)
+	}
+	return result.PhotoID
+}
+
+func ingestFile(t *testing.T, pipeline *ingest.Pipeline, album storage.Album, name string, content []byte, opts ingest.Options) ingest.Result {
+	t.Helper()
+	src := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(src, content, 0o644); err != nil {
+		t.Fatalf("write photo: %v", err)
+	}
+	return pipeline.Ingest(context.Background(), album, name, ingest.CopyFile(src), opts)
+}
+
+// testJPEG returns a JPEG of the given width, large enough for variants when
+// width exceeds the smallest variant size.
+func testJPEG(t *testing.T, width int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, width/2))
+	for x := 0; x < width; x++ {
+		img.Set(x, x%(width/2), color.RGBA{R: uint8(x), G: 100, B: 200, A: 255})
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("encode test jpeg: %v", err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,121 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+)
+
+const (
+	gifExtension       = 0x21
+	gifImageDescriptor = 0x2c
+	gifTrailer         = 0x3b
+
+	gifGraphicControl = 0xf9
+	gifPlainText      = 0x01
+	gifApplication    = 0xff
+)
+
+// gifKeepApplications lists the application extensions that control looping.
+// Other application extensions (such as XMP DataXMP) are dropped, as are
+// comment extensions.
+var gifKeepApplications = map[string]bool{
+	"NETSCAPE2.0": true,
+	"ANIMEXTS1.0": true,
+}
+
+func isGIF(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("GIF87a")) || bytes.HasPrefix(data, []byte("GIF89a"))
+}
+
+// stripGIF copies image frames, graphic control and looping extensions
+// verbatim and drops comment and other application extensions.
+func stripGIF(data []byte) ([]byte, error) {
+	const headerSize = 6 + 7 // signature + logical screen descriptor
+	if len(data) < headerSize {
+		return nil, fmt.Errorf("%w: gif: truncated header", ErrMalformed)
+	}
+
+	pos := headerSize
+	if packed := data[10]; packed&0x80 != 0 {
+		pos += 3 << (packed&0x07 + 1)
+	}
+	if pos > len(data) {
+		return nil, fmt.Errorf("%w: gif: truncated color table", ErrMalformed)
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:pos])
+
+	for pos < len(data) {
+		start := pos
+		switch data[pos] {
+		case gifTrailer:
+			out.WriteByte(gifTrailer)
+			return out.Bytes(), nil
+
+		case gifImageDescriptor:
+			const descriptorSize = 10
+			if pos+descriptorSize > len(data) {
+				return nil, fmt.Errorf("%w: gif: truncated image descriptor", ErrMalformed)
+			}
+			packed := data[pos+9]
+			pos += descriptorSize
+			if packed&0x80 != 0 {
+				pos += 3 << (packed&0x07 + 1)
+			}
+			pos++ // LZW minimum code size
+			end, err := skipGIFSubBlocks(data, pos)
+			if err != nil {
+				return nil, err
+			}
+			pos = end
+			out.Write(data[start:pos])
+
+		case gifExtension:
+			if pos+2 > len(data) {
+				return nil, fmt.Errorf("%w: gif: truncated extension", ErrMalformed)
+			}
+			label := data[pos+1]
+			end, err := skipGIFSubBlocks(data, pos+2)
+			if err != nil {
+				return nil, err
+			}
+			pos = end
+			if keepGIFExtension(label, data[start+2:end]) {
+				out.Write(data[start:end])
+			}
+
+		default:
+			return nil, fmt.Errorf("%w: gif: unexpected block 0x%02x", ErrMalformed, data[pos])
+		}
+	}
+
+	return nil, fmt.Errorf("%w: gif: missing trailer", ErrMalformed)
+}
+
+func keepGIFExtension(label byte, blocks []byte) bool {
+	switch label {
+	case gifGraphicControl, gifPlainText:
+		return true
+	case gifApplication:
+		return len(blocks) >= 12 && blocks[0] == 11 && gifKeepApplications[string(blocks[1:12])]
+	default:
+		return false
+	}
+}
+
+// skipGIFSubBlocks returns the offset just past the block terminator of the
+// sub-block sequence starting at pos.
+func skipGIFSubBlocks(data []byte, pos int) (int, error) {
+	for {
+		if pos >= len(data) {
+			return 0, fmt.Errorf("%w: gif: truncated data sub-block", ErrMalformed)
+		}
+		size := int(data[pos])
+		pos++
+		if size == 0 {
+			return pos, nil
+		}
+		pos += size
+	}
+}
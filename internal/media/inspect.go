@@ -0,0 +1,77 @@
+package media
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"os"
+
+	// Registers the WebP decoder for image.DecodeConfig and image.Decode;
+	// imaging already pulls in JPEG, PNG, GIF and TIFF.
+	_ "golang.org/x/image/webp"
+)
+
+// FileInfo describes a stored image file. SHA256 is the hex digest of the file
+// contents.
+type FileInfo struct {
+	Width    int
+	Height   int
+	Size     int64
+	MIMEType string
+	SHA256   string
+}
+
+// Inspect reads the dimensions, size, MIME type and content hash of the image
+// at path. It is meant to run after Sanitize, so the values describe the file
+// that is actually published. Files in formats Sanitize does not accept return
+// ErrUnsupported; files whose header cannot be decoded return ErrMalformed.
+func Inspect(path string) (FileInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	mimeType := DetectMIMEType(data)
+	if mimeType == "" {
+		return FileInfo{}, ErrUnsupported
+	}
+
+	width, height, ok := webpCanvasSize(data)
+	if !ok {
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return FileInfo{}, fmt.Errorf("%w: %s: %v", ErrMalformed, mimeType, err)
+		}
+		width, height = cfg.Width, cfg.Height
+	}
+
+	sum := sha256.Sum256(data)
+	return FileInfo{
+		Width:    width,
+		Height:   height,
+		Size:     int64(len(data)),
+		MIMEType: mimeType,
+		SHA256:   hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+// DetectMIMEType returns the MIME type of an image in one of the formats
+// Sanitize supports, or "" for anything else.
+func DetectMIMEType(data []byte) string {
+	switch {
+	case isJPEG(data):
+		return "image/jpeg"
+	case isPNG(data):
+		return "image/png"
+	case isGIF(data):
+		return "image/gif"
+	case isWebP(data):
+		return "image/webp"
+	case isTIFF(data):
+		return "image/tiff"
+	default:
+		return ""
+	}
+}
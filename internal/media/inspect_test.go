@@ -0,0 +1,65 @@
+package media_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Oxyrus/memories/internal/media"
+)
+
+func TestInspect(t *testing.T) {
+	tests := []struct {
+		fixture  string
+		mimeType string
+		width    int
+		height   int
+	}{
+		{fixture: "camera.jpg", mimeType: "image/jpeg", width: 16, height: 12},
+		{fixture: "metadata.png", mimeType: "image/png", width: 4, height: 3},
+		{fixture: "metadata.gif", mimeType: "image/gif", width: 4, height: 3},
+		{fixture: "metadata.webp", mimeType: "image/webp", width: 75, height: 100},
+		{fixture: "metadata.tiff", mimeType: "image/tiff", width: 4, height: 3},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.fixture, func(t *testing.T) {
+			path := filepath.Join("testdata", tc.fixture)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read fixture: %v", err)
+			}
+
+			info, err := media.Inspect(path)
+			if err != nil {
+				t.Fatalf("Inspect: %v", err)
+			}
+
+			sum := sha256.Sum256(data)
+			want := media.FileInfo{
+				Width:    tc.width,
+				Height:   tc.height,
+				Size:     int64(len(data)),
+				MIMEType: tc.mimeType,
+				SHA256:   hex.EncodeToString(sum[:]),
+			}
+			if info != want {
+				t.Fatalf("expected %+v, got %+v", want, info)
+			}
+		})
+	}
+}
+
+func TestInspectRejectsUnknownFormats(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("not an image"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if _, err := media.Inspect(path); !errors.Is(err, media.ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+}
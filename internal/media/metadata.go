@@ -0,0 +1,257 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rwcarlsen/goexif/exif"
+	"github.com/rwcarlsen/goexif/tiff"
+)
+
+// Metadata holds the capture details read from an image before Sanitize
+// strips them from the published file. Zero values mean the file did not
+// record the field.
+type Metadata struct {
+	// TakenAt is the EXIF capture time in UTC, or nil when the file does not
+	// record one.
+	TakenAt *time.Time
+
+	CameraMake   string
+	CameraModel  string
+	Lens         string
+	FocalLength  float64 // millimetres
+	Aperture     float64 // f-number
+	ExposureTime string  // e.g. "1/250" or "2"
+	ISO          int
+}
+
+// HasCamera reports whether any camera detail beyond the capture time was
+// recorded.
+func (m Metadata) HasCamera() bool {
+	return m.CameraMake != "" || m.CameraModel != "" || m.Lens != "" ||
+		m.FocalLength > 0 || m.Aperture > 0 || m.ExposureTime != "" || m.ISO > 0
+}
+
+const exifTimeLayout = "2006:01:02 15:04:05"
+
+// offsetTimeOriginal is the EXIF 2.31 tag holding the UTC offset of
+// DateTimeOriginal. goexif predates it, so it is loaded separately.
+const (
+	offsetTimeOriginalTag                = 0x9011
+	offsetTimeOriginal    exif.FieldName = "OffsetTimeOriginal"
+)
+
+// ReadMetadata extracts capture details from the EXIF block of the image at
+// path. JPEG and TIFF files are read directly; PNG eXIf and WebP EXIF chunks
+// are read when present. Files without usable EXIF data yield an empty
+// Metadata; only I/O failures are returned as errors.
+func ReadMetadata(path string) (Metadata, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	var meta Metadata
+	x := decodeEXIF(data)
+	if x == nil {
+		return meta, nil
+	}
+
+	if takenAt, ok := captureTime(x); ok {
+		meta.TakenAt = &takenAt
+	}
+
+	meta.CameraMake, _ = exifString(x, exif.Make)
+	meta.CameraModel, _ = exifString(x, exif.Model)
+	meta.Lens, _ = exifString(x, exif.LensModel)
+	meta.FocalLength = exifRational(x, exif.FocalLength)
+	meta.Aperture = exifRational(x, exif.FNumber)
+	meta.ExposureTime = exposureTime(x)
+	if tag, err := x.Get(exif.ISOSpeedRatings); err == nil {
+		if iso, err := tag.Int(0); err == nil && iso > 0 {
+			meta.ISO = iso
+		}
+	}
+
+	return meta, nil
+}
+
+// decodeEXIF returns the parsed EXIF block embedded in data, or nil.
+func decodeEXIF(data []byte) *exif.Exif {
+	var payload []byte
+	switch {
+	case isJPEG(data), isTIFF(data):
+		payload = data
+	case isPNG(data):
+		payload = pngEXIF(data)
+	case isWebP(data):
+		payload = webpEXIF(data)
+	}
+	if len(payload) == 0 {
+		return nil
+	}
+
+	x, err := exif.Decode(bytes.NewReader(payload))
+	if err != nil {
+		return nil
+	}
+	loadOffsetTime(x)
+	return x
+}
+
+// loadOffsetTime adds OffsetTimeOriginal from the EXIF sub-IFD to x.
+func loadOffsetTime(x *exif.Exif) {
+	tag, err := x.Get(exif.ExifIFDPointer)
+	if err != nil {
+		return
+	}
+	offset, err := tag.Int64(0)
+	if err != nil {
+		return
+	}
+
+	r := bytes.NewReader(x.Raw)
+	if _, err := r.Seek(offset, 0); err != nil {
+		return
+	}
+	dir, _, err := tiff.DecodeDir(r, x.Tiff.Order)
+	if err != nil {
+		return
+	}
+	x.LoadTags(dir, map[uint16]exif.FieldName{offsetTimeOriginalTag: offsetTimeOriginal}, false)
+}
+
+// captureTime combines DateTimeOriginal, SubSecTimeOriginal and
+// OffsetTimeOriginal. Without an offset the camera's wall-clock time is taken
+// as UTC, matching how manually entered dates are stored.
+func captureTime(x *exif.Exif) (time.Time, bool) {
+	value, ok := exifString(x, exif.DateTimeOriginal)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	loc := time.UTC
+	if offset, ok := exifString(x, offsetTimeOriginal); ok {
+		if parsed, err := time.Parse("-07:00", offset); err == nil {
+			_, seconds := parsed.Zone()
+			loc = time.FixedZone(offset, seconds)
+		}
+	}
+
+	t, err := time.ParseInLocation(exifTimeLayout, value, loc)
+	if err != nil || t.Year() < 1 {
+		return time.Time{}, false
+	}
+
+	if subsec, ok := exifString(x, exif.SubSecTimeOriginal); ok {
+		t = t.Add(subsecDuration(subsec))
+	}
+
+	return t.UTC(), true
+}
+
+// subsecDuration interprets an EXIF SubSecTime value, which holds the leading
+// decimal digits of the fractional second.
+func subsecDuration(value string) time.Duration {
+	digits := value
+	if len(digits) > 9 {
+		digits = digits[:9]
+	}
+
+	var nanos int64
+	for i := 0; i < 9; i++ {
+		nanos *= 10
+		if i < len(digits) {
+			if digits[i] < '0' || digits[i] > '9' {
+				return 0
+			}
+			nanos += int64(digits[i] - '0')
+		}
+	}
+	return time.Duration(nanos)
+}
+
+func exifString(x *exif.Exif, name exif.FieldName) (string, bool) {
+	tag, err := x.Get(name)
+	if err != nil {
+		return "", false
+	}
+	value, err := tag.StringVal()
+	if err != nil {
+		return "", false
+	}
+	value = strings.TrimSpace(strings.TrimRight(value, "\x00"))
+	return value, value != ""
+}
+
+// exifRational returns a positive rational tag as a float, or zero.
+func exifRational(x *exif.Exif, name exif.FieldName) float64 {
+	tag, err := x.Get(name)
+	if err != nil {
+		return 0
+	}
+	num, den, err := tag.Rat2(0)
+	if err != nil || num <= 0 || den <= 0 {
+		return 0
+	}
+	return float64(num) / float64(den)
+}
+
+// exposureTime formats ExposureTime the way photographers write it: a
+// fraction of a second below one second, whole or decimal seconds above.
+func exposureTime(x *exif.Exif) string {
+	tag, err := x.Get(exif.ExposureTime)
+	if err != nil {
+		return ""
+	}
+	num, den, err := tag.Rat2(0)
+	if err != nil || num <= 0 || den <= 0 {
+		return ""
+	}
+	if num < den {
+		return "1/" + strconv.FormatInt(int64(math.Round(float64(den)/float64(num))), 10)
+	}
+	return strconv.FormatFloat(float64(num)/float64(den), 'f', -1, 64)
+}
+
+// pngEXIF returns the payload of the eXIf chunk, if any.
+func pngEXIF(data []byte) []byte {
+	rest := data[len(pngSignature):]
+	for len(rest) >= 12 {
+		length := binary.BigEndian.Uint32(rest[:4])
+		if uint64(length) > uint64(len(rest)-12) {
+			return nil
+		}
+		if string(rest[4:8]) == "eXIf" {
+			return rest[8 : 8+length]
+		}
+		rest = rest[12+length:]
+	}
+	return nil
+}
+
+// webpEXIF returns the payload of the EXIF chunk, if any, without the
+// "Exif\0\0" prefix some encoders add.
+func webpEXIF(data []byte) []byte {
+	rest := data[12:]
+	for len(rest) >= 8 {
+		size := uint64(binary.LittleEndian.Uint32(rest[4:8]))
+		if size > uint64(len(rest)-8) {
+			return nil
+		}
+		if string(rest[:4]) == "EXIF" {
+			return bytes.TrimPrefix(rest[8:8+size], []byte("Exif\x00\x00"))
+		}
+		next := 8 + size + size&1
+		if next > uint64(len(rest)) {
+			return nil
+		}
+		rest = rest[next:]
+	}
+	return nil
+}
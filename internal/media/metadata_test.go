@@ -0,0 +1,96 @@
+package media_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Oxyrus/memories/internal/media"
+)
+
+func TestReadMetadataCaptureTime(t *testing.T) {
+	meta, err := media.ReadMetadata(filepath.Join("testdata", "camera.jpg"))
+	if err != nil {
+		t.Fatalf("ReadMetadata: %v", err)
+	}
+
+	// 18:30:05.25 at +02:00.
+	want := time.Date(2024, 7, 14, 16, 30, 5, 250_000_000, time.UTC)
+	if meta.TakenAt == nil || !meta.TakenAt.Equal(want) {
+		t.Fatalf("expected TakenAt %v, got %v", want, meta.TakenAt)
+	}
+	if meta.TakenAt.Location() != time.UTC {
+		t.Fatalf("expected TakenAt in UTC, got %v", meta.TakenAt.Location())
+	}
+}
+
+func TestReadMetadataCamera(t *testing.T) {
+	meta, err := media.ReadMetadata(filepath.Join("testdata", "camera.jpg"))
+	if err != nil {
+		t.Fatalf("ReadMetadata: %v", err)
+	}
+
+	meta.TakenAt = nil
+	want := media.Metadata{
+		CameraMake:   "Fujifilm",
+		CameraModel:  "X-T4",
+		Lens:         "XF35mmF1.4 R",
+		FocalLength:  35,
+		Aperture:     2.8,
+		ExposureTime: "1/250",
+		ISO:          400,
+	}
+	if meta != want {
+		t.Fatalf("expected %+v, got %+v", want, meta)
+	}
+	if !meta.HasCamera() {
+		t.Fatal("expected HasCamera to be true")
+	}
+}
+
+func TestReadMetadataWithoutEXIF(t *testing.T) {
+	for _, fixture := range []string{"metadata.gif", "metadata.png"} {
+		t.Run(fixture, func(t *testing.T) {
+			meta, err := media.ReadMetadata(filepath.Join("testdata", fixture))
+			if err != nil {
+				t.Fatalf("ReadMetadata: %v", err)
+			}
+			if meta.TakenAt != nil || meta.HasCamera() {
+				t.Fatalf("expected no metadata, got %+v", meta)
+			}
+		})
+	}
+}
+
+func TestSanitizeStripsCameraEXIF(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "camera.jpg"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "camera.jpg")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := media.Sanitize(path); err != nil {
+		t.Fatalf("Sanitize: %v", err)
+	}
+
+	clean, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read sanitized: %v", err)
+	}
+	if bytes.Contains(clean, []byte("Exif\x00\x00")) {
+		t.Fatal("expected EXIF block to be removed")
+	}
+
+	meta, err := media.ReadMetadata(path)
+	if err != nil {
+		t.Fatalf("ReadMetadata: %v", err)
+	}
+	if meta.TakenAt != nil {
+		t.Fatalf("expected sanitized file to carry no capture time, got %v", meta.TakenAt)
+	}
+}
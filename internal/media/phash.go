@@ -0,0 +1,110 @@
+package media
+
+import (
+	"fmt"
+	"math/bits"
+	"path/filepath"
+	"strconv"
+
+	"github.com/disintegration/imaging"
+)
+
+// PerceptualHash is a 64-bit difference hash (dHash) of an image. Visually
+// similar images, such as burst shots or re-exported edits, produce hashes a
+// small Hamming distance apart even when their bytes differ.
+type PerceptualHash uint64
+
+// ComputePerceptualHash returns the dHash of the image at path. The image is
+// reduced to a 9x8 greyscale thumbnail and each bit records whether a pixel
+// is brighter than its right-hand neighbour.
+func ComputePerceptualHash(path string) (PerceptualHash, error) {
+	src, err := imaging.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("media: decode %s: %w", filepath.Base(path), err)
+	}
+
+	small := imaging.Grayscale(imaging.Resize(src, 9, 8, imaging.Box))
+
+	var hash PerceptualHash
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			left := small.Pix[small.PixOffset(x, y)]
+			right := small.Pix[small.PixOffset(x+1, y)]
+			hash <<= 1
+			if left > right {
+				hash |= 1
+			}
+		}
+	}
+	return hash, nil
+}
+
+// ParsePerceptualHash parses the hexadecimal form produced by String.
+func ParsePerceptualHash(s string) (PerceptualHash, error) {
+	value, err := strconv.ParseUint(s, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("media: parse perceptual hash %q: %w", s, err)
+	}
+	return PerceptualHash(value), nil
+}
+
+// String returns the hash as 16 hexadecimal digits.
+func (h PerceptualHash) String() string {
+	return fmt.Sprintf("%016x", uint64(h))
+}
+
+// Distance returns the number of differing bits between h and other, from 0
+// (identical) to 64.
+func (h PerceptualHash) Distance(other PerceptualHash) int {
+	return bits.OnesCount64(uint64(h ^ other))
+}
+
+// GroupSimilar clusters hashes whose distance is at most maxDistance, linking
+// a hash into a group when it is close to any member. It returns the indexes
+// of each group with two or more members, in order of their first member.
+func GroupSimilar(hashes []PerceptualHash, maxDistance int) [][]int {
+	parent := make([]int, len(hashes))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for i := range hashes {
+		for j := i + 1; j < len(hashes); j++ {
+			if hashes[i].Distance(hashes[j]) > maxDistance {
+				continue
+			}
+			if ri, rj := find(i), find(j); ri != rj {
+				if ri < rj {
+					parent[rj] = ri
+				} else {
+					parent[ri] = rj
+				}
+			}
+		}
+	}
+
+	members := make(map[int][]int)
+	var roots []int
+	for i := range hashes {
+		root := find(i)
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], i)
+	}
+
+	var groups [][]int
+	for _, root := range roots {
+		if len(members[root]) > 1 {
+			groups = append(groups, members[root])
+		}
+	}
+	return groups
+}
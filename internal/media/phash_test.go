@@ -0,0 +1,104 @@
+package media_test
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/disintegration/imaging"
+
+	"github.com/Oxyrus/memories/internal/media"
+)
+
+func TestPerceptualHashMatchesReencodedImage(t *testing.T) {
+	dir := t.TempDir()
+	scene := func(x, y int) color.Color {
+		return color.Gray{Y: uint8(128 + 100*math.Sin(float64(x)/17)*math.Cos(float64(y)/23))}
+	}
+
+	original := writeScene(t, filepath.Join(dir, "original.png"), 160, 120, scene)
+	// A brighter, lossy re-export of the same scene.
+	edited := writeScene(t, filepath.Join(dir, "edited.jpg"), 160, 120, func(x, y int) color.Color {
+		g := scene(x, y).(color.Gray)
+		return color.Gray{Y: uint8(min(255, int(g.Y)+12))}
+	})
+	// An unrelated scene.
+	other := writeScene(t, filepath.Join(dir, "other.png"), 160, 120, func(x, y int) color.Color {
+		return color.Gray{Y: uint8(128 + 100*math.Cos(float64(x)/9)*math.Sin(float64(y)/13))}
+	})
+
+	hashes := make([]media.PerceptualHash, 0, 3)
+	for _, path := range []string{original, edited, other} {
+		hash, err := media.ComputePerceptualHash(path)
+		if err != nil {
+			t.Fatalf("ComputePerceptualHash(%s): %v", filepath.Base(path), err)
+		}
+		hashes = append(hashes, hash)
+	}
+
+	if d := hashes[0].Distance(hashes[1]); d > 6 {
+		t.Fatalf("expected re-encoded image to be within 6 bits, got %d", d)
+	}
+	if d := hashes[0].Distance(hashes[2]); d < 20 {
+		t.Fatalf("expected a different image to be at least 20 bits away, got %d", d)
+	}
+}
+
+func TestPerceptualHashString(t *testing.T) {
+	hash := media.PerceptualHash(0x00ff00ff00ff00f1)
+	if got := hash.String(); got != "00ff00ff00ff00f1" {
+		t.Fatalf("expected 16 hex digits, got %q", got)
+	}
+
+	parsed, err := media.ParsePerceptualHash(hash.String())
+	if err != nil {
+		t.Fatalf("ParsePerceptualHash: %v", err)
+	}
+	if parsed != hash {
+		t.Fatalf("expected %v after round trip, got %v", hash, parsed)
+	}
+
+	if _, err := media.ParsePerceptualHash(""); err == nil {
+		t.Fatal("expected an error for an empty hash")
+	}
+}
+
+func TestGroupSimilar(t *testing.T) {
+	hashes := []media.PerceptualHash{
+		0x0000000000000000,
+		0xffffffffffffffff,
+		0x0000000000000007, // 3 bits from the first
+		0xfffffffffffffff0, // 4 bits from the second
+		0x00000000000000ff, // 5 bits from the third, 8 from the first
+		0x0f0f0f0f0f0f0f0f,
+	}
+
+	got := media.GroupSimilar(hashes, 5)
+	want := [][]int{{0, 2, 4}, {1, 3}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected groups %v, got %v", want, got)
+	}
+
+	if got := media.GroupSimilar(hashes, 0); got != nil {
+		t.Fatalf("expected no groups at distance 0, got %v", got)
+	}
+}
+
+func writeScene(t *testing.T, path string, width, height int, pixel func(x, y int) color.Color) string {
+	t.Helper()
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, pixel(x, y))
+		}
+	}
+
+	if err := imaging.Save(img, path, imaging.JPEGQuality(80)); err != nil {
+		t.Fatalf("save test image: %v", err)
+	}
+	return path
+}
@@ -0,0 +1,77 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngKeepChunks lists the chunks that affect how an image is rendered. Every
+// other chunk, notably eXIf, tEXt, zTXt, iTXt and tIME, is dropped.
+var pngKeepChunks = map[string]bool{
+	"IHDR": true,
+	"PLTE": true,
+	"IDAT": true,
+	"IEND": true,
+	"tRNS": true,
+	"cHRM": true,
+	"gAMA": true,
+	"iCCP": true,
+	"sBIT": true,
+	"sRGB": true,
+	"bKGD": true,
+	"hIST": true,
+	"pHYs": true,
+	"sPLT": true,
+	// APNG animation control and frame data.
+	"acTL": true,
+	"fcTL": true,
+	"fdAT": true,
+}
+
+func isPNG(data []byte) bool {
+	return bytes.HasPrefix(data, pngSignature)
+}
+
+// stripPNG copies the chunks in pngKeepChunks verbatim and drops the rest.
+// Anything after IEND is discarded.
+func stripPNG(data []byte) ([]byte, error) {
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(pngSignature)
+
+	rest := data[len(pngSignature):]
+	first := true
+	for {
+		if len(rest) < 12 {
+			return nil, fmt.Errorf("%w: png: truncated chunk", ErrMalformed)
+		}
+
+		length := binary.BigEndian.Uint32(rest[:4])
+		if uint64(length) > uint64(len(rest)-12) {
+			return nil, fmt.Errorf("%w: png: chunk length out of range", ErrMalformed)
+		}
+
+		chunk := rest[:12+int(length)]
+		kind := string(chunk[4:8])
+		crc := binary.BigEndian.Uint32(chunk[8+length:])
+		if crc32.ChecksumIEEE(chunk[4:8+length]) != crc {
+			return nil, fmt.Errorf("%w: png: bad checksum in %q chunk", ErrMalformed, kind)
+		}
+		if first && kind != "IHDR" {
+			return nil, fmt.Errorf("%w: png: missing IHDR", ErrMalformed)
+		}
+		first = false
+
+		if pngKeepChunks[kind] {
+			out.Write(chunk)
+		}
+
+		if kind == "IEND" {
+			return out.Bytes(), nil
+		}
+		rest = rest[len(chunk):]
+	}
+}
@@ -0,0 +1,143 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+
+	"github.com/disintegration/imaging"
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+var (
+	// ErrMalformed is returned when an image cannot be parsed well enough to
+	// remove its metadata.
+	ErrMalformed = errors.New("media: malformed image")
+	// ErrUnsupported is returned for files that are not in one of the image
+	// formats Sanitize knows how to clean.
+	ErrUnsupported = errors.New("media: unsupported image format")
+)
+
+const sanitizeJPEGQuality = 95
+
+// Sanitize strips embedded metadata (EXIF, XMP, text comments and similar)
+// from the image at path, rewriting it in place. JPEGs are re-encoded with
+// their EXIF orientation applied. PNG, GIF, WebP and TIFF files are rewritten
+// losslessly: metadata chunks are dropped while pixel data, colour profiles
+// and animation frames are copied unchanged. Sanitize fails closed: files that
+// cannot be parsed return ErrMalformed, files in other formats return
+// ErrUnsupported, and in both cases the file is left as it was for the caller
+// to discard.
+func Sanitize(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var clean []byte
+	switch {
+	case isJPEG(data):
+		clean, err = sanitizeJPEG(data)
+	case isPNG(data):
+		clean, err = stripPNG(data)
+	case isGIF(data):
+		clean, err = stripGIF(data)
+	case isWebP(data):
+		clean, err = stripWebP(data)
+	case isTIFF(data):
+		clean, err = stripTIFF(data)
+	default:
+		return ErrUnsupported
+	}
+	if err != nil {
+		return err
+	}
+
+	return replaceFile(path, clean)
+}
+
+func isJPEG(data []byte) bool {
+	return len(data) > 2 && data[0] == 0xff && data[1] == 0xd8
+}
+
+func sanitizeJPEG(data []byte) ([]byte, error) {
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: jpeg: %v", ErrMalformed, err)
+	}
+
+	img = normalizeOrientation(img, readOrientation(data))
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: sanitizeJPEGQuality}); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func readOrientation(data []byte) int {
+	ex, err := exif.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 1
+	}
+
+	tag, err := ex.Get(exif.Orientation)
+	if err != nil {
+		return 1
+	}
+
+	val, err := tag.Int(0)
+	if err != nil {
+		return 1
+	}
+
+	return val
+}
+
+func normalizeOrientation(img image.Image, orientation int) image.Image {
+	switch orientation {
+	case 2:
+		return imaging.FlipH(img)
+	case 3:
+		return imaging.Rotate180(img)
+	case 4:
+		return imaging.FlipV(img)
+	case 5:
+		return imaging.FlipH(imaging.Rotate90(img))
+	case 6:
+		return imaging.Rotate90(img)
+	case 7:
+		return imaging.FlipV(imaging.Rotate90(img))
+	case 8:
+		return imaging.Rotate270(img)
+	default:
+		return img
+	}
+}
+
+// replaceFile atomically swaps the contents of path for data.
+func replaceFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "photo-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,278 @@
+package media_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/image/tiff"
+	"golang.org/x/image/webp"
+
+	"github.com/Oxyrus/memories/internal/media"
+)
+
+// Every fixture in testdata embeds this marker in its metadata (EXIF, XMP,
+// text chunks, comments, GPS tags) but never in its pixel data.
+const fixtureSecret = "memories-secret"
+
+func TestSanitizeStripsMetadata(t *testing.T) {
+	decodeTIFF := func(data []byte) (image.Image, error) { return tiff.Decode(bytes.NewReader(data)) }
+	decodePNG := func(data []byte) (image.Image, error) { return png.Decode(bytes.NewReader(data)) }
+	decodeGIF := func(data []byte) (image.Image, error) { return gif.Decode(bytes.NewReader(data)) }
+
+	tests := []struct {
+		fixture string
+		decode  func([]byte) (image.Image, error)
+	}{
+		{fixture: "metadata.png", decode: decodePNG},
+		{fixture: "metadata.gif", decode: decodeGIF},
+		{fixture: "metadata.tiff", decode: decodeTIFF},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.fixture, func(t *testing.T) {
+			original, clean := sanitizeFixture(t, tc.fixture)
+
+			if bytes.Contains(clean, []byte(fixtureSecret)) {
+				t.Fatalf("expected metadata to be stripped from %s", tc.fixture)
+			}
+
+			before, err := tc.decode(original)
+			if err != nil {
+				t.Fatalf("decode original: %v", err)
+			}
+			after, err := tc.decode(clean)
+			if err != nil {
+				t.Fatalf("decode sanitized: %v", err)
+			}
+			assertSamePixels(t, before, after)
+		})
+	}
+}
+
+func TestSanitizeKeepsGIFAnimation(t *testing.T) {
+	_, clean := sanitizeFixture(t, "metadata.gif")
+
+	anim, err := gif.DecodeAll(bytes.NewReader(clean))
+	if err != nil {
+		t.Fatalf("decode sanitized: %v", err)
+	}
+	if len(anim.Image) != 2 {
+		t.Fatalf("expected 2 frames, got %d", len(anim.Image))
+	}
+	if anim.Delay[0] != 10 || anim.Delay[1] != 20 {
+		t.Fatalf("expected frame delays to be kept, got %v", anim.Delay)
+	}
+	if anim.LoopCount != 0 {
+		t.Fatalf("expected infinite loop to be kept, got %d", anim.LoopCount)
+	}
+}
+
+func TestSanitizeStripsWebPMetadata(t *testing.T) {
+	for _, fixture := range []string{"metadata.webp", "metadata-animated.webp"} {
+		t.Run(fixture, func(t *testing.T) {
+			original, clean := sanitizeFixture(t, fixture)
+
+			if bytes.Contains(clean, []byte(fixtureSecret)) {
+				t.Fatalf("expected metadata to be stripped from %s", fixture)
+			}
+
+			var want []riffChunk
+			for _, chunk := range webpChunks(t, original) {
+				if chunk.kind != "EXIF" && chunk.kind != "XMP " {
+					want = append(want, chunk)
+				}
+			}
+			got := webpChunks(t, clean)
+			if len(got) != len(want) {
+				t.Fatalf("expected %d chunks, got %d", len(want), len(got))
+			}
+
+			for i, chunk := range got {
+				if chunk.kind != want[i].kind {
+					t.Fatalf("chunk %d is %q, want %q", i, chunk.kind, want[i].kind)
+				}
+				if chunk.kind == "VP8X" {
+					if flags := chunk.data[0]; flags&0x0c != 0 {
+						t.Fatalf("expected EXIF/XMP flags to be cleared, got %#x", flags)
+					} else if flags|0x0c != want[i].data[0]|0x0c {
+						t.Fatalf("expected other VP8X flags to be kept, got %#x", flags)
+					}
+					continue
+				}
+				if !bytes.Equal(chunk.data, want[i].data) {
+					t.Fatalf("%q chunk %d changed", chunk.kind, i)
+				}
+			}
+		})
+	}
+}
+
+func TestSanitizeKeepsWebPPixels(t *testing.T) {
+	_, clean := sanitizeFixture(t, "metadata.webp")
+
+	// The decoder in x/image does not handle extended lossless files, so
+	// decode the image chunk on its own as a simple-format WebP.
+	for _, chunk := range webpChunks(t, clean) {
+		if chunk.kind != "VP8L" {
+			continue
+		}
+
+		simple := make([]byte, 20, 20+len(chunk.data))
+		copy(simple, "RIFF")
+		binary.LittleEndian.PutUint32(simple[4:], uint32(12+len(chunk.data)))
+		copy(simple[8:], "WEBPVP8L")
+		binary.LittleEndian.PutUint32(simple[16:], uint32(len(chunk.data)))
+		simple = append(simple, chunk.data...)
+
+		if _, err := webp.Decode(bytes.NewReader(simple)); err != nil {
+			t.Fatalf("decode image chunk: %v", err)
+		}
+		return
+	}
+	t.Fatal("expected a VP8L chunk")
+}
+
+func TestSanitizeKeepsTIFFPages(t *testing.T) {
+	original, clean := sanitizeFixture(t, "metadata.tiff")
+
+	if got, want := tiffPageCount(t, clean), tiffPageCount(t, original); got != want {
+		t.Fatalf("expected %d pages, got %d", want, got)
+	}
+}
+
+func TestSanitizeRejectsTruncatedFiles(t *testing.T) {
+	for _, fixture := range []string{"metadata.png", "metadata.gif", "metadata.webp", "metadata.tiff"} {
+		t.Run(fixture, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", fixture))
+			if err != nil {
+				t.Fatalf("read fixture: %v", err)
+			}
+
+			path := filepath.Join(t.TempDir(), fixture)
+			if err := os.WriteFile(path, data[:len(data)/2], 0o644); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+
+			if err := media.Sanitize(path); !errors.Is(err, media.ErrMalformed) {
+				t.Fatalf("expected ErrMalformed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSanitizeFailsClosed(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    error
+	}{
+		{name: "corrupt jpeg", content: []byte{0xff, 0xd8, 0xff, 0xe1, 0x00, 0x10}, want: media.ErrMalformed},
+		{name: "unknown format", content: []byte("<svg xmlns=\"http://www.w3.org/2000/svg\"/>"), want: media.ErrUnsupported},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "upload")
+			if err := os.WriteFile(path, tc.content, 0o644); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+
+			if err := media.Sanitize(path); !errors.Is(err, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func sanitizeFixture(t *testing.T, fixture string) (original, clean []byte) {
+	t.Helper()
+
+	original, err := os.ReadFile(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	if !bytes.Contains(original, []byte(fixtureSecret)) {
+		t.Fatalf("fixture %s does not carry the metadata marker", fixture)
+	}
+
+	path := filepath.Join(t.TempDir(), fixture)
+	if err := os.WriteFile(path, original, 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if err := media.Sanitize(path); err != nil {
+		t.Fatalf("Sanitize: %v", err)
+	}
+
+	clean, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read sanitized: %v", err)
+	}
+	return original, clean
+}
+
+func assertSamePixels(t *testing.T, want, got image.Image) {
+	t.Helper()
+
+	if want.Bounds() != got.Bounds() {
+		t.Fatalf("bounds changed from %v to %v", want.Bounds(), got.Bounds())
+	}
+	b := want.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			wr, wg, wb, wa := want.At(x, y).RGBA()
+			gr, gg, gb, ga := got.At(x, y).RGBA()
+			if wr != gr || wg != gg || wb != gb || wa != ga {
+				t.Fatalf("pixel (%d,%d) changed", x, y)
+			}
+		}
+	}
+}
+
+type riffChunk struct {
+	kind string
+	data []byte
+}
+
+func webpChunks(t *testing.T, data []byte) []riffChunk {
+	t.Helper()
+
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		t.Fatal("not a WebP file")
+	}
+	if size := binary.LittleEndian.Uint32(data[4:8]); int(size) != len(data)-8 {
+		t.Fatalf("RIFF size %d does not match file length %d", size, len(data))
+	}
+
+	var chunks []riffChunk
+	rest := data[12:]
+	for len(rest) >= 8 {
+		size := int(binary.LittleEndian.Uint32(rest[4:8]))
+		chunks = append(chunks, riffChunk{kind: string(rest[:4]), data: rest[8 : 8+size]})
+		rest = rest[8+size+size%2:]
+	}
+	return chunks
+}
+
+func tiffPageCount(t *testing.T, data []byte) int {
+	t.Helper()
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if data[0] == 'M' {
+		order = binary.BigEndian
+	}
+
+	pages := 0
+	for offset := order.Uint32(data[4:8]); offset != 0; pages++ {
+		count := int(order.Uint16(data[offset:]))
+		offset = order.Uint32(data[int(offset)+2+12*count:])
+	}
+	return pages
+}
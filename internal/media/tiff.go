@@ -0,0 +1,306 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+const (
+	tiffStripOffsets    = 273
+	tiffStripByteCounts = 279
+	tiffTileOffsets     = 324
+	tiffTileByteCounts  = 325
+
+	tiffTypeShort = 3
+	tiffTypeLong  = 4
+
+	tiffMaxIFDs = 1024
+)
+
+// tiffKeepTags lists the baseline and extension tags that describe how pixel
+// data is laid out and rendered. Descriptive tags (make, model, software,
+// artist, dates), the EXIF and GPS sub-IFDs, XMP, IPTC and Photoshop blocks
+// are all dropped.
+var tiffKeepTags = map[uint16]bool{
+	254:                 true, // NewSubfileType
+	255:                 true, // SubfileType
+	256:                 true, // ImageWidth
+	257:                 true, // ImageLength
+	258:                 true, // BitsPerSample
+	259:                 true, // Compression
+	262:                 true, // PhotometricInterpretation
+	263:                 true, // Threshholding
+	264:                 true, // CellWidth
+	265:                 true, // CellLength
+	266:                 true, // FillOrder
+	tiffStripOffsets:    true,
+	274:                 true, // Orientation
+	277:                 true, // SamplesPerPixel
+	278:                 true, // RowsPerStrip
+	tiffStripByteCounts: true,
+	280:                 true, // MinSampleValue
+	281:                 true, // MaxSampleValue
+	282:                 true, // XResolution
+	283:                 true, // YResolution
+	284:                 true, // PlanarConfiguration
+	296:                 true, // ResolutionUnit
+	297:                 true, // PageNumber
+	301:                 true, // TransferFunction
+	317:                 true, // Predictor
+	318:                 true, // WhitePoint
+	319:                 true, // PrimaryChromaticities
+	320:                 true, // ColorMap
+	321:                 true, // HalftoneHints
+	322:                 true, // TileWidth
+	323:                 true, // TileLength
+	tiffTileOffsets:     true,
+	tiffTileByteCounts:  true,
+	332:                 true, // InkSet
+	338:                 true, // ExtraSamples
+	339:                 true, // SampleFormat
+	340:                 true, // SMinSampleValue
+	341:                 true, // SMaxSampleValue
+	347:                 true, // JPEGTables
+	529:                 true, // YCbCrCoefficients
+	530:                 true, // YCbCrSubSampling
+	531:                 true, // YCbCrPositioning
+	532:                 true, // ReferenceBlackWhite
+	34675:               true, // ICC profile
+}
+
+var tiffTypeSizes = map[uint16]int{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8, 13: 4,
+}
+
+type tiffEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+func isTIFF(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("II*\x00")) || bytes.HasPrefix(data, []byte("MM\x00*"))
+}
+
+// stripTIFF rewrites every image file directory in the chain keeping only the
+// tags in tiffKeepTags. Strip and tile data is copied unchanged and its
+// offsets are rewritten to match the new layout.
+func stripTIFF(data []byte) ([]byte, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("%w: tiff: truncated header", ErrMalformed)
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if data[0] == 'M' {
+		order = binary.BigEndian
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:4])
+	out.Write(make([]byte, 4)) // first IFD offset, patched below
+	nextPointer := 4
+
+	offset := order.Uint32(data[4:8])
+	seen := make(map[uint32]bool)
+	for offset != 0 {
+		if seen[offset] || len(seen) >= tiffMaxIFDs {
+			return nil, fmt.Errorf("%w: tiff: IFD loop", ErrMalformed)
+		}
+		seen[offset] = true
+
+		entries, next, err := readTIFFIFD(data, order, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		ifdOffset, err := writeTIFFIFD(out, data, order, entries)
+		if err != nil {
+			return nil, err
+		}
+
+		result := out.Bytes()
+		order.PutUint32(result[nextPointer:], ifdOffset)
+		nextPointer = int(ifdOffset) + 2 + 12*countKept(entries)
+		offset = next
+	}
+
+	if nextPointer == 4 {
+		return nil, fmt.Errorf("%w: tiff: no image directory", ErrMalformed)
+	}
+
+	return out.Bytes(), nil
+}
+
+func countKept(entries []tiffEntry) int {
+	kept := 0
+	for _, entry := range entries {
+		if tiffKeepTags[entry.tag] {
+			kept++
+		}
+	}
+	return kept
+}
+
+func readTIFFIFD(data []byte, order binary.ByteOrder, offset uint32) ([]tiffEntry, uint32, error) {
+	if uint64(offset)+2 > uint64(len(data)) {
+		return nil, 0, fmt.Errorf("%w: tiff: IFD offset out of range", ErrMalformed)
+	}
+
+	pos := int(offset)
+	count := int(order.Uint16(data[pos:]))
+	pos += 2
+	if pos+12*count+4 > len(data) {
+		return nil, 0, fmt.Errorf("%w: tiff: truncated IFD", ErrMalformed)
+	}
+
+	entries := make([]tiffEntry, 0, count)
+	for i := 0; i < count; i++ {
+		raw := data[pos+12*i : pos+12*i+12]
+		entry := tiffEntry{
+			tag:   order.Uint16(raw[0:2]),
+			typ:   order.Uint16(raw[2:4]),
+			count: order.Uint32(raw[4:8]),
+		}
+
+		size, ok := tiffTypeSizes[entry.typ]
+		if !ok {
+			if tiffKeepTags[entry.tag] {
+				return nil, 0, fmt.Errorf("%w: tiff: tag %d has unknown type %d", ErrMalformed, entry.tag, entry.typ)
+			}
+			continue
+		}
+
+		length := uint64(size) * uint64(entry.count)
+		if length <= 4 {
+			entry.value = raw[8 : 8+length]
+		} else {
+			valueOffset := uint64(order.Uint32(raw[8:12]))
+			if valueOffset+length > uint64(len(data)) {
+				return nil, 0, fmt.Errorf("%w: tiff: tag %d value out of range", ErrMalformed, entry.tag)
+			}
+			entry.value = data[valueOffset : valueOffset+length]
+		}
+		entries = append(entries, entry)
+	}
+
+	next := order.Uint32(data[pos+12*count:])
+	return entries, next, nil
+}
+
+// writeTIFFIFD appends the kept entries of one directory, together with their
+// out-of-line values and image data, and returns the offset of the directory.
+// The next-IFD pointer is written as zero for the caller to patch.
+func writeTIFFIFD(out *bytes.Buffer, data []byte, order binary.ByteOrder, entries []tiffEntry) (uint32, error) {
+	kept := make([]tiffEntry, 0, len(entries))
+	byTag := make(map[uint16]tiffEntry, len(entries))
+	for _, entry := range entries {
+		if tiffKeepTags[entry.tag] {
+			kept = append(kept, entry)
+			byTag[entry.tag] = entry
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].tag < kept[j].tag })
+
+	for _, pair := range [][2]uint16{{tiffStripOffsets, tiffStripByteCounts}, {tiffTileOffsets, tiffTileByteCounts}} {
+		offsetsEntry, ok := byTag[pair[0]]
+		if !ok {
+			continue
+		}
+		countsEntry, ok := byTag[pair[1]]
+		if !ok {
+			return 0, fmt.Errorf("%w: tiff: tag %d without byte counts", ErrMalformed, pair[0])
+		}
+
+		offsets, err := tiffUints(offsetsEntry, order)
+		if err != nil {
+			return 0, err
+		}
+		counts, err := tiffUints(countsEntry, order)
+		if err != nil {
+			return 0, err
+		}
+		if len(offsets) != len(counts) {
+			return 0, fmt.Errorf("%w: tiff: mismatched image data tables", ErrMalformed)
+		}
+
+		moved := make([]byte, 4*len(offsets))
+		for i := range offsets {
+			start, length := uint64(offsets[i]), uint64(counts[i])
+			if start+length > uint64(len(data)) {
+				return 0, fmt.Errorf("%w: tiff: image data out of range", ErrMalformed)
+			}
+			padTIFF(out)
+			order.PutUint32(moved[4*i:], uint32(out.Len()))
+			out.Write(data[start : start+length])
+		}
+
+		for i := range kept {
+			if kept[i].tag == pair[0] {
+				kept[i].typ = tiffTypeLong
+				kept[i].value = moved
+			}
+		}
+	}
+
+	valueOffsets := make([]uint32, len(kept))
+	for i, entry := range kept {
+		if len(entry.value) > 4 {
+			padTIFF(out)
+			valueOffsets[i] = uint32(out.Len())
+			out.Write(entry.value)
+		}
+	}
+
+	padTIFF(out)
+	ifdOffset := uint32(out.Len())
+	if uint64(ifdOffset)+uint64(2+12*len(kept)+4) > 1<<32-1 {
+		return 0, fmt.Errorf("%w: tiff: file too large", ErrMalformed)
+	}
+
+	buf := make([]byte, 2+12*len(kept)+4)
+	order.PutUint16(buf, uint16(len(kept)))
+	for i, entry := range kept {
+		raw := buf[2+12*i:]
+		order.PutUint16(raw[0:2], entry.tag)
+		order.PutUint16(raw[2:4], entry.typ)
+		order.PutUint32(raw[4:8], uint32(len(entry.value)/tiffTypeSizes[entry.typ]))
+		if len(entry.value) > 4 {
+			order.PutUint32(raw[8:12], valueOffsets[i])
+		} else {
+			copy(raw[8:12], entry.value)
+		}
+	}
+	out.Write(buf)
+
+	return ifdOffset, nil
+}
+
+func tiffUints(entry tiffEntry, order binary.ByteOrder) ([]uint32, error) {
+	switch entry.typ {
+	case tiffTypeShort:
+		values := make([]uint32, len(entry.value)/2)
+		for i := range values {
+			values[i] = uint32(order.Uint16(entry.value[2*i:]))
+		}
+		return values, nil
+	case tiffTypeLong:
+		values := make([]uint32, len(entry.value)/4)
+		for i := range values {
+			values[i] = order.Uint32(entry.value[4*i:])
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("%w: tiff: tag %d has type %d, want SHORT or LONG", ErrMalformed, entry.tag, entry.typ)
+	}
+}
+
+// padTIFF aligns the next write to a word boundary, as the TIFF spec requires
+// for value and directory offsets.
+func padTIFF(out *bytes.Buffer) {
+	if out.Len()%2 == 1 {
+		out.WriteByte(0)
+	}
+}
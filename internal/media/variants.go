@@ -0,0 +1,123 @@
+// Package media implements the image processing applied to uploaded photos.
+package media
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// VariantSpec describes a resized derivative generated for every upload.
+type VariantSpec struct {
+	Name  string
+	Width int
+}
+
+// DefaultVariants are the derivative sizes generated for uploads, smallest
+// first.
+var DefaultVariants = []VariantSpec{
+	{Name: "thumb", Width: 320},
+	{Name: "medium", Width: 1280},
+	{Name: "large", Width: 2048},
+}
+
+// Variant is a derivative written next to its original. Filename is the base
+// name of the file inside the original's directory.
+type Variant struct {
+	Name     string
+	Filename string
+	Width    int
+	Height   int
+}
+
+const variantJPEGQuality = 85
+
+// GenerateVariants writes a resized copy of the image at srcPath for each spec
+// narrower than the source, next to the source file. Sizes that would upscale
+// are skipped. PNG sources produce PNG derivatives to keep transparency; all
+// other formats produce JPEGs. On error, files written so far are removed.
+func GenerateVariants(srcPath string, specs []VariantSpec) ([]Variant, error) {
+	src, err := imaging.Open(srcPath)
+	if err != nil {
+		return nil, fmt.Errorf("media: decode %s: %w", filepath.Base(srcPath), err)
+	}
+
+	dir := filepath.Dir(srcPath)
+	ext := strings.ToLower(filepath.Ext(srcPath))
+	base := strings.TrimSuffix(filepath.Base(srcPath), filepath.Ext(srcPath))
+	outExt := ".jpg"
+	if ext == ".png" {
+		outExt = ".png"
+	}
+
+	srcWidth := src.Bounds().Dx()
+	var written []Variant
+	for _, spec := range specs {
+		if spec.Width <= 0 || spec.Width >= srcWidth {
+			continue
+		}
+
+		resized := imaging.Resize(src, spec.Width, 0, imaging.Lanczos)
+		variant := Variant{
+			Name:     spec.Name,
+			Filename: base + "_" + spec.Name + outExt,
+			Width:    resized.Bounds().Dx(),
+			Height:   resized.Bounds().Dy(),
+		}
+
+		if err := saveVariant(resized, filepath.Join(dir, variant.Filename)); err != nil {
+			RemoveVariants(dir, written)
+			return nil, err
+		}
+		written = append(written, variant)
+	}
+
+	return written, nil
+}
+
+// RemoveVariants deletes derivative files from dir. Files that are already
+// gone are ignored; the first other failure is returned after trying all.
+func RemoveVariants(dir string, variants []Variant) error {
+	var firstErr error
+	for _, variant := range variants {
+		name := filepath.Base(variant.Filename)
+		if name == "." || name == string(filepath.Separator) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func saveVariant(img image.Image, path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "variant-*")
+	if err != nil {
+		return fmt.Errorf("media: create variant: %w", err)
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	format := imaging.JPEG
+	if strings.EqualFold(filepath.Ext(path), ".png") {
+		format = imaging.PNG
+	}
+	if err := imaging.Encode(tmp, img, format, imaging.JPEGQuality(variantJPEGQuality)); err != nil {
+		return fmt.Errorf("media: encode variant: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("media: write variant: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("media: write variant: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package media_test
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/disintegration/imaging"
+
+	"github.com/Oxyrus/memories/internal/media"
+)
+
+func TestGenerateVariantsSkipsUpscaling(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "photo.jpg")
+	writeTestImage(t, src, 1600, 1200)
+
+	variants, err := media.GenerateVariants(src, media.DefaultVariants)
+	if err != nil {
+		t.Fatalf("GenerateVariants: %v", err)
+	}
+
+	if len(variants) != 2 {
+		t.Fatalf("expected thumb and medium variants, got %+v", variants)
+	}
+
+	want := []media.Variant{
+		{Name: "thumb", Filename: "photo_thumb.jpg", Width: 320, Height: 240},
+		{Name: "medium", Filename: "photo_medium.jpg", Width: 1280, Height: 960},
+	}
+	for i, variant := range variants {
+		if variant != want[i] {
+			t.Fatalf("variant %d = %+v, want %+v", i, variant, want[i])
+		}
+		img, err := imaging.Open(filepath.Join(dir, variant.Filename))
+		if err != nil {
+			t.Fatalf("open %s: %v", variant.Filename, err)
+		}
+		if img.Bounds().Dx() != variant.Width || img.Bounds().Dy() != variant.Height {
+			t.Fatalf("%s has bounds %v", variant.Filename, img.Bounds())
+		}
+	}
+
+	if err := media.RemoveVariants(dir, variants); err != nil {
+		t.Fatalf("RemoveVariants: %v", err)
+	}
+	for _, variant := range variants {
+		if _, err := os.Stat(filepath.Join(dir, variant.Filename)); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be removed, stat err=%v", variant.Filename, err)
+		}
+	}
+	if _, err := os.Stat(src); err != nil {
+		t.Fatalf("expected original to remain: %v", err)
+	}
+}
+
+func TestGenerateVariantsRejectsNonImages(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "photo.jpg")
+	if err := os.WriteFile(src, []byte("not an image"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if _, err := media.GenerateVariants(src, media.DefaultVariants); err == nil {
+		t.Fatal("expected decode error")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected only the original to remain, found %d entries", len(entries))
+	}
+}
+
+func writeTestImage(t *testing.T, path string, width, height int) {
+	t.Helper()
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	if err := imaging.Save(img, path); err != nil {
+		t.Fatalf("save test image: %v", err)
+	}
+}
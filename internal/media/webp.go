@@ -0,0 +1,103 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// webpKeepChunks lists the RIFF chunks needed to render a WebP image. EXIF,
+// XMP and unknown chunks are dropped.
+var webpKeepChunks = map[string]bool{
+	"VP8 ": true,
+	"VP8L": true,
+	"VP8X": true,
+	"ALPH": true,
+	"ANIM": true,
+	"ANMF": true,
+	"ICCP": true,
+}
+
+// VP8X feature flags announcing EXIF and XMP chunks.
+const (
+	webpFlagEXIF = 0x08
+	webpFlagXMP  = 0x04
+)
+
+func isWebP(data []byte) bool {
+	return len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP"
+}
+
+// webpCanvasSize reads the canvas size from the VP8X header of an extended
+// WebP file. The x/image decoder only reports it for files with an alpha
+// channel, so it is parsed here for all of them.
+func webpCanvasSize(data []byte) (width, height int, ok bool) {
+	if !isWebP(data) || len(data) < 30 || string(data[12:16]) != "VP8X" {
+		return 0, 0, false
+	}
+	header := data[20:30]
+	width = 1 + (int(header[4]) | int(header[5])<<8 | int(header[6])<<16)
+	height = 1 + (int(header[7]) | int(header[8])<<8 | int(header[9])<<16)
+	return width, height, true
+}
+
+// stripWebP rebuilds the RIFF container from the chunks in webpKeepChunks and
+// clears the EXIF and XMP flags in the VP8X header.
+func stripWebP(data []byte) ([]byte, error) {
+	riffSize := binary.LittleEndian.Uint32(data[4:8])
+	if uint64(riffSize) < 4 || uint64(riffSize) > uint64(len(data)-8) {
+		return nil, fmt.Errorf("%w: webp: invalid RIFF size", ErrMalformed)
+	}
+
+	body := bytes.NewBuffer(make([]byte, 0, len(data)))
+	hasImage := false
+	rest := data[12 : 8+int(riffSize)]
+	for len(rest) > 0 {
+		if len(rest) < 8 {
+			return nil, fmt.Errorf("%w: webp: truncated chunk", ErrMalformed)
+		}
+
+		kind := string(rest[:4])
+		size := binary.LittleEndian.Uint32(rest[4:8])
+		padded := uint64(size) + uint64(size&1)
+		if uint64(size) > uint64(len(rest)-8) {
+			return nil, fmt.Errorf("%w: webp: chunk length out of range", ErrMalformed)
+		}
+		if padded > uint64(len(rest)-8) {
+			// Tolerate a missing pad byte on the final chunk.
+			padded = uint64(size)
+		}
+
+		chunk := rest[:8+int(padded)]
+		rest = rest[len(chunk):]
+		if !webpKeepChunks[kind] {
+			continue
+		}
+
+		switch kind {
+		case "VP8X":
+			if size < 1 {
+				return nil, fmt.Errorf("%w: webp: truncated VP8X chunk", ErrMalformed)
+			}
+			chunk = append([]byte(nil), chunk...)
+			chunk[8] &^= webpFlagEXIF | webpFlagXMP
+		case "VP8 ", "VP8L", "ANMF":
+			hasImage = true
+		}
+
+		body.Write(chunk)
+		if size&1 == 1 && len(chunk) == 8+int(size) {
+			body.WriteByte(0)
+		}
+	}
+
+	if !hasImage {
+		return nil, fmt.Errorf("%w: webp: no image data", ErrMalformed)
+	}
+
+	out := make([]byte, 12, 12+body.Len())
+	copy(out, "RIFF")
+	binary.LittleEndian.PutUint32(out[4:8], uint32(4+body.Len()))
+	copy(out[8:], "WEBP")
+	return append(out, body.Bytes()...), nil
+}
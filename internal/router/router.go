@@ -6,35 +6,60 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/Oxyrus/memories/internal/blob"
 	"github.com/Oxyrus/memories/internal/config"
 	"github.com/Oxyrus/memories/internal/http/handlers"
 	"github.com/Oxyrus/memories/internal/http/middleware"
+	"github.com/Oxyrus/memories/internal/ingest"
 	"github.com/Oxyrus/memories/internal/storage"
+	"github.com/Oxyrus/memories/internal/tus"
 )
 
-func New(cfg *config.Config, logger *slog.Logger, store storage.Store) *gin.Engine {
+func New(cfg *config.Config, logger *slog.Logger, store storage.Store, blobs blob.Store, pipeline *ingest.Pipeline, staging *tus.Store) *gin.Engine {
 	r := gin.New()
 
 	r.Use(gin.Recovery())
 	r.Use(middleware.Logging(logger))
-	r.Static("/uploads", cfg.UploadsDir)
 
-	albumHandler := handlers.NewAlbumHandler(logger, store.Albums(), store.Photos(), cfg.UploadsDir)
-	authHandler := handlers.NewAuthHandler(logger, cfg.AdminPassword, cfg.AdminCookie)
+	fileHandler := handlers.NewFileHandler(logger, blobs)
+	albumHandler := handlers.NewAlbumHandler(logger, store.Albums(), store.Photos(), store.WatchFailures(), blobs, pipeline)
+	uploadHandler := handlers.NewResumableUploadHandler(logger, albumHandler, staging)
+	authHandler := handlers.NewAuthHandler(logger, store.Sessions(), cfg.AdminPassword, cfg.AdminCookie, cfg.SessionTTL)
 
 	protected := r.Group("/")
-	protected.Use(middleware.RequireAdmin(cfg.AdminCookie))
+	protected.Use(middleware.RequireAdmin(logger, store.Sessions(), cfg.AdminCookie, cfg.SessionTTL))
 	protected.GET("/albums", albumHandler.List)
 	protected.GET("/albums/new", albumHandler.New)
 	protected.POST("/albums", albumHandler.Create)
 	protected.GET("/albums/:slug/edit", albumHandler.Edit)
 	protected.POST("/albums/:slug/edit", albumHandler.Update)
+	protected.GET("/albums/:slug/similar", albumHandler.Similar)
+	protected.GET("/albums/:slug/download", albumHandler.Download)
+	protected.HEAD("/albums/:slug/download", albumHandler.Download)
+	protected.GET("/albums/:slug/delete", albumHandler.ConfirmDelete)
+	protected.POST("/albums/:slug/delete", albumHandler.Delete)
 	protected.POST("/albums/:slug/photos", albumHandler.UploadPhoto)
+	protected.POST("/albums/:slug/photos/order", albumHandler.ReorderPhotos)
+	protected.POST("/albums/:slug/import", albumHandler.ImportArchive)
+	protected.OPTIONS("/albums/:slug/uploads", uploadHandler.Options)
+	protected.POST("/albums/:slug/uploads", uploadHandler.Create)
+	protected.HEAD("/albums/:slug/uploads/:uploadID", uploadHandler.Head)
+	protected.PATCH("/albums/:slug/uploads/:uploadID", uploadHandler.Patch)
+	protected.DELETE("/albums/:slug/uploads/:uploadID", uploadHandler.Terminate)
+	protected.POST("/albums/:slug/photos/:photoID/edit", albumHandler.UpdatePhoto)
+	protected.POST("/albums/:slug/photos/:photoID/delete", albumHandler.DeletePhoto)
+	protected.POST("/albums/:slug/photos/:photoID/cover", albumHandler.SetCover)
 	protected.GET("/albums/:slug", albumHandler.View)
+	protected.POST("/watch/failures/:failureID/dismiss", albumHandler.DismissWatchFailure)
 
+	r.GET("/uploads/*filepath", fileHandler.Serve)
+	r.HEAD("/uploads/*filepath", fileHandler.Serve)
 	r.GET("/a/:slug", albumHandler.Public)
+	r.GET("/a/:slug/download", albumHandler.PublicDownload)
+	r.HEAD("/a/:slug/download", albumHandler.PublicDownload)
 	r.GET("/login", authHandler.ShowLogin)
 	r.POST("/login", authHandler.SubmitLogin)
+	r.POST("/logout", authHandler.Logout)
 
 	r.NoRoute(func(c *gin.Context) {
 		c.String(http.StatusNotFound, "not found")
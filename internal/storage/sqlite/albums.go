@@ -46,7 +46,7 @@ func (r *albumRepository) Create(ctx context.Context, input storage.AlbumCreate)
 
 func (r *albumRepository) GetByID(ctx context.Context, id int64) (storage.Album, error) {
 	row := r.db.QueryRowContext(ctx, `
-		SELECT id, slug, title, description, cover_photo_id, created_at, updated_at
+		SELECT id, slug, title, description, cover_photo_id, sort_mode, show_photo_info, allow_download, created_at, updated_at
 		FROM albums
 		WHERE id = ?`,
 		id,
@@ -56,7 +56,7 @@ func (r *albumRepository) GetByID(ctx context.Context, id int64) (storage.Album,
 
 func (r *albumRepository) GetBySlug(ctx context.Context, slug string) (storage.Album, error) {
 	row := r.db.QueryRowContext(ctx, `
-		SELECT id, slug, title, description, cover_photo_id, created_at, updated_at
+		SELECT id, slug, title, description, cover_photo_id, sort_mode, show_photo_info, allow_download, created_at, updated_at
 		FROM albums
 		WHERE slug = ?`,
 		slug,
@@ -66,7 +66,7 @@ func (r *albumRepository) GetBySlug(ctx context.Context, slug string) (storage.A
 
 func (r *albumRepository) List(ctx context.Context) ([]storage.Album, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, slug, title, description, cover_photo_id, created_at, updated_at
+		SELECT id, slug, title, description, cover_photo_id, sort_mode, show_photo_info, allow_download, created_at, updated_at
 		FROM albums
 		ORDER BY created_at DESC, id DESC`)
 	if err != nil {
@@ -91,8 +91,8 @@ func (r *albumRepository) List(ctx context.Context) ([]storage.Album, error) {
 }
 
 func (r *albumRepository) Update(ctx context.Context, id int64, input storage.AlbumUpdate) (storage.Album, error) {
-	setClauses := make([]string, 0, 3)
-	args := make([]any, 0, 4)
+	setClauses := make([]string, 0, 6)
+	args := make([]any, 0, 5)
 
 	if input.Title != nil {
 		setClauses = append(setClauses, "title = ?")
@@ -104,6 +104,24 @@ func (r *albumRepository) Update(ctx context.Context, id int64, input storage.Al
 		args = append(args, *input.Description)
 	}
 
+	if input.SortMode != nil {
+		if !input.SortMode.Valid() {
+			return storage.Album{}, fmt.Errorf("sqlite: update album: unknown sort mode %q", *input.SortMode)
+		}
+		setClauses = append(setClauses, "sort_mode = ?")
+		args = append(args, string(*input.SortMode))
+	}
+
+	if input.ShowPhotoInfo != nil {
+		setClauses = append(setClauses, "show_photo_info = ?")
+		args = append(args, *input.ShowPhotoInfo)
+	}
+
+	if input.AllowDownload != nil {
+		setClauses = append(setClauses, "allow_download = ?")
+		args = append(args, *input.AllowDownload)
+	}
+
 	if len(setClauses) == 0 {
 		return r.GetByID(ctx, id)
 	}
@@ -217,7 +235,7 @@ func isUniqueConstraint(err error) bool {
 	var sqliteErr *sqlitedriver.Error
 	if errors.As(err, &sqliteErr) {
 		switch sqliteErr.Code() {
-		case sqlite3.SQLITE_CONSTRAINT, sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+		case sqlite3.SQLITE_CONSTRAINT, sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
 			return true
 		}
 	}
@@ -232,6 +250,7 @@ func scanAlbum(s albumScanner) (storage.Album, error) {
 	var (
 		album        storage.Album
 		coverPhotoID sql.NullInt64
+		sortMode     string
 		createdAtRaw time.Time
 		updatedAtRaw time.Time
 	)
@@ -242,6 +261,9 @@ func scanAlbum(s albumScanner) (storage.Album, error) {
 		&album.Title,
 		&album.Description,
 		&coverPhotoID,
+		&sortMode,
+		&album.ShowPhotoInfo,
+		&album.AllowDownload,
 		&createdAtRaw,
 		&updatedAtRaw,
 	)
@@ -257,6 +279,7 @@ func scanAlbum(s albumScanner) (storage.Album, error) {
 		album.CoverPhotoID = &v
 	}
 
+	album.SortMode = storage.PhotoSort(sortMode)
 	album.CreatedAt = createdAtRaw.UTC()
 	album.UpdatedAt = updatedAtRaw.UTC()
 
@@ -0,0 +1,133 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Oxyrus/memories/internal/storage"
+)
+
+type blobRepository struct {
+	db *sql.DB
+}
+
+func (r *blobRepository) Claim(ctx context.Context, key string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE blobs SET updated_at = ? WHERE key = ?`, time.Now().UTC(), key)
+	if err != nil {
+		return false, fmt.Errorf("sqlite: claim blob: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("sqlite: claim blob: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (r *blobRepository) Register(ctx context.Context, key string, sizeBytes int64) error {
+	now := time.Now().UTC()
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO blobs (key, size_bytes, ref_count, created_at, updated_at)
+		VALUES (?, ?, 0, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET
+			size_bytes = excluded.size_bytes,
+			updated_at = excluded.updated_at`,
+		key,
+		sizeBytes,
+		now,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: register blob: %w", err)
+	}
+	return nil
+}
+
+func (r *blobRepository) Get(ctx context.Context, key string) (storage.Blob, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT key, size_bytes, ref_count, created_at, updated_at
+		FROM blobs
+		WHERE key = ?`,
+		key,
+	)
+	blob, err := scanBlob(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return storage.Blob{}, storage.ErrNotFound
+		}
+		return storage.Blob{}, fmt.Errorf("sqlite: scan blob: %w", err)
+	}
+	return blob, nil
+}
+
+func (r *blobRepository) ListUnreferenced(ctx context.Context, cutoff time.Time) ([]storage.Blob, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT key, size_bytes, ref_count, created_at, updated_at
+		FROM blobs
+		WHERE ref_count <= 0 AND updated_at < ?
+		ORDER BY key`,
+		cutoff.UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: list unreferenced blobs: %w", err)
+	}
+	defer rows.Close()
+
+	var blobs []storage.Blob
+	for rows.Next() {
+		blob, err := scanBlob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("sqlite: scan blob: %w", err)
+		}
+		blobs = append(blobs, blob)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite: iterate blobs: %w", err)
+	}
+
+	return blobs, nil
+}
+
+func (r *blobRepository) Delete(ctx context.Context, key string, cutoff time.Time) error {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM blobs
+		WHERE key = ? AND ref_count <= 0 AND updated_at < ?`,
+		key,
+		cutoff.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: delete blob: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("sqlite: delete blob: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+type blobScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanBlob(s blobScanner) (storage.Blob, error) {
+	var (
+		blob         storage.Blob
+		createdAtRaw time.Time
+		updatedAtRaw time.Time
+	)
+	if err := s.Scan(&blob.Key, &blob.SizeBytes, &blob.RefCount, &createdAtRaw, &updatedAtRaw); err != nil {
+		return storage.Blob{}, err
+	}
+	blob.CreatedAt = createdAtRaw.UTC()
+	blob.UpdatedAt = updatedAtRaw.UTC()
+	return blob, nil
+}
@@ -0,0 +1,249 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// ErrSchemaTooNew is returned when the database has migrations applied that
+// this binary does not know about, which usually means an older build is being
+// started against a database upgraded by a newer one.
+var ErrSchemaTooNew = errors.New("sqlite: database schema is newer than this binary")
+
+// Migration is a single embedded schema change.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// MigrationStatus reports whether a migration has been applied. Migrations
+// recorded in the database but unknown to the binary are reported with
+// Known set to false.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Known     bool
+	AppliedAt *time.Time
+}
+
+// Migrations returns the embedded migrations ordered by version.
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: read migrations: %w", err)
+	}
+
+	result := make([]Migration, 0, len(entries))
+	seen := make(map[int]string, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		base := strings.TrimSuffix(entry.Name(), ".sql")
+		prefix, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("sqlite: migration %q must be named <version>_<name>.sql", entry.Name())
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("sqlite: migration %q has an invalid version", entry.Name())
+		}
+		if other, dup := seen[version]; dup {
+			return nil, fmt.Errorf("sqlite: migrations %q and %q share version %d", other, entry.Name(), version)
+		}
+		seen[version] = entry.Name()
+
+		body, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("sqlite: read migration %q: %w", entry.Name(), err)
+		}
+
+		result = append(result, Migration{Version: version, Name: name, SQL: string(body)})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+
+	return result, nil
+}
+
+// MigrationStatus lists every known or applied migration ordered by version.
+func (s *Store) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	return migrationStatus(ctx, s.db)
+}
+
+// Migrate applies all pending migrations and returns the ones it applied. It
+// refuses to run with ErrSchemaTooNew when the database is ahead of the binary.
+func (s *Store) Migrate(ctx context.Context) ([]Migration, error) {
+	return migrate(ctx, s.db)
+}
+
+func migrate(ctx context.Context, db *sql.DB) ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkSchemaVersion(migrations, applied); err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if err := applyMigration(ctx, db, m); err != nil {
+			return done, err
+		}
+		done = append(done, m)
+	}
+
+	return done, nil
+}
+
+func migrationStatus(ctx context.Context, db *sql.DB) ([]MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]MigrationStatus, 0, len(migrations))
+	known := make(map[int]bool, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = true
+		status := MigrationStatus{Version: m.Version, Name: m.Name, Known: true}
+		if at, ok := applied[m.Version]; ok {
+			appliedAt := at
+			status.AppliedAt = &appliedAt
+		}
+		result = append(result, status)
+	}
+
+	for version, at := range applied {
+		if known[version] {
+			continue
+		}
+		appliedAt := at
+		result = append(result, MigrationStatus{Version: version, AppliedAt: &appliedAt})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+
+	return result, nil
+}
+
+func ensureMigrationsTable(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at DATETIME NOT NULL
+		);`)
+	if err != nil {
+		return fmt.Errorf("sqlite: create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+func appliedMigrations(ctx context.Context, db *sql.DB) (map[int]time.Time, error) {
+	if err := ensureMigrationsTable(ctx, db); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: list applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var (
+			version   int
+			appliedAt time.Time
+		)
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("sqlite: scan applied migration: %w", err)
+		}
+		applied[version] = appliedAt.UTC()
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite: list applied migrations: %w", err)
+	}
+
+	return applied, nil
+}
+
+func checkSchemaVersion(migrations []Migration, applied map[int]time.Time) error {
+	latest := 0
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].Version
+	}
+
+	for version := range applied {
+		if version > latest {
+			return fmt.Errorf("%w: database is at version %d, binary supports up to %d", ErrSchemaTooNew, version, latest)
+		}
+	}
+
+	return nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, m Migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sqlite: migration %04d_%s: %w", m.Version, m.Name, err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+		return fmt.Errorf("sqlite: migration %04d_%s: %w", m.Version, m.Name, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO schema_migrations (version, name, applied_at)
+		VALUES (?, ?, ?)`,
+		m.Version,
+		m.Name,
+		time.Now().UTC(),
+	); err != nil {
+		return fmt.Errorf("sqlite: record migration %04d_%s: %w", m.Version, m.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("sqlite: migration %04d_%s: %w", m.Version, m.Name, err)
+	}
+
+	return nil
+}
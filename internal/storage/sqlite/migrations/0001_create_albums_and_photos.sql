@@ -0,0 +1,25 @@
+-- Databases created before versioned migrations already contain these tables,
+-- so the initial migration is written to be a no-op for them.
+CREATE TABLE IF NOT EXISTS albums (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	slug TEXT NOT NULL UNIQUE,
+	title TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	cover_photo_id INTEGER,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS photos (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	album_id INTEGER NOT NULL,
+	filename TEXT NOT NULL,
+	caption TEXT NOT NULL DEFAULT '',
+	taken_at DATETIME,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	FOREIGN KEY(album_id) REFERENCES albums(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_photos_album_id ON photos(album_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_photos_album_filename ON photos(album_id, filename);
@@ -0,0 +1,15 @@
+-- albums.cover_photo_id predates migrations and has no foreign key, which
+-- SQLite cannot add without rebuilding the table. A trigger keeps it from
+-- pointing at deleted photos instead, including photos removed by cascade.
+UPDATE albums
+SET cover_photo_id = NULL
+WHERE cover_photo_id IS NOT NULL
+	AND cover_photo_id NOT IN (SELECT id FROM photos);
+
+CREATE TRIGGER IF NOT EXISTS trg_photos_clear_cover
+AFTER DELETE ON photos
+BEGIN
+	UPDATE albums
+	SET cover_photo_id = NULL
+	WHERE id = OLD.album_id AND cover_photo_id = OLD.id;
+END;
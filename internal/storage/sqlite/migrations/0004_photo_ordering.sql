@@ -0,0 +1,17 @@
+ALTER TABLE photos ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE photos ADD COLUMN original_filename TEXT NOT NULL DEFAULT '';
+ALTER TABLE albums ADD COLUMN sort_mode TEXT NOT NULL DEFAULT 'taken';
+
+-- Seed manual positions from the order albums were displayed in so far.
+UPDATE photos
+SET position = ranked.pos
+FROM (
+	SELECT id, ROW_NUMBER() OVER (
+		PARTITION BY album_id
+		ORDER BY taken_at IS NULL, taken_at, created_at, id
+	) AS pos
+	FROM photos
+) AS ranked
+WHERE photos.id = ranked.id;
+
+CREATE INDEX IF NOT EXISTS idx_photos_album_position ON photos(album_id, position);
@@ -0,0 +1,2 @@
+-- JSON array of resized derivatives written next to the original file.
+ALTER TABLE photos ADD COLUMN variants TEXT NOT NULL DEFAULT '[]';
@@ -0,0 +1,5 @@
+-- Record whether taken_at was read from the camera's EXIF data or typed in
+-- by an admin. Dates recorded before this migration were all entered by hand.
+ALTER TABLE photos ADD COLUMN taken_at_source TEXT NOT NULL DEFAULT '';
+
+UPDATE photos SET taken_at_source = 'manual' WHERE taken_at IS NOT NULL;
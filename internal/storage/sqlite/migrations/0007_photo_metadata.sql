@@ -0,0 +1,15 @@
+-- Camera details read from EXIF at upload, before the published file is
+-- stripped. Kept out of the photos table so the public file and the private
+-- record stay clearly separate.
+CREATE TABLE IF NOT EXISTS photo_metadata (
+	photo_id INTEGER PRIMARY KEY REFERENCES photos(id) ON DELETE CASCADE,
+	camera_make TEXT NOT NULL DEFAULT '',
+	camera_model TEXT NOT NULL DEFAULT '',
+	lens TEXT NOT NULL DEFAULT '',
+	focal_length REAL,
+	aperture REAL,
+	exposure_time TEXT NOT NULL DEFAULT '',
+	iso INTEGER
+);
+
+ALTER TABLE albums ADD COLUMN show_photo_info INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,8 @@
+-- Properties of the published file, measured after sanitisation. Rows created
+-- before this migration keep the defaults until `memories photos backfill`
+-- fills them in; an empty sha256 marks a row that still needs it.
+ALTER TABLE photos ADD COLUMN width INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE photos ADD COLUMN height INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE photos ADD COLUMN size_bytes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE photos ADD COLUMN mime_type TEXT NOT NULL DEFAULT '';
+ALTER TABLE photos ADD COLUMN sha256 TEXT NOT NULL DEFAULT '';
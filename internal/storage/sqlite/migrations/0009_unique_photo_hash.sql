@@ -0,0 +1,18 @@
+-- A photo's content may appear only once per album unless it was uploaded
+-- with allow_duplicate set. Photos without a recorded hash are not checked.
+ALTER TABLE photos ADD COLUMN allow_duplicate INTEGER NOT NULL DEFAULT 0;
+
+-- Keep the earliest copy of any duplicates that already exist and mark the
+-- rest as intentional so the index can be built.
+UPDATE photos
+SET allow_duplicate = 1
+WHERE sha256 <> ''
+	AND EXISTS (
+		SELECT 1 FROM photos earlier
+		WHERE earlier.album_id = photos.album_id
+			AND earlier.sha256 = photos.sha256
+			AND earlier.id < photos.id
+	);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_photos_album_sha256 ON photos(album_id, sha256)
+	WHERE sha256 <> '' AND allow_duplicate = 0;
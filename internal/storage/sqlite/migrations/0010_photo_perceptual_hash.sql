@@ -0,0 +1,3 @@
+-- 64-bit dHash of the published file as 16 hex digits, used to find visually
+-- similar photos. Empty until measured.
+ALTER TABLE photos ADD COLUMN phash TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Lets public viewers download the whole album as a ZIP. Admins always can.
+ALTER TABLE albums ADD COLUMN allow_download INTEGER NOT NULL DEFAULT 0;
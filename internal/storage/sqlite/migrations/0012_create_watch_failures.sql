@@ -0,0 +1,10 @@
+-- Files in the watch folder that could not be imported, shown to admins
+-- until dismissed.
+CREATE TABLE IF NOT EXISTS watch_failures (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	path TEXT NOT NULL UNIQUE,
+	album_slug TEXT NOT NULL DEFAULT '',
+	error TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
@@ -0,0 +1,37 @@
+-- Content-addressed photo files. ref_count is the number of photos whose
+-- original or variants are stored under key. The triggers keep it current,
+-- including when photos are deleted along with their album; keys of files
+-- stored under an album's slug have no row and are left alone.
+CREATE TABLE IF NOT EXISTS blobs (
+	key TEXT PRIMARY KEY,
+	size_bytes INTEGER NOT NULL,
+	ref_count INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_blobs_unreferenced ON blobs(updated_at) WHERE ref_count = 0;
+
+CREATE TRIGGER IF NOT EXISTS photos_blobs_insert AFTER INSERT ON photos
+BEGIN
+	UPDATE blobs SET ref_count = ref_count + 1
+	WHERE key = NEW.filename
+		OR key IN (SELECT json_extract(value, '$.filename') FROM json_each(NEW.variants));
+END;
+
+CREATE TRIGGER IF NOT EXISTS photos_blobs_delete AFTER DELETE ON photos
+BEGIN
+	UPDATE blobs SET ref_count = ref_count - 1
+	WHERE key = OLD.filename
+		OR key IN (SELECT json_extract(value, '$.filename') FROM json_each(OLD.variants));
+END;
+
+CREATE TRIGGER IF NOT EXISTS photos_blobs_update AFTER UPDATE OF filename, variants ON photos
+BEGIN
+	UPDATE blobs SET ref_count = ref_count - 1
+	WHERE key = OLD.filename
+		OR key IN (SELECT json_extract(value, '$.filename') FROM json_each(OLD.variants));
+	UPDATE blobs SET ref_count = ref_count + 1
+	WHERE key = NEW.filename
+		OR key IN (SELECT json_extract(value, '$.filename') FROM json_each(NEW.variants));
+END;
@@ -0,0 +1,6 @@
+-- Photos of the same content share a content-addressed key, so the filename
+-- is only unique within an album for files stored under the album's slug.
+-- Copies uploaded with allow_duplicate would otherwise be rejected.
+DROP INDEX IF EXISTS idx_photos_album_filename;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_photos_album_filename ON photos(album_id, filename)
+	WHERE filename NOT GLOB '_sha256/*';
@@ -3,7 +3,9 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Oxyrus/memories/internal/storage"
@@ -13,26 +15,65 @@ type photoRepository struct {
 	db *sql.DB
 }
 
+// photoSelect reads the columns expected by scanPhoto, joining the private
+// camera metadata when it exists. Callers append WHERE and ORDER BY clauses.
+const photoSelect = `
+	SELECT p.id, p.album_id, p.filename, p.original_filename, p.caption, p.taken_at, p.taken_at_source,
+		p.position, p.variants, p.width, p.height, p.size_bytes, p.mime_type, p.sha256, p.phash, p.allow_duplicate, p.created_at, p.updated_at,
+		m.photo_id, m.camera_make, m.camera_model, m.lens, m.focal_length, m.aperture, m.exposure_time, m.iso
+	FROM photos p
+	LEFT JOIN photo_metadata m ON m.photo_id = p.id`
+
 func (r *photoRepository) Create(ctx context.Context, input storage.PhotoCreate) (storage.Photo, error) {
 	now := time.Now().UTC()
 
 	var takenAt sql.NullTime
+	takenAtSource := storage.TakenAtSourceNone
 	if input.TakenAt != nil {
 		utc := input.TakenAt.UTC()
 		takenAt = sql.NullTime{Time: utc, Valid: true}
+		takenAtSource = input.TakenAtSource
+	}
+
+	variants, err := encodeVariants(input.Variants)
+	if err != nil {
+		return storage.Photo{}, fmt.Errorf("sqlite: create photo: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return storage.Photo{}, fmt.Errorf("sqlite: create photo: %w", err)
 	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
 
-	res, err := r.db.ExecContext(ctx, `
-		INSERT INTO photos (album_id, filename, caption, taken_at, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?)`,
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO photos (album_id, filename, original_filename, caption, taken_at, taken_at_source, position, variants,
+			width, height, size_bytes, mime_type, sha256, phash, allow_duplicate, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, (SELECT COALESCE(MAX(position), 0) + 1 FROM photos WHERE album_id = ?), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		input.AlbumID,
 		input.Filename,
+		input.OriginalFilename,
 		input.Caption,
 		takenAt,
+		string(takenAtSource),
+		input.AlbumID,
+		variants,
+		input.Width,
+		input.Height,
+		input.SizeBytes,
+		input.MimeType,
+		input.SHA256,
+		input.PerceptualHash,
+		input.AllowDuplicate,
 		now,
 		now,
 	)
 	if err != nil {
+		if isUniqueConstraint(err) {
+			return storage.Photo{}, storage.ErrConflict
+		}
 		return storage.Photo{}, fmt.Errorf("sqlite: create photo: %w", err)
 	}
 
@@ -41,25 +82,81 @@ func (r *photoRepository) Create(ctx context.Context, input storage.PhotoCreate)
 		return storage.Photo{}, fmt.Errorf("sqlite: create photo: %w", err)
 	}
 
+	if input.Metadata != nil {
+		if err := insertPhotoMetadata(ctx, tx, id, *input.Metadata); err != nil {
+			return storage.Photo{}, fmt.Errorf("sqlite: create photo: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return storage.Photo{}, fmt.Errorf("sqlite: create photo: %w", err)
+	}
+
 	return r.GetByID(ctx, id)
 }
 
+func insertPhotoMetadata(ctx context.Context, tx *sql.Tx, photoID int64, meta storage.PhotoMetadata) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO photo_metadata (photo_id, camera_make, camera_model, lens, focal_length, aperture, exposure_time, iso)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		photoID,
+		meta.CameraMake,
+		meta.CameraModel,
+		meta.Lens,
+		sql.NullFloat64{Float64: meta.FocalLength, Valid: meta.FocalLength > 0},
+		sql.NullFloat64{Float64: meta.Aperture, Valid: meta.Aperture > 0},
+		meta.ExposureTime,
+		sql.NullInt64{Int64: int64(meta.ISO), Valid: meta.ISO > 0},
+	)
+	return err
+}
+
 func (r *photoRepository) GetByID(ctx context.Context, id int64) (storage.Photo, error) {
-	row := r.db.QueryRowContext(ctx, `
-		SELECT id, album_id, filename, caption, taken_at, created_at, updated_at
-		FROM photos
-		WHERE id = ?`,
+	row := r.db.QueryRowContext(ctx, photoSelect+`
+		WHERE p.id = ?`,
 		id,
 	)
 	return scanPhoto(row)
 }
 
+func (r *photoRepository) FindByHash(ctx context.Context, albumID int64, sha256 string) (storage.Photo, error) {
+	if sha256 == "" {
+		return storage.Photo{}, storage.ErrNotFound
+	}
+	row := r.db.QueryRowContext(ctx, photoSelect+`
+		WHERE p.album_id = ? AND p.sha256 = ?
+		ORDER BY p.id
+		LIMIT 1`,
+		albumID,
+		sha256,
+	)
+	return scanPhoto(row)
+}
+
+// photoOrderClauses maps each album sort mode to its ORDER BY clause. The
+// trailing id keeps the order stable when the primary keys tie.
+var photoOrderClauses = map[storage.PhotoSort]string{
+	storage.PhotoSortManual:   "p.position, p.id",
+	storage.PhotoSortTakenAt:  "p.taken_at IS NULL, p.taken_at, p.created_at, p.id",
+	storage.PhotoSortUploaded: "p.created_at, p.id",
+	storage.PhotoSortFilename: "COALESCE(NULLIF(p.original_filename, ''), p.filename) COLLATE NOCASE, p.id",
+}
+
 func (r *photoRepository) ListByAlbum(ctx context.Context, albumID int64) ([]storage.Photo, error) {
-	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, album_id, filename, caption, taken_at, created_at, updated_at
-		FROM photos
-		WHERE album_id = ?
-		ORDER BY taken_at IS NULL, taken_at, created_at, id`,
+	var sortMode string
+	err := r.db.QueryRowContext(ctx, `SELECT sort_mode FROM albums WHERE id = ?`, albumID).Scan(&sortMode)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("sqlite: list photos: %w", err)
+	}
+
+	orderBy, ok := photoOrderClauses[storage.PhotoSort(sortMode)]
+	if !ok {
+		orderBy = photoOrderClauses[storage.PhotoSortTakenAt]
+	}
+
+	rows, err := r.db.QueryContext(ctx, photoSelect+`
+		WHERE p.album_id = ?
+		ORDER BY `+orderBy,
 		albumID,
 	)
 	if err != nil {
@@ -83,6 +180,212 @@ func (r *photoRepository) ListByAlbum(ctx context.Context, albumID int64) ([]sto
 	return result, nil
 }
 
+// coverSelect selects the cover of every album with photos. Each album's
+// photos are ranked in its sort mode, one window per mode, falling back to
+// capture date for unknown modes as ListByAlbum does.
+var coverSelect = func() string {
+	modes := []storage.PhotoSort{storage.PhotoSortManual, storage.PhotoSortUploaded, storage.PhotoSortFilename}
+	known := make([]string, 0, len(modes))
+	ranked := make([]string, 0, len(modes)+1)
+	rank := func(orderBy, where string) string {
+		return `SELECT p.id, p.album_id, ROW_NUMBER() OVER (PARTITION BY p.album_id ORDER BY ` + orderBy + `) AS ordinal
+			FROM photos p JOIN albums a ON a.id = p.album_id
+			WHERE ` + where
+	}
+	for _, mode := range modes {
+		known = append(known, "'"+string(mode)+"'")
+		ranked = append(ranked, rank(photoOrderClauses[mode], "a.sort_mode = '"+string(mode)+"'"))
+	}
+	ranked = append(ranked, rank(photoOrderClauses[storage.PhotoSortTakenAt], "a.sort_mode NOT IN ("+strings.Join(known, ", ")+")"))
+
+	return `WITH ranked AS (` + strings.Join(ranked, " UNION ALL ") + `)` + photoSelect + `
+		WHERE p.id IN (
+			SELECT COALESCE(a.cover_photo_id, r.id)
+			FROM albums a JOIN ranked r ON r.album_id = a.id AND r.ordinal = 1
+		)`
+}()
+
+func (r *photoRepository) ListCovers(ctx context.Context) (map[int64]storage.Photo, error) {
+	rows, err := r.db.QueryContext(ctx, coverSelect)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: list covers: %w", err)
+	}
+	defer rows.Close()
+
+	covers := make(map[int64]storage.Photo)
+	for rows.Next() {
+		photo, err := scanPhoto(rows)
+		if err != nil {
+			return nil, err
+		}
+		covers[photo.AlbumID] = photo
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite: list covers: %w", err)
+	}
+
+	return covers, nil
+}
+
+func (r *photoRepository) Update(ctx context.Context, id int64, input storage.PhotoUpdate) (storage.Photo, error) {
+	setClauses := make([]string, 0, 4)
+	args := make([]any, 0, 5)
+
+	if input.Caption != nil {
+		setClauses = append(setClauses, "caption = ?")
+		args = append(args, *input.Caption)
+	}
+
+	switch {
+	case input.ClearTakenAt:
+		setClauses = append(setClauses, "taken_at = NULL", "taken_at_source = ''")
+	case input.TakenAt != nil:
+		setClauses = append(setClauses, "taken_at = ?")
+		args = append(args, input.TakenAt.UTC())
+	}
+
+	if input.TakenAtSource != nil && !input.ClearTakenAt {
+		setClauses = append(setClauses, "taken_at_source = ?")
+		args = append(args, string(*input.TakenAtSource))
+	}
+
+	if input.Variants != nil {
+		variants, err := encodeVariants(*input.Variants)
+		if err != nil {
+			return storage.Photo{}, fmt.Errorf("sqlite: update photo: %w", err)
+		}
+		setClauses = append(setClauses, "variants = ?")
+		args = append(args, variants)
+	}
+
+	if input.Width != nil {
+		setClauses = append(setClauses, "width = ?")
+		args = append(args, *input.Width)
+	}
+
+	if input.Height != nil {
+		setClauses = append(setClauses, "height = ?")
+		args = append(args, *input.Height)
+	}
+
+	if input.SizeBytes != nil {
+		setClauses = append(setClauses, "size_bytes = ?")
+		args = append(args, *input.SizeBytes)
+	}
+
+	if input.MimeType != nil {
+		setClauses = append(setClauses, "mime_type = ?")
+		args = append(args, *input.MimeType)
+	}
+
+	if input.SHA256 != nil {
+		setClauses = append(setClauses, "sha256 = ?")
+		args = append(args, *input.SHA256)
+	}
+
+	if input.PerceptualHash != nil {
+		setClauses = append(setClauses, "phash = ?")
+		args = append(args, *input.PerceptualHash)
+	}
+
+	if input.AllowDuplicate != nil {
+		setClauses = append(setClauses, "allow_duplicate = ?")
+		args = append(args, *input.AllowDuplicate)
+	}
+
+	if len(setClauses) == 0 {
+		return r.GetByID(ctx, id)
+	}
+
+	setClauses = append(setClauses, "updated_at = ?")
+	args = append(args, time.Now().UTC())
+	args = append(args, id)
+
+	query := fmt.Sprintf("UPDATE photos SET %s WHERE id = ?", strings.Join(setClauses, ", "))
+
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		if isUniqueConstraint(err) {
+			return storage.Photo{}, storage.ErrConflict
+		}
+		return storage.Photo{}, fmt.Errorf("sqlite: update photo: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return storage.Photo{}, fmt.Errorf("sqlite: update photo: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.Photo{}, storage.ErrNotFound
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+func (r *photoRepository) Reorder(ctx context.Context, albumID int64, photoIDs []int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sqlite: reorder photos: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM photos WHERE album_id = ?`, albumID)
+	if err != nil {
+		return fmt.Errorf("sqlite: reorder photos: %w", err)
+	}
+	existing := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("sqlite: reorder photos: %w", err)
+		}
+		existing[id] = false
+	}
+	if err := rows.Close(); err != nil {
+		return fmt.Errorf("sqlite: reorder photos: %w", err)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("sqlite: reorder photos: %w", err)
+	}
+
+	if len(photoIDs) != len(existing) {
+		return storage.ErrConflict
+	}
+	for _, id := range photoIDs {
+		seen, ok := existing[id]
+		if !ok || seen {
+			return storage.ErrConflict
+		}
+		existing[id] = true
+	}
+
+	now := time.Now().UTC()
+	for idx, id := range photoIDs {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE photos
+			SET position = ?, updated_at = ?
+			WHERE id = ? AND album_id = ?`,
+			idx+1,
+			now,
+			id,
+			albumID,
+		); err != nil {
+			return fmt.Errorf("sqlite: reorder photos: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("sqlite: reorder photos: %w", err)
+	}
+
+	return nil
+}
+
 func (r *photoRepository) Delete(ctx context.Context, id int64) error {
 	res, err := r.db.ExecContext(ctx, `DELETE FROM photos WHERE id = ?`, id)
 	if err != nil {
@@ -109,18 +412,47 @@ func scanPhoto(s photoScanner) (storage.Photo, error) {
 	var (
 		photo        storage.Photo
 		takenAtRaw   sql.NullTime
+		sourceRaw    string
+		variantsRaw  string
 		createdAtRaw time.Time
 		updatedAtRaw time.Time
+		metaPhotoID  sql.NullInt64
+		cameraMake   sql.NullString
+		cameraModel  sql.NullString
+		lens         sql.NullString
+		focalLength  sql.NullFloat64
+		aperture     sql.NullFloat64
+		exposureTime sql.NullString
+		iso          sql.NullInt64
 	)
 
 	err := s.Scan(
 		&photo.ID,
 		&photo.AlbumID,
 		&photo.Filename,
+		&photo.OriginalFilename,
 		&photo.Caption,
 		&takenAtRaw,
+		&sourceRaw,
+		&photo.Position,
+		&variantsRaw,
+		&photo.Width,
+		&photo.Height,
+		&photo.SizeBytes,
+		&photo.MimeType,
+		&photo.SHA256,
+		&photo.PerceptualHash,
+		&photo.AllowDuplicate,
 		&createdAtRaw,
 		&updatedAtRaw,
+		&metaPhotoID,
+		&cameraMake,
+		&cameraModel,
+		&lens,
+		&focalLength,
+		&aperture,
+		&exposureTime,
+		&iso,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -132,6 +464,25 @@ func scanPhoto(s photoScanner) (storage.Photo, error) {
 	if takenAtRaw.Valid {
 		t := takenAtRaw.Time.UTC()
 		photo.TakenAt = &t
+		photo.TakenAtSource = storage.TakenAtSource(sourceRaw)
+	}
+
+	if variantsRaw != "" {
+		if err := json.Unmarshal([]byte(variantsRaw), &photo.Variants); err != nil {
+			return storage.Photo{}, fmt.Errorf("sqlite: scan photo variants: %w", err)
+		}
+	}
+
+	if metaPhotoID.Valid {
+		photo.Metadata = &storage.PhotoMetadata{
+			CameraMake:   cameraMake.String,
+			CameraModel:  cameraModel.String,
+			Lens:         lens.String,
+			FocalLength:  focalLength.Float64,
+			Aperture:     aperture.Float64,
+			ExposureTime: exposureTime.String,
+			ISO:          int(iso.Int64),
+		}
 	}
 
 	photo.CreatedAt = createdAtRaw.UTC()
@@ -139,3 +490,14 @@ func scanPhoto(s photoScanner) (storage.Photo, error) {
 
 	return photo, nil
 }
+
+func encodeVariants(variants []storage.PhotoVariant) (string, error) {
+	if len(variants) == 0 {
+		return "[]", nil
+	}
+	encoded, err := json.Marshal(variants)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
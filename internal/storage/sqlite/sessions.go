@@ -0,0 +1,120 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Oxyrus/memories/internal/storage"
+)
+
+type sessionRepository struct {
+	db *sql.DB
+}
+
+func (r *sessionRepository) Create(ctx context.Context, input storage.SessionCreate) (storage.Session, error) {
+	now := time.Now().UTC()
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO sessions (id, created_at, last_seen_at, expires_at)
+		VALUES (?, ?, ?, ?)`,
+		input.ID,
+		now,
+		now,
+		input.ExpiresAt.UTC(),
+	)
+	if err != nil {
+		if isUniqueConstraint(err) {
+			return storage.Session{}, storage.ErrConflict
+		}
+		return storage.Session{}, fmt.Errorf("sqlite: create session: %w", err)
+	}
+
+	return r.Get(ctx, input.ID)
+}
+
+func (r *sessionRepository) Get(ctx context.Context, id string) (storage.Session, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT id, created_at, last_seen_at, expires_at
+		FROM sessions
+		WHERE id = ?`,
+		id,
+	)
+
+	var (
+		session       storage.Session
+		createdAtRaw  time.Time
+		lastSeenAtRaw time.Time
+		expiresAtRaw  time.Time
+	)
+	err := row.Scan(&session.ID, &createdAtRaw, &lastSeenAtRaw, &expiresAtRaw)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return storage.Session{}, storage.ErrNotFound
+		}
+		return storage.Session{}, fmt.Errorf("sqlite: scan session: %w", err)
+	}
+
+	session.CreatedAt = createdAtRaw.UTC()
+	session.LastSeenAt = lastSeenAtRaw.UTC()
+	session.ExpiresAt = expiresAtRaw.UTC()
+
+	return session, nil
+}
+
+func (r *sessionRepository) Touch(ctx context.Context, id string, expiresAt time.Time) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE sessions
+		SET last_seen_at = ?, expires_at = ?
+		WHERE id = ?`,
+		time.Now().UTC(),
+		expiresAt.UTC(),
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: touch session: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("sqlite: touch session: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *sessionRepository) Delete(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("sqlite: delete session: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("sqlite: delete session: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *sessionRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at <= ?`, now.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("sqlite: delete expired sessions: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("sqlite: delete expired sessions: %w", err)
+	}
+
+	return rowsAffected, nil
+}
@@ -14,15 +14,30 @@ import (
 
 // Store is a SQLite-backed implementation of the storage.Store interface.
 type Store struct {
-	db     *sql.DB
-	albums *albumRepository
-	photos *photoRepository
+	db       *sql.DB
+	albums   *albumRepository
+	photos   *photoRepository
+	sessions *sessionRepository
+	watch    *watchFailureRepository
+	blobs    *blobRepository
 }
 
 // Open initialises (or opens) a SQLite database located at the provided path.
-// The directory is created if it does not already exist. The returned Store is
-// safe for concurrent use.
+// The directory is created if it does not already exist and any pending schema
+// migrations are applied. Open fails with ErrSchemaTooNew when the database was
+// migrated by a newer binary. The returned Store is safe for concurrent use.
 func Open(path string) (*Store, error) {
+	return open(path, true)
+}
+
+// OpenUnmigrated opens the database like Open but leaves pending migrations
+// untouched, so callers can inspect MigrationStatus before calling Migrate.
+// Repositories may fail against an unmigrated schema.
+func OpenUnmigrated(path string) (*Store, error) {
+	return open(path, false)
+}
+
+func open(path string, runMigrations bool) (*Store, error) {
 	if path == "" {
 		return nil, fmt.Errorf("sqlite: path must not be empty")
 	}
@@ -45,15 +60,20 @@ func Open(path string) (*Store, error) {
 		return nil, err
 	}
 
-	if err := bootstrap(db); err != nil {
-		_ = db.Close()
-		return nil, err
+	if runMigrations {
+		if _, err := migrate(context.Background(), db); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
 	}
 
 	return &Store{
-		db:     db,
-		albums: &albumRepository{db: db},
-		photos: &photoRepository{db: db},
+		db:       db,
+		albums:   &albumRepository{db: db},
+		photos:   &photoRepository{db: db},
+		sessions: &sessionRepository{db: db},
+		watch:    &watchFailureRepository{db: db},
+		blobs:    &blobRepository{db: db},
 	}, nil
 }
 
@@ -67,6 +87,21 @@ func (s *Store) Photos() storage.Photos {
 	return s.photos
 }
 
+// Sessions returns the session repository.
+func (s *Store) Sessions() storage.Sessions {
+	return s.sessions
+}
+
+// WatchFailures returns the watch folder failure repository.
+func (s *Store) WatchFailures() storage.WatchFailures {
+	return s.watch
+}
+
+// Blobs returns the content-addressed file repository.
+func (s *Store) Blobs() storage.Blobs {
+	return s.blobs
+}
+
 // Ping verifies the database connection is still alive.
 func (s *Store) Ping(ctx context.Context) error {
 	return s.db.PingContext(ctx)
@@ -101,38 +136,4 @@ func configure(db *sql.DB) error {
 	return nil
 }
 
-func bootstrap(db *sql.DB) error {
-	stmts := []string{
-		`CREATE TABLE IF NOT EXISTS albums (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			slug TEXT NOT NULL UNIQUE,
-			title TEXT NOT NULL,
-			description TEXT NOT NULL DEFAULT '',
-			cover_photo_id INTEGER,
-			created_at DATETIME NOT NULL,
-			updated_at DATETIME NOT NULL
-		);`,
-		`CREATE TABLE IF NOT EXISTS photos (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			album_id INTEGER NOT NULL,
-			filename TEXT NOT NULL,
-			caption TEXT NOT NULL DEFAULT '',
-			taken_at DATETIME,
-			created_at DATETIME NOT NULL,
-			updated_at DATETIME NOT NULL,
-			FOREIGN KEY(album_id) REFERENCES albums(id) ON DELETE CASCADE
-		);`,
-		`CREATE INDEX IF NOT EXISTS idx_photos_album_id ON photos(album_id);`,
-		`CREATE UNIQUE INDEX IF NOT EXISTS idx_photos_album_filename ON photos(album_id, filename);`,
-	}
-
-	for _, stmt := range stmts {
-		if _, err := db.Exec(stmt); err != nil {
-			return fmt.Errorf("sqlite: bootstrap: %w", err)
-		}
-	}
-
-	return nil
-}
-
 var _ storage.Store = (*Store)(nil)
@@ -2,6 +2,7 @@ package sqlite_test
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"path/filepath"
 	"testing"
@@ -34,6 +35,69 @@ func TestOpenCreatesSchema(t *testing.T) {
 	}
 }
 
+func TestOpenAppliesMigrations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memories.db")
+	ctx := context.Background()
+
+	store, err := sqlite.Open(path)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+
+	statuses, err := store.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("MigrationStatus returned error: %v", err)
+	}
+	if len(statuses) == 0 {
+		t.Fatalf("expected embedded migrations to be reported")
+	}
+	for _, st := range statuses {
+		if st.AppliedAt == nil {
+			t.Fatalf("expected migration %04d_%s to be applied", st.Version, st.Name)
+		}
+	}
+	closeStore(t, store)
+
+	reopened, err := sqlite.Open(path)
+	if err != nil {
+		t.Fatalf("reopen returned error: %v", err)
+	}
+	defer closeStore(t, reopened)
+
+	applied, err := reopened.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("Migrate returned error: %v", err)
+	}
+	if len(applied) != 0 {
+		t.Fatalf("expected no pending migrations, applied %d", len(applied))
+	}
+}
+
+func TestOpenRejectsNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memories.db")
+
+	store, err := sqlite.Open(path)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	closeStore(t, store)
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("sql.Open returned error: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (9999, 'from_the_future', ?)`, time.Now().UTC()); err != nil {
+		t.Fatalf("insert future migration: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("close raw db: %v", err)
+	}
+
+	if _, err := sqlite.Open(path); !errors.Is(err, sqlite.ErrSchemaTooNew) {
+		t.Fatalf("expected ErrSchemaTooNew, got %v", err)
+	}
+}
+
 func TestAlbumLifecycle(t *testing.T) {
 	store := newStore(t)
 	defer closeStore(t, store)
@@ -79,9 +143,20 @@ func TestAlbumLifecycle(t *testing.T) {
 		t.Fatalf("expected 1 album, got %d", len(items))
 	}
 
+	if created.ShowPhotoInfo {
+		t.Fatalf("expected photo info to be hidden by default")
+	}
+	if created.AllowDownload {
+		t.Fatalf("expected public downloads to be off by default")
+	}
+
 	newTitle := "Summer Adventure"
+	showPhotoInfo := true
+	allowDownload := true
 	updated, err := store.Albums().Update(ctx, created.ID, storage.AlbumUpdate{
-		Title: &newTitle,
+		Title:         &newTitle,
+		ShowPhotoInfo: &showPhotoInfo,
+		AllowDownload: &allowDownload,
 	})
 	if err != nil {
 		t.Fatalf("Update returned error: %v", err)
@@ -89,6 +164,12 @@ func TestAlbumLifecycle(t *testing.T) {
 	if updated.Title != newTitle {
 		t.Fatalf("expected updated title %q, got %q", newTitle, updated.Title)
 	}
+	if !updated.ShowPhotoInfo {
+		t.Fatalf("expected photo info to be shown after update")
+	}
+	if !updated.AllowDownload {
+		t.Fatalf("expected public downloads to be allowed after update")
+	}
 	if !updated.UpdatedAt.After(updated.CreatedAt) {
 		t.Fatalf("expected updated_at to be refreshed")
 	}
@@ -154,10 +235,17 @@ func TestPhotosLifecycle(t *testing.T) {
 	takenAt := time.Date(2024, 12, 24, 21, 15, 0, 0, time.UTC)
 
 	first, err := store.Photos().Create(ctx, storage.PhotoCreate{
-		AlbumID:  album.ID,
-		Filename: "tower.jpg",
-		Caption:  "Observation deck",
-		TakenAt:  &takenAt,
+		AlbumID:        album.ID,
+		Filename:       "tower.jpg",
+		Caption:        "Observation deck",
+		TakenAt:        &takenAt,
+		TakenAtSource:  storage.TakenAtSourceCamera,
+		Width:          4032,
+		Height:         3024,
+		SizeBytes:      2_481_337,
+		MimeType:       "image/jpeg",
+		SHA256:         "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		PerceptualHash: "f0e1d2c3b4a59687",
 	})
 	if err != nil {
 		t.Fatalf("Create photo returned error: %v", err)
@@ -190,6 +278,57 @@ func TestPhotosLifecycle(t *testing.T) {
 	if got.TakenAt == nil || !got.TakenAt.Equal(takenAt) {
 		t.Fatalf("expected TakenAt %v, got %v", takenAt, got.TakenAt)
 	}
+	if got.TakenAtSource != storage.TakenAtSourceCamera {
+		t.Fatalf("expected TakenAtSource camera, got %q", got.TakenAtSource)
+	}
+	if got.Width != 4032 || got.Height != 3024 || got.SizeBytes != 2_481_337 || got.MimeType != "image/jpeg" {
+		t.Fatalf("expected file details to round-trip, got %dx%d %d bytes %q", got.Width, got.Height, got.SizeBytes, got.MimeType)
+	}
+	if got.SHA256 != "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08" {
+		t.Fatalf("expected SHA256 to round-trip, got %q", got.SHA256)
+	}
+	if got.PerceptualHash != "f0e1d2c3b4a59687" {
+		t.Fatalf("expected PerceptualHash to round-trip, got %q", got.PerceptualHash)
+	}
+	if photos[1].SHA256 != "" || photos[1].Width != 0 {
+		t.Fatalf("expected unmeasured photo to have empty file details, got %+v", photos[1])
+	}
+
+	width, height, size := 800, 600, int64(91_204)
+	mimeType, sum, phash := "image/png", "60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752", "0011223344556677"
+	measured, err := store.Photos().Update(ctx, second.ID, storage.PhotoUpdate{
+		Width:          &width,
+		Height:         &height,
+		SizeBytes:      &size,
+		MimeType:       &mimeType,
+		SHA256:         &sum,
+		PerceptualHash: &phash,
+	})
+	if err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	if measured.Width != width || measured.Height != height || measured.SizeBytes != size || measured.MimeType != mimeType || measured.SHA256 != sum || measured.PerceptualHash != phash {
+		t.Fatalf("expected file details to be updated, got %+v", measured)
+	}
+
+	newCaption := "Observation deck at dusk"
+	updated, err := store.Photos().Update(ctx, first.ID, storage.PhotoUpdate{
+		Caption:      &newCaption,
+		ClearTakenAt: true,
+	})
+	if err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	if updated.Caption != newCaption {
+		t.Fatalf("expected caption %q, got %q", newCaption, updated.Caption)
+	}
+	if updated.TakenAt != nil || updated.TakenAtSource != storage.TakenAtSourceNone {
+		t.Fatalf("expected TakenAt and its source to be cleared, got %v (%q)", updated.TakenAt, updated.TakenAtSource)
+	}
+
+	if _, err := store.Photos().Update(ctx, 9999, storage.PhotoUpdate{Caption: &newCaption}); err != storage.ErrNotFound {
+		t.Fatalf("expected ErrNotFound updating missing photo, got %v", err)
+	}
 
 	if err := store.Photos().Delete(ctx, first.ID); err != nil {
 		t.Fatalf("Delete returned error: %v", err)
@@ -200,6 +339,308 @@ func TestPhotosLifecycle(t *testing.T) {
 	}
 }
 
+func TestPhotoMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memories.db")
+	store, err := sqlite.Open(path)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer closeStore(t, store)
+	ctx := context.Background()
+
+	album, err := store.Albums().Create(ctx, storage.AlbumCreate{
+		Slug:  "mountains",
+		Title: "Mountains",
+	})
+	if err != nil {
+		t.Fatalf("Create album returned error: %v", err)
+	}
+
+	meta := storage.PhotoMetadata{
+		CameraMake:   "Fujifilm",
+		CameraModel:  "X-T4",
+		Lens:         "XF35mmF1.4 R",
+		FocalLength:  35,
+		Aperture:     2.8,
+		ExposureTime: "1/250",
+		ISO:          400,
+	}
+	withMeta, err := store.Photos().Create(ctx, storage.PhotoCreate{
+		AlbumID:  album.ID,
+		Filename: "peak.jpg",
+		Metadata: &meta,
+	})
+	if err != nil {
+		t.Fatalf("Create photo returned error: %v", err)
+	}
+	if withMeta.Metadata == nil || *withMeta.Metadata != meta {
+		t.Fatalf("expected metadata %+v, got %+v", meta, withMeta.Metadata)
+	}
+
+	partial := storage.PhotoMetadata{CameraModel: "Pixel 8"}
+	if _, err := store.Photos().Create(ctx, storage.PhotoCreate{
+		AlbumID:  album.ID,
+		Filename: "trail.jpg",
+		Metadata: &partial,
+	}); err != nil {
+		t.Fatalf("Create photo returned error: %v", err)
+	}
+
+	if _, err := store.Photos().Create(ctx, storage.PhotoCreate{
+		AlbumID:  album.ID,
+		Filename: "hut.jpg",
+	}); err != nil {
+		t.Fatalf("Create photo returned error: %v", err)
+	}
+
+	photos, err := store.Photos().ListByAlbum(ctx, album.ID)
+	if err != nil {
+		t.Fatalf("ListByAlbum returned error: %v", err)
+	}
+	if len(photos) != 3 {
+		t.Fatalf("expected 3 photos, got %d", len(photos))
+	}
+	if photos[0].Metadata == nil || *photos[0].Metadata != meta {
+		t.Fatalf("expected listed metadata %+v, got %+v", meta, photos[0].Metadata)
+	}
+	if photos[1].Metadata == nil || *photos[1].Metadata != partial {
+		t.Fatalf("expected partial metadata %+v, got %+v", partial, photos[1].Metadata)
+	}
+	if photos[2].Metadata != nil {
+		t.Fatalf("expected no metadata for photo without camera details, got %+v", photos[2].Metadata)
+	}
+
+	if err := store.Photos().Delete(ctx, withMeta.ID); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("sql.Open returned error: %v", err)
+	}
+	defer db.Close()
+
+	var remaining int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM photo_metadata WHERE photo_id = ?`, withMeta.ID).Scan(&remaining); err != nil {
+		t.Fatalf("count metadata: %v", err)
+	}
+	if remaining != 0 {
+		t.Fatalf("expected metadata to be deleted with its photo, found %d rows", remaining)
+	}
+}
+
+func TestPhotosRejectDuplicateContent(t *testing.T) {
+	store := newStore(t)
+	defer closeStore(t, store)
+	ctx := context.Background()
+
+	var albumIDs []int64
+	for _, slug := range []string{"harbour", "lighthouse"} {
+		album, err := store.Albums().Create(ctx, storage.AlbumCreate{Slug: slug, Title: slug})
+		if err != nil {
+			t.Fatalf("Create album returned error: %v", err)
+		}
+		albumIDs = append(albumIDs, album.ID)
+	}
+
+	const hash = "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+	original, err := store.Photos().Create(ctx, storage.PhotoCreate{AlbumID: albumIDs[0], Filename: "a.jpg", SHA256: hash})
+	if err != nil {
+		t.Fatalf("Create photo returned error: %v", err)
+	}
+
+	if _, err := store.Photos().Create(ctx, storage.PhotoCreate{AlbumID: albumIDs[0], Filename: "b.jpg", SHA256: hash}); !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("expected ErrConflict for duplicate content, got %v", err)
+	}
+
+	copied, err := store.Photos().Create(ctx, storage.PhotoCreate{AlbumID: albumIDs[0], Filename: "c.jpg", SHA256: hash, AllowDuplicate: true})
+	if err != nil {
+		t.Fatalf("expected allowed duplicate to be created, got %v", err)
+	}
+	if !copied.AllowDuplicate {
+		t.Fatalf("expected AllowDuplicate to round-trip")
+	}
+
+	if _, err := store.Photos().Create(ctx, storage.PhotoCreate{AlbumID: albumIDs[1], Filename: "a.jpg", SHA256: hash}); err != nil {
+		t.Fatalf("expected same content in another album to be allowed, got %v", err)
+	}
+
+	for _, name := range []string{"d.jpg", "e.jpg"} {
+		if _, err := store.Photos().Create(ctx, storage.PhotoCreate{AlbumID: albumIDs[0], Filename: name}); err != nil {
+			t.Fatalf("expected photos without a hash to be allowed, got %v", err)
+		}
+	}
+
+	found, err := store.Photos().FindByHash(ctx, albumIDs[0], hash)
+	if err != nil {
+		t.Fatalf("FindByHash returned error: %v", err)
+	}
+	if found.ID != original.ID {
+		t.Fatalf("expected FindByHash to return the earliest photo %d, got %d", original.ID, found.ID)
+	}
+	if _, err := store.Photos().FindByHash(ctx, albumIDs[0], ""); err != storage.ErrNotFound {
+		t.Fatalf("expected ErrNotFound for an empty hash, got %v", err)
+	}
+
+	unmeasured, err := store.Photos().Create(ctx, storage.PhotoCreate{AlbumID: albumIDs[0], Filename: "f.jpg"})
+	if err != nil {
+		t.Fatalf("Create photo returned error: %v", err)
+	}
+	sum := hash
+	if _, err := store.Photos().Update(ctx, unmeasured.ID, storage.PhotoUpdate{SHA256: &sum}); !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("expected ErrConflict when recording a duplicate hash, got %v", err)
+	}
+}
+
+func TestPhotosSortModes(t *testing.T) {
+	store := newStore(t)
+	defer closeStore(t, store)
+	ctx := context.Background()
+
+	album, err := store.Albums().Create(ctx, storage.AlbumCreate{
+		Slug:  "road-trip",
+		Title: "Road Trip",
+	})
+	if err != nil {
+		t.Fatalf("create album: %v", err)
+	}
+	if album.SortMode != storage.PhotoSortTakenAt {
+		t.Fatalf("expected default sort mode %q, got %q", storage.PhotoSortTakenAt, album.SortMode)
+	}
+
+	var ids []int64
+	for _, name := range []string{"c.jpg", "a.jpg", "b.jpg"} {
+		photo, err := store.Photos().Create(ctx, storage.PhotoCreate{
+			AlbumID:          album.ID,
+			Filename:         "road-trip/" + name,
+			OriginalFilename: "IMG_" + name,
+		})
+		if err != nil {
+			t.Fatalf("create photo %s: %v", name, err)
+		}
+		ids = append(ids, photo.ID)
+	}
+
+	filenameSort := storage.PhotoSortFilename
+	if _, err := store.Albums().Update(ctx, album.ID, storage.AlbumUpdate{SortMode: &filenameSort}); err != nil {
+		t.Fatalf("Update sort mode returned error: %v", err)
+	}
+	assertPhotoOrder(t, store, album.ID, []int64{ids[1], ids[2], ids[0]})
+
+	if err := store.Photos().Reorder(ctx, album.ID, []int64{ids[2], ids[0]}); err != storage.ErrConflict {
+		t.Fatalf("expected ErrConflict for incomplete order, got %v", err)
+	}
+	if err := store.Photos().Reorder(ctx, album.ID, []int64{ids[2], ids[0], ids[0]}); err != storage.ErrConflict {
+		t.Fatalf("expected ErrConflict for duplicate IDs, got %v", err)
+	}
+	if err := store.Photos().Reorder(ctx, album.ID, []int64{ids[2], ids[0], ids[1]}); err != nil {
+		t.Fatalf("Reorder returned error: %v", err)
+	}
+
+	manualSort := storage.PhotoSortManual
+	if _, err := store.Albums().Update(ctx, album.ID, storage.AlbumUpdate{SortMode: &manualSort}); err != nil {
+		t.Fatalf("Update sort mode returned error: %v", err)
+	}
+	assertPhotoOrder(t, store, album.ID, []int64{ids[2], ids[0], ids[1]})
+
+	appended, err := store.Photos().Create(ctx, storage.PhotoCreate{AlbumID: album.ID, Filename: "road-trip/d.jpg"})
+	if err != nil {
+		t.Fatalf("create photo: %v", err)
+	}
+	assertPhotoOrder(t, store, album.ID, []int64{ids[2], ids[0], ids[1], appended.ID})
+}
+
+func assertPhotoOrder(t *testing.T, store storage.Store, albumID int64, want []int64) {
+	t.Helper()
+
+	photos, err := store.Photos().ListByAlbum(context.Background(), albumID)
+	if err != nil {
+		t.Fatalf("ListByAlbum returned error: %v", err)
+	}
+
+	got := make([]int64, 0, len(photos))
+	for _, photo := range photos {
+		got = append(got, photo.ID)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected order %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected order %v, got %v", want, got)
+		}
+	}
+}
+
+func TestPhotosListCovers(t *testing.T) {
+	store := newStore(t)
+	defer closeStore(t, store)
+	ctx := context.Background()
+
+	createAlbum := func(slug string, sort storage.PhotoSort) storage.Album {
+		t.Helper()
+		album, err := store.Albums().Create(ctx, storage.AlbumCreate{Slug: slug, Title: slug})
+		if err != nil {
+			t.Fatalf("create album %s: %v", slug, err)
+		}
+		if _, err := store.Albums().Update(ctx, album.ID, storage.AlbumUpdate{SortMode: &sort}); err != nil {
+			t.Fatalf("Update sort mode returned error: %v", err)
+		}
+		return album
+	}
+	createPhotos := func(album storage.Album, names ...string) []int64 {
+		t.Helper()
+		var ids []int64
+		for _, name := range names {
+			photo, err := store.Photos().Create(ctx, storage.PhotoCreate{
+				AlbumID:          album.ID,
+				Filename:         album.Slug + "/" + name,
+				OriginalFilename: name,
+			})
+			if err != nil {
+				t.Fatalf("create photo %s: %v", name, err)
+			}
+			ids = append(ids, photo.ID)
+		}
+		return ids
+	}
+
+	byName := createAlbum("by-name", storage.PhotoSortFilename)
+	byNameIDs := createPhotos(byName, "c.jpg", "a.jpg", "b.jpg")
+
+	chosen := createAlbum("chosen", storage.PhotoSortUploaded)
+	chosenIDs := createPhotos(chosen, "a.jpg", "b.jpg")
+	if err := store.Albums().SetCoverPhoto(ctx, chosen.ID, chosenIDs[1]); err != nil {
+		t.Fatalf("SetCoverPhoto returned error: %v", err)
+	}
+
+	manual := createAlbum("manual", storage.PhotoSortManual)
+	manualIDs := createPhotos(manual, "a.jpg", "b.jpg")
+	if err := store.Photos().Reorder(ctx, manual.ID, []int64{manualIDs[1], manualIDs[0]}); err != nil {
+		t.Fatalf("Reorder returned error: %v", err)
+	}
+
+	createAlbum("empty", storage.PhotoSortTakenAt)
+
+	covers, err := store.Photos().ListCovers(ctx)
+	if err != nil {
+		t.Fatalf("ListCovers returned error: %v", err)
+	}
+	want := map[int64]int64{
+		byName.ID: byNameIDs[1],
+		chosen.ID: chosenIDs[1],
+		manual.ID: manualIDs[1],
+	}
+	if len(covers) != len(want) {
+		t.Fatalf("expected covers for %d albums, got %+v", len(want), covers)
+	}
+	for albumID, photoID := range want {
+		if covers[albumID].ID != photoID {
+			t.Fatalf("expected photo %d as the cover of album %d, got %d", photoID, albumID, covers[albumID].ID)
+		}
+	}
+}
+
 func TestSetCoverPhotoValidatesOwnership(t *testing.T) {
 	store := newStore(t)
 	defer closeStore(t, store)
@@ -234,6 +675,254 @@ func TestSetCoverPhotoValidatesOwnership(t *testing.T) {
 	}
 }
 
+func TestDeletingCoverPhotoClearsCover(t *testing.T) {
+	store := newStore(t)
+	defer closeStore(t, store)
+	ctx := context.Background()
+
+	album, err := store.Albums().Create(ctx, storage.AlbumCreate{
+		Slug:  "harbour",
+		Title: "Harbour",
+	})
+	if err != nil {
+		t.Fatalf("create album: %v", err)
+	}
+
+	photo, err := store.Photos().Create(ctx, storage.PhotoCreate{
+		AlbumID:  album.ID,
+		Filename: "harbour/boats.jpg",
+	})
+	if err != nil {
+		t.Fatalf("create photo: %v", err)
+	}
+
+	if err := store.Albums().SetCoverPhoto(ctx, album.ID, photo.ID); err != nil {
+		t.Fatalf("SetCoverPhoto returned error: %v", err)
+	}
+
+	if err := store.Photos().Delete(ctx, photo.ID); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+
+	got, err := store.Albums().GetByID(ctx, album.ID)
+	if err != nil {
+		t.Fatalf("GetByID returned error: %v", err)
+	}
+	if got.CoverPhotoID != nil {
+		t.Fatalf("expected cover photo to be cleared, got %d", *got.CoverPhotoID)
+	}
+}
+
+func TestSessionsLifecycle(t *testing.T) {
+	store := newStore(t)
+	defer closeStore(t, store)
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(time.Hour).UTC()
+	created, err := store.Sessions().Create(ctx, storage.SessionCreate{
+		ID:        "live-session",
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("Create session returned error: %v", err)
+	}
+	if !created.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("expected ExpiresAt %v, got %v", expiresAt, created.ExpiresAt)
+	}
+
+	if _, err := store.Sessions().Create(ctx, storage.SessionCreate{ID: "live-session", ExpiresAt: expiresAt}); !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("expected ErrConflict on duplicate session, got %v", err)
+	}
+
+	extended := expiresAt.Add(24 * time.Hour)
+	if err := store.Sessions().Touch(ctx, created.ID, extended); err != nil {
+		t.Fatalf("Touch returned error: %v", err)
+	}
+
+	got, err := store.Sessions().Get(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Get session returned error: %v", err)
+	}
+	if !got.ExpiresAt.Equal(extended) {
+		t.Fatalf("expected slid ExpiresAt %v, got %v", extended, got.ExpiresAt)
+	}
+
+	if _, err := store.Sessions().Create(ctx, storage.SessionCreate{
+		ID:        "stale-session",
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}); err != nil {
+		t.Fatalf("Create stale session returned error: %v", err)
+	}
+
+	removed, err := store.Sessions().DeleteExpired(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("DeleteExpired returned error: %v", err)
+	}
+	if removed != 1 {
+		t.Fatalf("expected 1 expired session removed, got %d", removed)
+	}
+	if _, err := store.Sessions().Get(ctx, "stale-session"); err != storage.ErrNotFound {
+		t.Fatalf("expected ErrNotFound for pruned session, got %v", err)
+	}
+
+	if err := store.Sessions().Delete(ctx, created.ID); err != nil {
+		t.Fatalf("Delete session returned error: %v", err)
+	}
+	if _, err := store.Sessions().Get(ctx, created.ID); err != storage.ErrNotFound {
+		t.Fatalf("expected ErrNotFound after revoke, got %v", err)
+	}
+	if err := store.Sessions().Touch(ctx, created.ID, extended); err != storage.ErrNotFound {
+		t.Fatalf("expected ErrNotFound touching revoked session, got %v", err)
+	}
+}
+
+func TestWatchFailuresLifecycle(t *testing.T) {
+	store := newStore(t)
+	defer closeStore(t, store)
+	ctx := context.Background()
+
+	first, err := store.WatchFailures().Record(ctx, storage.WatchFailureRecord{
+		Path:      "summer/broken.jpg",
+		AlbumSlug: "summer",
+		Error:     "unreadable",
+	})
+	if err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+	if _, err := store.WatchFailures().Record(ctx, storage.WatchFailureRecord{Path: "stray.jpg", Error: "not in an album folder"}); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+
+	again, err := store.WatchFailures().Record(ctx, storage.WatchFailureRecord{
+		Path:      "summer/broken.jpg",
+		AlbumSlug: "summer",
+		Error:     "still unreadable",
+	})
+	if err != nil {
+		t.Fatalf("Record again returned error: %v", err)
+	}
+	if again.ID != first.ID || again.Error != "still unreadable" {
+		t.Fatalf("expected failure %d to be updated in place, got %+v", first.ID, again)
+	}
+
+	failures, err := store.WatchFailures().List(ctx)
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if len(failures) != 2 || failures[0].Path != "summer/broken.jpg" {
+		t.Fatalf("expected the latest failure first, got %+v", failures)
+	}
+
+	if err := store.WatchFailures().Delete(ctx, first.ID); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if err := store.WatchFailures().Delete(ctx, first.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound deleting twice, got %v", err)
+	}
+	if err := store.WatchFailures().DeleteByPath(ctx, "stray.jpg"); err != nil {
+		t.Fatalf("DeleteByPath returned error: %v", err)
+	}
+
+	failures, err = store.WatchFailures().List(ctx)
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if len(failures) != 0 {
+		t.Fatalf("expected no failures left, got %+v", failures)
+	}
+}
+
+func TestBlobRefCounts(t *testing.T) {
+	store := newStore(t)
+	defer closeStore(t, store)
+	ctx := context.Background()
+
+	const (
+		original = "ab/cd/abcdef.jpg"
+		thumb    = "ab/cd/abcdef_thumb.jpg"
+		medium   = "ab/cd/abcdef_medium.jpg"
+	)
+	for _, key := range []string{original, thumb, medium} {
+		if err := store.Blobs().Register(ctx, key, 10); err != nil {
+			t.Fatalf("Register returned error: %v", err)
+		}
+	}
+	if claimed, err := store.Blobs().Claim(ctx, original); err != nil || !claimed {
+		t.Fatalf("expected a registered blob to be claimed, got %t (%v)", claimed, err)
+	}
+	if claimed, err := store.Blobs().Claim(ctx, "ef/gh/missing.jpg"); err != nil || claimed {
+		t.Fatalf("expected an unknown blob not to be claimed, got %t (%v)", claimed, err)
+	}
+
+	summer, err := store.Albums().Create(ctx, storage.AlbumCreate{Slug: "summer", Title: "Summer"})
+	if err != nil {
+		t.Fatalf("create album: %v", err)
+	}
+	winter, err := store.Albums().Create(ctx, storage.AlbumCreate{Slug: "winter", Title: "Winter"})
+	if err != nil {
+		t.Fatalf("create album: %v", err)
+	}
+	variants := []storage.PhotoVariant{{Name: "thumb", Filename: thumb, Width: 320, Height: 240}}
+	first, err := store.Photos().Create(ctx, storage.PhotoCreate{AlbumID: summer.ID, Filename: original, SHA256: "abcdef", Variants: variants})
+	if err != nil {
+		t.Fatalf("create photo: %v", err)
+	}
+	if _, err := store.Photos().Create(ctx, storage.PhotoCreate{AlbumID: winter.ID, Filename: original, SHA256: "abcdef", Variants: variants}); err != nil {
+		t.Fatalf("create photo: %v", err)
+	}
+	assertRefCounts(t, store, map[string]int{original: 2, thumb: 2, medium: 0})
+
+	withMedium := append(variants, storage.PhotoVariant{Name: "medium", Filename: medium, Width: 1280, Height: 960})
+	if _, err := store.Photos().Update(ctx, first.ID, storage.PhotoUpdate{Variants: &withMedium}); err != nil {
+		t.Fatalf("update photo: %v", err)
+	}
+	assertRefCounts(t, store, map[string]int{original: 2, thumb: 2, medium: 1})
+
+	if err := store.Photos().Delete(ctx, first.ID); err != nil {
+		t.Fatalf("delete photo: %v", err)
+	}
+	assertRefCounts(t, store, map[string]int{original: 1, thumb: 1, medium: 0})
+
+	cutoff := time.Now().Add(time.Minute)
+	if err := store.Blobs().Delete(ctx, original, cutoff); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected a referenced blob to be kept, got %v", err)
+	}
+	if err := store.Albums().Delete(ctx, winter.ID); err != nil {
+		t.Fatalf("delete album: %v", err)
+	}
+	assertRefCounts(t, store, map[string]int{original: 0, thumb: 0, medium: 0})
+
+	if unreferenced, err := store.Blobs().ListUnreferenced(ctx, time.Now().Add(-time.Hour)); err != nil || len(unreferenced) != 0 {
+		t.Fatalf("expected recently claimed blobs to be kept, got %+v (%v)", unreferenced, err)
+	}
+	unreferenced, err := store.Blobs().ListUnreferenced(ctx, cutoff)
+	if err != nil {
+		t.Fatalf("ListUnreferenced returned error: %v", err)
+	}
+	if len(unreferenced) != 3 || unreferenced[0].Key != original {
+		t.Fatalf("expected every blob to be unreferenced, got %+v", unreferenced)
+	}
+	if err := store.Blobs().Delete(ctx, original, cutoff); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if _, err := store.Blobs().Get(ctx, original); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func assertRefCounts(t *testing.T, store storage.Store, want map[string]int) {
+	t.Helper()
+	for key, count := range want {
+		blob, err := store.Blobs().Get(context.Background(), key)
+		if err != nil {
+			t.Fatalf("get blob %s: %v", key, err)
+		}
+		if blob.RefCount != count {
+			t.Fatalf("expected %s to have %d references, got %d", key, count, blob.RefCount)
+		}
+	}
+}
+
 func newStore(t *testing.T) storage.Store {
 	t.Helper()
 
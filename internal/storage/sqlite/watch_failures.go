@@ -0,0 +1,117 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Oxyrus/memories/internal/storage"
+)
+
+type watchFailureRepository struct {
+	db *sql.DB
+}
+
+func (r *watchFailureRepository) Record(ctx context.Context, input storage.WatchFailureRecord) (storage.WatchFailure, error) {
+	now := time.Now().UTC()
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO watch_failures (path, album_slug, error, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(path) DO UPDATE SET
+			album_slug = excluded.album_slug,
+			error = excluded.error,
+			updated_at = excluded.updated_at`,
+		input.Path,
+		input.AlbumSlug,
+		input.Error,
+		now,
+		now,
+	)
+	if err != nil {
+		return storage.WatchFailure{}, fmt.Errorf("sqlite: record watch failure: %w", err)
+	}
+
+	row := r.db.QueryRowContext(ctx, `
+		SELECT id, path, album_slug, error, created_at, updated_at
+		FROM watch_failures
+		WHERE path = ?`,
+		input.Path,
+	)
+	failure, err := scanWatchFailure(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return storage.WatchFailure{}, storage.ErrNotFound
+		}
+		return storage.WatchFailure{}, fmt.Errorf("sqlite: scan watch failure: %w", err)
+	}
+	return failure, nil
+}
+
+func (r *watchFailureRepository) List(ctx context.Context) ([]storage.WatchFailure, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, path, album_slug, error, created_at, updated_at
+		FROM watch_failures
+		ORDER BY updated_at DESC, id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: list watch failures: %w", err)
+	}
+	defer rows.Close()
+
+	var failures []storage.WatchFailure
+	for rows.Next() {
+		failure, err := scanWatchFailure(rows)
+		if err != nil {
+			return nil, fmt.Errorf("sqlite: scan watch failure: %w", err)
+		}
+		failures = append(failures, failure)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite: iterate watch failures: %w", err)
+	}
+
+	return failures, nil
+}
+
+func (r *watchFailureRepository) Delete(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM watch_failures WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("sqlite: delete watch failure: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("sqlite: delete watch failure: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *watchFailureRepository) DeleteByPath(ctx context.Context, path string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM watch_failures WHERE path = ?`, path); err != nil {
+		return fmt.Errorf("sqlite: delete watch failure: %w", err)
+	}
+	return nil
+}
+
+type watchFailureScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWatchFailure(s watchFailureScanner) (storage.WatchFailure, error) {
+	var (
+		failure      storage.WatchFailure
+		createdAtRaw time.Time
+		updatedAtRaw time.Time
+	)
+	if err := s.Scan(&failure.ID, &failure.Path, &failure.AlbumSlug, &failure.Error, &createdAtRaw, &updatedAtRaw); err != nil {
+		return storage.WatchFailure{}, err
+	}
+	failure.CreatedAt = createdAtRaw.UTC()
+	failure.UpdatedAt = updatedAtRaw.UTC()
+	return failure, nil
+}
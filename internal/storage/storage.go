@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"errors"
+	"regexp"
 	"time"
 )
 
@@ -19,19 +20,60 @@ var ErrConflict = errors.New("storage: conflict")
 type Store interface {
 	Albums() Albums
 	Photos() Photos
+	Sessions() Sessions
+	WatchFailures() WatchFailures
+	Blobs() Blobs
 	Ping(ctx context.Context) error
 	Close() error
 }
 
-// Album represents a logical collection of photos.
+// PhotoSort selects the order in which an album's photos are listed.
+type PhotoSort string
+
+const (
+	// PhotoSortManual orders photos by the position set via Photos.Reorder.
+	PhotoSortManual PhotoSort = "manual"
+	// PhotoSortTakenAt orders photos by capture date, undated photos last.
+	PhotoSortTakenAt PhotoSort = "taken"
+	// PhotoSortUploaded orders photos by upload time.
+	PhotoSortUploaded PhotoSort = "uploaded"
+	// PhotoSortFilename orders photos by their original file name.
+	PhotoSortFilename PhotoSort = "filename"
+)
+
+// Valid reports whether s is one of the known sort modes.
+func (s PhotoSort) Valid() bool {
+	switch s {
+	case PhotoSortManual, PhotoSortTakenAt, PhotoSortUploaded, PhotoSortFilename:
+		return true
+	}
+	return false
+}
+
+// Album represents a logical collection of photos. ShowPhotoInfo controls
+// whether the public viewer offers the camera details of each photo;
+// AllowDownload whether it offers the whole album as a ZIP.
 type Album struct {
-	ID           int64
-	Slug         string
-	Title        string
-	Description  string
-	CoverPhotoID *int64
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	ID            int64
+	Slug          string
+	Title         string
+	Description   string
+	CoverPhotoID  *int64
+	SortMode      PhotoSort
+	ShowPhotoInfo bool
+	AllowDownload bool
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+// ValidSlug reports whether slug is a valid album slug: groups of lowercase
+// letters and digits separated by single hyphens. Slugs name the directories
+// album files are stored in, so they never contain dots, slashes or
+// underscores.
+func ValidSlug(slug string) bool {
+	return slugPattern.MatchString(slug)
 }
 
 // AlbumCreate captures the data required to create a new album.
@@ -44,8 +86,11 @@ type AlbumCreate struct {
 // AlbumUpdate describes the mutable fields for an album. A nil field indicates
 // that no update should be applied for that attribute.
 type AlbumUpdate struct {
-	Title       *string
-	Description *string
+	Title         *string
+	Description   *string
+	SortMode      *PhotoSort
+	ShowPhotoInfo *bool
+	AllowDownload *bool
 }
 
 // Albums defines the operations supported for managing albums.
@@ -60,29 +105,207 @@ type Albums interface {
 	ClearCoverPhoto(ctx context.Context, albumID int64) error
 }
 
-// Photo is a single image that belongs to an album.
+// PhotoVariant is a resized derivative of a photo. Filename is relative to the
+// uploads directory, like Photo.Filename.
+type PhotoVariant struct {
+	Name     string `json:"name"`
+	Filename string `json:"filename"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+}
+
+// PhotoMetadata holds the camera details read from a photo's EXIF data at
+// upload. It is stored separately from the published file, which has its
+// metadata stripped. Zero values mean the camera did not record the field.
+type PhotoMetadata struct {
+	CameraMake   string
+	CameraModel  string
+	Lens         string
+	FocalLength  float64 // millimetres
+	Aperture     float64 // f-number
+	ExposureTime string  // as recorded, e.g. "1/250"
+	ISO          int
+}
+
+// TakenAtSource records where a photo's TakenAt value came from.
+type TakenAtSource string
+
+const (
+	// TakenAtSourceNone is used when the photo has no capture time.
+	TakenAtSourceNone TakenAtSource = ""
+	// TakenAtSourceCamera marks a capture time read from the file's EXIF data.
+	TakenAtSourceCamera TakenAtSource = "camera"
+	// TakenAtSourceManual marks a capture time entered by an admin.
+	TakenAtSourceManual TakenAtSource = "manual"
+)
+
+// Photo is a single image that belongs to an album. OriginalFilename is the
+// name the file was uploaded with; Filename is where it is stored. Width,
+// Height, SizeBytes, MimeType and SHA256 describe the stored file and are zero
+// for photos that have not been measured yet, as is PerceptualHash, a 64-bit
+// dHash in hex used to find visually similar photos. AllowDuplicate marks a photo
+// deliberately kept although the album already holds the same content.
+// Metadata is nil when no camera details were recorded.
 type Photo struct {
-	ID        int64
-	AlbumID   int64
-	Filename  string
-	Caption   string
-	TakenAt   *time.Time
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID               int64
+	AlbumID          int64
+	Filename         string
+	OriginalFilename string
+	Caption          string
+	TakenAt          *time.Time
+	TakenAtSource    TakenAtSource
+	Position         int
+	Variants         []PhotoVariant
+	Width            int
+	Height           int
+	SizeBytes        int64
+	MimeType         string
+	SHA256           string
+	PerceptualHash   string
+	AllowDuplicate   bool
+	Metadata         *PhotoMetadata
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
 }
 
-// PhotoCreate contains the data required to insert a new photo.
+// PhotoCreate contains the data required to insert a new photo. New photos are
+// placed after the existing ones in manual order. Create fails with
+// ErrConflict when the album already has a photo with the same SHA256, unless
+// AllowDuplicate is set.
 type PhotoCreate struct {
-	AlbumID  int64
-	Filename string
-	Caption  string
-	TakenAt  *time.Time
+	AlbumID          int64
+	Filename         string
+	OriginalFilename string
+	Caption          string
+	TakenAt          *time.Time
+	TakenAtSource    TakenAtSource
+	Variants         []PhotoVariant
+	Width            int
+	Height           int
+	SizeBytes        int64
+	MimeType         string
+	SHA256           string
+	PerceptualHash   string
+	AllowDuplicate   bool
+	Metadata         *PhotoMetadata
 }
 
-// Photos defines the operations supported for managing photos.
+// PhotoUpdate describes the mutable fields for a photo. A nil field indicates
+// that no update should be applied for that attribute. Because a nil TakenAt
+// means "unchanged", ClearTakenAt is used to remove a recorded date; it also
+// resets TakenAtSource. Setting a SHA256 already used in the album fails with
+// ErrConflict unless AllowDuplicate is set to true.
+type PhotoUpdate struct {
+	Caption        *string
+	TakenAt        *time.Time
+	TakenAtSource  *TakenAtSource
+	ClearTakenAt   bool
+	Variants       *[]PhotoVariant
+	Width          *int
+	Height         *int
+	SizeBytes      *int64
+	MimeType       *string
+	SHA256         *string
+	PerceptualHash *string
+	AllowDuplicate *bool
+}
+
+// Photos defines the operations supported for managing photos. ListByAlbum
+// returns photos in the album's SortMode. ListCovers returns the photo that
+// represents each album with photos, keyed by album ID: its chosen cover, or
+// otherwise the first photo in its SortMode. FindByHash returns the earliest
+// photo in the album with the given SHA256. Reorder stores a manual order and
+// must be given every photo of the album exactly once, otherwise it fails with
+// ErrConflict.
 type Photos interface {
 	Create(ctx context.Context, input PhotoCreate) (Photo, error)
 	GetByID(ctx context.Context, id int64) (Photo, error)
+	FindByHash(ctx context.Context, albumID int64, sha256 string) (Photo, error)
 	ListByAlbum(ctx context.Context, albumID int64) ([]Photo, error)
+	ListCovers(ctx context.Context) (map[int64]Photo, error)
+	Update(ctx context.Context, id int64, input PhotoUpdate) (Photo, error)
+	Reorder(ctx context.Context, albumID int64, photoIDs []int64) error
+	Delete(ctx context.Context, id int64) error
+}
+
+// Session is a server-side admin login. The ID is the opaque value handed to
+// the browser in the admin cookie.
+type Session struct {
+	ID         string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+}
+
+// SessionCreate contains the data required to start a new session.
+type SessionCreate struct {
+	ID        string
+	ExpiresAt time.Time
+}
+
+// Sessions defines the operations supported for managing admin sessions.
+type Sessions interface {
+	Create(ctx context.Context, input SessionCreate) (Session, error)
+	Get(ctx context.Context, id string) (Session, error)
+	Touch(ctx context.Context, id string, expiresAt time.Time) error
+	Delete(ctx context.Context, id string) error
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// WatchFailure is a file in the watch folder that could not be imported. Path
+// is relative to the watch folder; AlbumSlug is empty when the file is not in
+// an album folder. UpdatedAt is the last time the problem was seen.
+type WatchFailure struct {
+	ID        int64
+	Path      string
+	AlbumSlug string
+	Error     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// WatchFailureRecord describes a failed import of the file at Path.
+type WatchFailureRecord struct {
+	Path      string
+	AlbumSlug string
+	Error     string
+}
+
+// WatchFailures keeps the watch folder problems shown to admins until they
+// are dismissed. Record replaces any earlier failure for the same path.
+type WatchFailures interface {
+	Record(ctx context.Context, input WatchFailureRecord) (WatchFailure, error)
+	List(ctx context.Context) ([]WatchFailure, error)
 	Delete(ctx context.Context, id int64) error
+	DeleteByPath(ctx context.Context, path string) error
+}
+
+// Blob is a photo file stored under a content-addressed key, so photos with
+// the same content share it. RefCount is the number of photos whose original
+// or variants are stored under Key; the store keeps it current as photos are
+// created, updated and deleted. UpdatedAt is the last time the file was
+// registered or claimed for a new photo.
+type Blob struct {
+	Key       string
+	SizeBytes int64
+	RefCount  int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Blobs tracks content-addressed photo files so that a file shared by several
+// photos is kept until the last of them is deleted. Files stored under an
+// album's slug are not tracked.
+//
+// Claim marks a recorded file as about to be referenced and reports whether
+// it is recorded; Register records a newly stored file. Both protect the file
+// from Delete with an earlier cutoff. Delete forgets a file only when no photo
+// references it and it was last claimed before cutoff, and fails with
+// ErrNotFound otherwise.
+type Blobs interface {
+	Claim(ctx context.Context, key string) (bool, error)
+	Register(ctx context.Context, key string, sizeBytes int64) error
+	Get(ctx context.Context, key string) (Blob, error)
+	ListUnreferenced(ctx context.Context, cutoff time.Time) ([]Blob, error)
+	Delete(ctx context.Context, key string, cutoff time.Time) error
 }
@@ -0,0 +1,325 @@
+// Package tus keeps the partial files of resumable uploads made with the tus
+// 1.0 protocol (https://tus.io/protocols/resumable-upload) until they are
+// complete.
+package tus
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound indicates that no upload exists with the given ID.
+	ErrNotFound = errors.New("tus: upload not found")
+	// ErrExpired indicates that the upload was abandoned for longer than the
+	// store's expiry and has been discarded.
+	ErrExpired = errors.New("tus: upload expired")
+	// ErrOffsetMismatch indicates that a chunk did not start at the upload's
+	// current offset.
+	ErrOffsetMismatch = errors.New("tus: offset mismatch")
+	// ErrLocked indicates that another request is writing to the upload.
+	ErrLocked = errors.New("tus: upload is locked")
+	// ErrTooLarge indicates that a chunk would grow the upload past its
+	// declared length.
+	ErrTooLarge = errors.New("tus: upload exceeds its length")
+	// ErrIncomplete indicates that Finish was called before every byte arrived.
+	ErrIncomplete = errors.New("tus: upload is incomplete")
+)
+
+var idPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// Upload describes a resumable upload. Offset is the number of bytes received
+// so far; the upload is complete once it equals Length.
+type Upload struct {
+	ID        string            `json:"id"`
+	AlbumID   int64             `json:"album_id"`
+	Length    int64             `json:"length"`
+	Offset    int64             `json:"-"`
+	Metadata  map[string]string `json:"metadata"`
+	CreatedAt time.Time         `json:"created_at"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// Complete reports whether every byte of the upload has been received.
+func (u Upload) Complete() bool {
+	return u.Offset == u.Length
+}
+
+// Store keeps partial uploads in a staging directory as a data file
+// (<id>.bin) next to a JSON description (<id>.json). Uploads that receive no
+// data for longer than expiry are discarded. It is safe for concurrent use.
+type Store struct {
+	dir    string
+	expiry time.Duration
+
+	mu     sync.Mutex
+	active map[string]bool
+}
+
+// NewStore returns a Store that stages uploads in dir, creating it if needed.
+func NewStore(dir string, expiry time.Duration) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("tus: create staging directory: %w", err)
+	}
+	return &Store{dir: dir, expiry: expiry, active: make(map[string]bool)}, nil
+}
+
+// Create starts an empty upload of length bytes for the album.
+func (s *Store) Create(albumID, length int64, metadata map[string]string) (Upload, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return Upload{}, fmt.Errorf("tus: generate id: %w", err)
+	}
+
+	now := time.Now().UTC()
+	upload := Upload{
+		ID:        hex.EncodeToString(buf),
+		AlbumID:   albumID,
+		Length:    length,
+		Metadata:  metadata,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.expiry),
+	}
+
+	data, err := os.OpenFile(s.dataPath(upload.ID), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return Upload{}, fmt.Errorf("tus: create upload: %w", err)
+	}
+	if err := data.Close(); err != nil {
+		return Upload{}, fmt.Errorf("tus: create upload: %w", err)
+	}
+	if err := s.writeInfo(upload); err != nil {
+		_ = os.Remove(s.dataPath(upload.ID))
+		return Upload{}, err
+	}
+	return upload, nil
+}
+
+// Get returns the upload with its current offset. Expired uploads are
+// removed and reported as ErrExpired.
+func (s *Store) Get(id string) (Upload, error) {
+	upload, err := s.readInfo(id)
+	if err != nil {
+		return Upload{}, err
+	}
+	if !time.Now().Before(upload.ExpiresAt) {
+		if !s.lock(id) {
+			return Upload{}, ErrLocked
+		}
+		defer s.unlock(id)
+		_ = s.remove(id)
+		return Upload{}, ErrExpired
+	}
+	return upload, nil
+}
+
+// Append writes the chunk read from r at offset, which must equal the
+// upload's current offset. Bytes received before r fails are kept so the
+// client can resume from them; the returned upload carries the new offset
+// either way. Every append pushes the upload's expiry forward.
+func (s *Store) Append(id string, offset int64, r io.Reader) (Upload, error) {
+	if !s.lock(id) {
+		return Upload{}, ErrLocked
+	}
+	defer s.unlock(id)
+
+	upload, err := s.readInfo(id)
+	if err != nil {
+		return Upload{}, err
+	}
+	if !time.Now().Before(upload.ExpiresAt) {
+		_ = s.remove(id)
+		return Upload{}, ErrExpired
+	}
+	if offset != upload.Offset {
+		return upload, ErrOffsetMismatch
+	}
+
+	data, err := os.OpenFile(s.dataPath(id), os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return upload, fmt.Errorf("tus: open upload: %w", err)
+	}
+
+	// Read one byte past the remaining length to detect oversized chunks.
+	remaining := upload.Length - upload.Offset
+	written, copyErr := io.Copy(data, io.LimitReader(r, remaining+1))
+	if written > remaining {
+		copyErr = ErrTooLarge
+		if err := data.Truncate(upload.Length); err != nil {
+			copyErr = fmt.Errorf("tus: truncate upload: %w", err)
+		}
+		written = remaining
+	}
+	if err := data.Close(); err != nil && copyErr == nil {
+		copyErr = fmt.Errorf("tus: write upload: %w", err)
+	}
+
+	upload.Offset += written
+	upload.ExpiresAt = time.Now().UTC().Add(s.expiry)
+	if err := s.writeInfo(upload); err != nil && copyErr == nil {
+		copyErr = err
+	}
+	return upload, copyErr
+}
+
+// Finish hands the data file of a complete upload to fn and discards the
+// upload once fn succeeds. When fn fails the upload is kept, so it can be
+// finished again. The upload stays locked while fn runs so retried chunks
+// cannot finish it twice.
+func (s *Store) Finish(id string, fn func(upload Upload, path string) error) error {
+	if !s.lock(id) {
+		return ErrLocked
+	}
+	defer s.unlock(id)
+
+	upload, err := s.readInfo(id)
+	if err != nil {
+		return err
+	}
+	if !upload.Complete() {
+		return ErrIncomplete
+	}
+
+	if err := fn(upload, s.dataPath(id)); err != nil {
+		return err
+	}
+	return s.remove(id)
+}
+
+// Remove discards the upload. It returns ErrLocked while a chunk is being
+// written.
+func (s *Store) Remove(id string) error {
+	if !idPattern.MatchString(id) {
+		return ErrNotFound
+	}
+	if !s.lock(id) {
+		return ErrLocked
+	}
+	defer s.unlock(id)
+	return s.remove(id)
+}
+
+// Sweep removes every upload whose expiry has passed, along with
+// descriptions left without a data file, and returns how many were discarded.
+func (s *Store) Sweep() (int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, fmt.Errorf("tus: read staging directory: %w", err)
+	}
+
+	now := time.Now()
+	removed := 0
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || !idPattern.MatchString(id) {
+			continue
+		}
+		upload, err := s.readInfo(id)
+		switch {
+		case errors.Is(err, ErrNotFound):
+		case err != nil, now.Before(upload.ExpiresAt):
+			continue
+		}
+		if !s.lock(id) {
+			continue
+		}
+		err = s.remove(id)
+		s.unlock(id)
+		if err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func (s *Store) lock(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active[id] {
+		return false
+	}
+	s.active[id] = true
+	return true
+}
+
+func (s *Store) unlock(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.active, id)
+}
+
+func (s *Store) readInfo(id string) (Upload, error) {
+	if !idPattern.MatchString(id) {
+		return Upload{}, ErrNotFound
+	}
+
+	raw, err := os.ReadFile(s.infoPath(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Upload{}, ErrNotFound
+	}
+	if err != nil {
+		return Upload{}, fmt.Errorf("tus: read upload: %w", err)
+	}
+
+	var upload Upload
+	if err := json.Unmarshal(raw, &upload); err != nil {
+		return Upload{}, fmt.Errorf("tus: decode upload %s: %w", id, err)
+	}
+
+	stat, err := os.Stat(s.dataPath(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Upload{}, ErrNotFound
+	}
+	if err != nil {
+		return Upload{}, fmt.Errorf("tus: stat upload: %w", err)
+	}
+	upload.Offset = stat.Size()
+	return upload, nil
+}
+
+// writeInfo replaces the JSON description atomically so a crash never leaves
+// a truncated file behind.
+func (s *Store) writeInfo(upload Upload) error {
+	raw, err := json.Marshal(upload)
+	if err != nil {
+		return fmt.Errorf("tus: encode upload: %w", err)
+	}
+	tmp := s.infoPath(upload.ID) + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return fmt.Errorf("tus: write upload: %w", err)
+	}
+	if err := os.Rename(tmp, s.infoPath(upload.ID)); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("tus: write upload: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) remove(id string) error {
+	for _, name := range []string{s.dataPath(id), s.infoPath(id)} {
+		if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("tus: remove upload: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *Store) dataPath(id string) string {
+	return filepath.Join(s.dir, id+".bin")
+}
+
+func (s *Store) infoPath(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
@@ -0,0 +1,146 @@
+package tus_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/Oxyrus/memories/internal/tus"
+)
+
+func TestStoreAppendAndFinish(t *testing.T) {
+	store, err := tus.NewStore(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+
+	upload, err := store.Create(7, 11, map[string]string{"filename": "beach.jpg"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if upload.Offset != 0 || upload.Complete() {
+		t.Fatalf("expected an empty upload, got %+v", upload)
+	}
+
+	// A connection that drops mid-chunk keeps the bytes that arrived.
+	upload, err = store.Append(upload.ID, 0, iotest.TimeoutReader(strings.NewReader("hello")))
+	if err == nil || upload.Offset != 5 {
+		t.Fatalf("expected offset 5 after interrupted chunk, got %d (err %v)", upload.Offset, err)
+	}
+
+	if _, err := store.Append(upload.ID, 0, strings.NewReader("hello")); !errors.Is(err, tus.ErrOffsetMismatch) {
+		t.Fatalf("expected ErrOffsetMismatch, got %v", err)
+	}
+
+	if err := store.Finish(upload.ID, func(tus.Upload, string) error { return nil }); !errors.Is(err, tus.ErrIncomplete) {
+		t.Fatalf("expected ErrIncomplete, got %v", err)
+	}
+
+	upload, err = store.Append(upload.ID, 5, strings.NewReader(" world"))
+	if err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if !upload.Complete() {
+		t.Fatalf("expected upload to be complete, got offset %d of %d", upload.Offset, upload.Length)
+	}
+
+	got, err := store.Get(upload.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Offset != 11 || got.AlbumID != 7 || got.Metadata["filename"] != "beach.jpg" {
+		t.Fatalf("unexpected upload %+v", got)
+	}
+
+	// A failed finish keeps the upload so it can be finished again.
+	failure := errors.New("storage unavailable")
+	if err := store.Finish(upload.ID, func(tus.Upload, string) error { return failure }); !errors.Is(err, failure) {
+		t.Fatalf("expected the callback's error, got %v", err)
+	}
+	if got, err := store.Get(upload.ID); err != nil || !got.Complete() {
+		t.Fatalf("expected the upload to be kept after a failed finish, got %+v (%v)", got, err)
+	}
+
+	var content []byte
+	err = store.Finish(upload.ID, func(finished tus.Upload, path string) error {
+		content, err = os.ReadFile(path)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("finish: %v", err)
+	}
+	if string(content) != "hello world" {
+		t.Fatalf("expected staged content %q, got %q", "hello world", content)
+	}
+
+	if _, err := store.Get(upload.ID); !errors.Is(err, tus.ErrNotFound) {
+		t.Fatalf("expected finished upload to be removed, got %v", err)
+	}
+}
+
+func TestStoreRejectsOversizedChunk(t *testing.T) {
+	store, err := tus.NewStore(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+
+	upload, err := store.Create(1, 4, nil)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	upload, err = store.Append(upload.ID, 0, strings.NewReader("too long"))
+	if !errors.Is(err, tus.ErrTooLarge) {
+		t.Fatalf("expected ErrTooLarge, got %v", err)
+	}
+	if upload.Offset != 4 {
+		t.Fatalf("expected offset to stop at the declared length, got %d", upload.Offset)
+	}
+}
+
+func TestStoreExpiresAbandonedUploads(t *testing.T) {
+	dir := t.TempDir()
+	store, err := tus.NewStore(dir, time.Millisecond)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+
+	expired, err := store.Create(1, 10, nil)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	swept, err := store.Create(1, 10, nil)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	if _, err := store.Get(expired.ID); !errors.Is(err, tus.ErrExpired) {
+		t.Fatalf("expected ErrExpired, got %v", err)
+	}
+	if _, err := store.Append(expired.ID, 0, strings.NewReader("x")); !errors.Is(err, tus.ErrNotFound) {
+		t.Fatalf("expected expired upload to be discarded, got %v", err)
+	}
+
+	removed, err := store.Sweep()
+	if err != nil {
+		t.Fatalf("sweep: %v", err)
+	}
+	if removed != 1 {
+		t.Fatalf("expected sweep to remove 1 upload, got %d", removed)
+	}
+	if _, err := store.Get(swept.ID); !errors.Is(err, tus.ErrNotFound) {
+		t.Fatalf("expected swept upload to be gone, got %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read staging dir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected staging dir to be empty, found %d entries", len(entries))
+	}
+}
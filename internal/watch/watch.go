@@ -0,0 +1,268 @@
+// Package watch imports photos dropped into a watch folder, such as a
+// directory a NAS syncs phone photos into. Each top-level folder is named
+// after the slug of the album its photos go to.
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Oxyrus/memories/internal/ingest"
+	"github.com/Oxyrus/memories/internal/storage"
+)
+
+// Imported photos, including ones already in their album, are moved to
+// ProcessedDir; photos that were rejected are moved to FailedDir. Both live in
+// the watch folder and mirror its layout.
+const (
+	ProcessedDir = ".processed"
+	FailedDir    = ".failed"
+)
+
+// Watcher scans a watch folder for new photos. Scans are not safe to run
+// concurrently.
+type Watcher struct {
+	logger   *slog.Logger
+	dir      string
+	settle   time.Duration
+	albums   storage.Albums
+	failures storage.WatchFailures
+	pipeline *ingest.Pipeline
+
+	// pending remembers the size and modification time each file had in the
+	// previous scan, so files still being written are left alone.
+	pending map[string]fileState
+	// reported holds the recorded failures, loaded at the start of each scan
+	// so a persistent problem is not rewritten every time, while failures
+	// left from an earlier run or dismissed by an admin are still noticed.
+	reported map[string]string
+}
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// New returns a Watcher for dir. A file is imported once its size and
+// modification time are unchanged between two scans and it has not been
+// modified for settle.
+func New(logger *slog.Logger, dir string, settle time.Duration, albums storage.Albums, failures storage.WatchFailures, pipeline *ingest.Pipeline) *Watcher {
+	return &Watcher{
+		logger:   logger,
+		dir:      dir,
+		settle:   settle,
+		albums:   albums,
+		failures: failures,
+		pipeline: pipeline,
+		pending:  make(map[string]fileState),
+	}
+}
+
+// Run scans the watch folder now and then every interval until ctx is done.
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Scan(ctx); err != nil {
+			w.logger.Error("failed to scan watch folder", "dir", w.dir, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan imports every photo in the watch folder that has finished writing.
+func (w *Watcher) Scan(ctx context.Context) error {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return err
+	}
+
+	if err := w.loadReported(ctx); err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			break
+		}
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		if !entry.IsDir() {
+			if ingest.IsImageFile(name) {
+				w.report(ctx, name, "", "Photos must be placed in a folder named after the album they belong to.")
+			}
+			continue
+		}
+
+		album, err := w.albums.GetBySlug(ctx, name)
+		if errors.Is(err, storage.ErrNotFound) {
+			w.report(ctx, name+"/", name, fmt.Sprintf("No album has the slug “%s”. Create the album or rename the folder.", name))
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("load album %s: %w", name, err)
+		}
+		w.resolve(ctx, name+"/")
+
+		if err := w.scanAlbum(ctx, album, seen); err != nil {
+			return err
+		}
+	}
+
+	for rel := range w.pending {
+		if !seen[rel] {
+			delete(w.pending, rel)
+		}
+	}
+	return nil
+}
+
+// scanAlbum imports the finished photos below the album's folder and adds
+// every photo it finds to seen.
+func (w *Watcher) scanAlbum(ctx context.Context, album storage.Album, seen map[string]bool) error {
+	root := filepath.Join(w.dir, album.Slug)
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return filepath.SkipAll
+		}
+		if p != root && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !ingest.IsImageFile(d.Name()) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(w.dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		seen[rel] = true
+
+		info, err := d.Info()
+		if err != nil {
+			// Moved or deleted since the directory was read.
+			return nil
+		}
+		if !w.ready(rel, info) {
+			return nil
+		}
+		w.importFile(ctx, album, rel, p)
+		return nil
+	})
+}
+
+// ready reports whether the file at rel has stopped changing.
+func (w *Watcher) ready(rel string, info fs.FileInfo) bool {
+	state := fileState{size: info.Size(), modTime: info.ModTime()}
+	previous, ok := w.pending[rel]
+	w.pending[rel] = state
+	if !ok || previous.size != state.size || !previous.modTime.Equal(state.modTime) {
+		return false
+	}
+	return time.Since(state.modTime) >= w.settle
+}
+
+func (w *Watcher) importFile(ctx context.Context, album storage.Album, rel, diskPath string) {
+	result := w.pipeline.Ingest(ctx, album, rel, ingest.CopyFile(diskPath), ingest.Options{})
+
+	switch result.Status {
+	case ingest.StatusUploaded, ingest.StatusDuplicate:
+		if err := w.move(rel, ProcessedDir); err != nil {
+			w.logger.Error("failed to move imported photo out of the watch folder", "path", rel, "error", err)
+			w.report(ctx, rel, album.Slug, "The photo was imported but could not be moved out of the watch folder.")
+			return
+		}
+		w.resolve(ctx, rel)
+		w.logger.Info("imported photo from watch folder", "path", rel, "albumID", album.ID, "status", result.Status)
+	case ingest.StatusRejected:
+		if err := w.move(rel, FailedDir); err != nil {
+			w.logger.Error("failed to move rejected photo out of the watch folder", "path", rel, "error", err)
+		}
+		w.report(ctx, rel, album.Slug, result.Error)
+	default:
+		// Left in place so the next scan tries again.
+		w.report(ctx, rel, album.Slug, result.Error)
+	}
+}
+
+// move relocates the file at rel into the same place below dir, adding a
+// number to its name if another file is already there.
+func (w *Watcher) move(rel, dir string) error {
+	src := filepath.Join(w.dir, filepath.FromSlash(rel))
+	dst := filepath.Join(w.dir, dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+
+	ext := filepath.Ext(dst)
+	stem := strings.TrimSuffix(dst, ext)
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(dst); errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		dst = fmt.Sprintf("%s-%d%s", stem, i, ext)
+	}
+
+	delete(w.pending, rel)
+	return os.Rename(src, dst)
+}
+
+// loadReported replaces reported with the failures currently recorded.
+func (w *Watcher) loadReported(ctx context.Context) error {
+	failures, err := w.failures.List(ctx)
+	if err != nil {
+		return fmt.Errorf("load watch folder failures: %w", err)
+	}
+	w.reported = make(map[string]string, len(failures))
+	for _, failure := range failures {
+		w.reported[failure.Path] = failure.Error
+	}
+	return nil
+}
+
+// report records a failure for admins unless the same one already is.
+func (w *Watcher) report(ctx context.Context, rel, slug, message string) {
+	if w.reported[rel] == message {
+		return
+	}
+	w.logger.Warn("watch folder import failed", "path", rel, "error", message)
+	if _, err := w.failures.Record(ctx, storage.WatchFailureRecord{Path: rel, AlbumSlug: slug, Error: message}); err != nil {
+		w.logger.Error("failed to record watch folder failure", "path", rel, "error", err)
+		return
+	}
+	w.reported[rel] = message
+}
+
+// resolve clears a failure reported earlier for rel.
+func (w *Watcher) resolve(ctx context.Context, rel string) {
+	if _, ok := w.reported[rel]; !ok {
+		return
+	}
+	if err := w.failures.DeleteByPath(ctx, rel); err != nil {
+		w.logger.Error("failed to clear watch folder failure", "path", rel, "error", err)
+		return
+	}
+	delete(w.reported, rel)
+}
@@ -0,0 +1,229 @@
+package watch_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Oxyrus/memories/internal/blob"
+	"github.com/Oxyrus/memories/internal/ingest"
+	"github.com/Oxyrus/memories/internal/storage"
+	"github.com/Oxyrus/memories/internal/storage/sqlite"
+	"github.com/Oxyrus/memories/internal/watch"
+)
+
+func TestWatcherImportsFinishedPhotos(t *testing.T) {
+	w, store, dir := newWatcher(t)
+	ctx := context.Background()
+
+	album, err := store.Albums().Create(ctx, storage.AlbumCreate{Slug: "summer", Title: "Summer"})
+	if err != nil {
+		t.Fatalf("create album: %v", err)
+	}
+	writeFile(t, filepath.Join(dir, "summer", "2024", "beach.jpg"), testJPEG(t))
+	writeFile(t, filepath.Join(dir, "summer", "notes.txt"), []byte("packing list"))
+
+	scan(t, w)
+	assertPhotoCount(t, store, album.ID, 0)
+
+	scan(t, w)
+	assertPhotoCount(t, store, album.ID, 1)
+	if _, err := os.Stat(filepath.Join(dir, watch.ProcessedDir, "summer", "2024", "beach.jpg")); err != nil {
+		t.Fatalf("expected imported photo to be moved to %s: %v", watch.ProcessedDir, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "summer", "notes.txt")); err != nil {
+		t.Fatalf("expected other files to be left alone: %v", err)
+	}
+
+	// The same photo synced again is already in the album.
+	writeFile(t, filepath.Join(dir, "summer", "2024", "beach.jpg"), testJPEG(t))
+	scan(t, w)
+	scan(t, w)
+	assertPhotoCount(t, store, album.ID, 1)
+	if _, err := os.Stat(filepath.Join(dir, watch.ProcessedDir, "summer", "2024", "beach-1.jpg")); err != nil {
+		t.Fatalf("expected duplicate to be moved next to the first copy: %v", err)
+	}
+	assertFailures(t, store)
+}
+
+func TestWatcherWaitsForFilesToStopChanging(t *testing.T) {
+	w, store, dir := newWatcher(t)
+	ctx := context.Background()
+
+	album, err := store.Albums().Create(ctx, storage.AlbumCreate{Slug: "summer", Title: "Summer"})
+	if err != nil {
+		t.Fatalf("create album: %v", err)
+	}
+	photo := testJPEG(t)
+	path := filepath.Join(dir, "summer", "beach.jpg")
+	writeFile(t, path, photo[:len(photo)/2])
+
+	scan(t, w)
+	writeFile(t, path, photo)
+	scan(t, w)
+	assertPhotoCount(t, store, album.ID, 0)
+
+	scan(t, w)
+	assertPhotoCount(t, store, album.ID, 1)
+}
+
+func TestWatcherReportsFailures(t *testing.T) {
+	w, store, dir := newWatcher(t)
+	ctx := context.Background()
+
+	album, err := store.Albums().Create(ctx, storage.AlbumCreate{Slug: "summer", Title: "Summer"})
+	if err != nil {
+		t.Fatalf("create album: %v", err)
+	}
+	writeFile(t, filepath.Join(dir, "summer", "broken.jpg"), []byte("not really a jpeg"))
+	writeFile(t, filepath.Join(dir, "winter", "snow.jpg"), testJPEG(t))
+	writeFile(t, filepath.Join(dir, "stray.jpg"), testJPEG(t))
+
+	scan(t, w)
+	scan(t, w)
+	assertPhotoCount(t, store, album.ID, 0)
+	if _, err := os.Stat(filepath.Join(dir, watch.FailedDir, "summer", "broken.jpg")); err != nil {
+		t.Fatalf("expected rejected photo to be moved to %s: %v", watch.FailedDir, err)
+	}
+	assertFailures(t, store, "summer/broken.jpg", "stray.jpg", "winter/")
+
+	// Creating the missing album clears its failure and imports its photos.
+	winter, err := store.Albums().Create(ctx, storage.AlbumCreate{Slug: "winter", Title: "Winter"})
+	if err != nil {
+		t.Fatalf("create album: %v", err)
+	}
+	scan(t, w)
+	scan(t, w)
+	assertPhotoCount(t, store, winter.ID, 1)
+	assertFailures(t, store, "summer/broken.jpg", "stray.jpg")
+}
+
+func TestWatcherClearsFailuresAfterRestart(t *testing.T) {
+	w, store, dir := newWatcher(t)
+	ctx := context.Background()
+
+	writeFile(t, filepath.Join(dir, "winter", "snow.jpg"), testJPEG(t))
+	scan(t, w)
+	assertFailures(t, store, "winter/")
+
+	// A failure recorded before a restart is cleared once it is resolved.
+	winter, err := store.Albums().Create(ctx, storage.AlbumCreate{Slug: "winter", Title: "Winter"})
+	if err != nil {
+		t.Fatalf("create album: %v", err)
+	}
+	restarted := openWatcher(t, store, dir)
+	scan(t, restarted)
+	scan(t, restarted)
+	assertPhotoCount(t, store, winter.ID, 1)
+	assertFailures(t, store)
+}
+
+func TestWatcherReportsDismissedFailuresAgain(t *testing.T) {
+	w, store, dir := newWatcher(t)
+	ctx := context.Background()
+
+	writeFile(t, filepath.Join(dir, "stray.jpg"), testJPEG(t))
+	scan(t, w)
+	failures, err := store.WatchFailures().List(ctx)
+	if err != nil || len(failures) != 1 {
+		t.Fatalf("expected one failure, got %+v (%v)", failures, err)
+	}
+	if err := store.WatchFailures().Delete(ctx, failures[0].ID); err != nil {
+		t.Fatalf("dismiss failure: %v", err)
+	}
+
+	scan(t, w)
+	assertFailures(t, store, "stray.jpg")
+}
+
+func newWatcher(t *testing.T) (*watch.Watcher, storage.Store, string) {
+	t.Helper()
+
+	store, err := sqlite.Open(filepath.Join(t.TempDir(), "memories.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	dir := t.TempDir()
+	return openWatcher(t, store, dir), store, dir
+}
+
+// openWatcher returns a fresh Watcher for dir, as after a restart.
+func openWatcher(t *testing.T, store storage.Store, dir string) *watch.Watcher {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError}))
+	blobs, err := blob.NewLocal(t.TempDir(), "/uploads")
+	if err != nil {
+		t.Fatalf("open blob store: %v", err)
+	}
+	pipeline := ingest.New(logger, store.Photos(), store.Blobs(), blobs, ingest.LayoutAlbum)
+	return watch.New(logger, dir, time.Nanosecond, store.Albums(), store.WatchFailures(), pipeline)
+}
+
+func scan(t *testing.T, w *watch.Watcher) {
+	t.Helper()
+	if err := w.Scan(context.Background()); err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+}
+
+func assertPhotoCount(t *testing.T, store storage.Store, albumID int64, want int) {
+	t.Helper()
+	photos, err := store.Photos().ListByAlbum(context.Background(), albumID)
+	if err != nil {
+		t.Fatalf("list photos: %v", err)
+	}
+	if len(photos) != want {
+		t.Fatalf("expected %d photos, got %d", want, len(photos))
+	}
+}
+
+func assertFailures(t *testing.T, store storage.Store, paths ...string) {
+	t.Helper()
+	failures, err := store.WatchFailures().List(context.Background())
+	if err != nil {
+		t.Fatalf("list failures: %v", err)
+	}
+	got := make(map[string]bool, len(failures))
+	for _, failure := range failures {
+		got[failure.Path] = true
+	}
+	if len(got) != len(paths) {
+		t.Fatalf("expected failures for %v, got %+v", paths, failures)
+	}
+	for _, path := range paths {
+		if !got[path] {
+			t.Fatalf("expected a failure for %s, got %+v", path, failures)
+		}
+	}
+}
+
+func writeFile(t *testing.T, path string, content []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("create dir: %v", err)
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func testJPEG(t *testing.T) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("encode test jpeg: %v", err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,349 @@
+// Package zipstream writes ZIP archives of files whose sizes are known up
+// front. Entries are stored without compression, so the length of the archive
+// and the position of every byte in it are known before any file is read.
+// That lets HTTP handlers send Content-Length and serve byte ranges of an
+// archive that is generated on the fly instead of buffered.
+package zipstream
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+)
+
+const (
+	localHeaderLen       = 30
+	centralHeaderLen     = 46
+	descriptorLen        = 16
+	descriptor64Len      = 24
+	zip64ExtraLen        = 28
+	endRecordLen         = 22
+	end64RecordLen       = 56
+	end64LocatorLen      = 20
+	uint32max            = 1<<32 - 1
+	uint16max            = 1<<16 - 1
+	flagDataDescriptor   = 0x8
+	flagUTF8             = 0x800
+	versionDefault       = 20
+	versionZip64         = 45
+	creatorUnix          = 3 << 8
+	unixRegularFileAttrs = 0o100644 << 16
+)
+
+// Entry is a file in the archive. Open must return exactly Size bytes every
+// time it is called; the archive is corrupt otherwise.
+type Entry struct {
+	Name     string
+	Size     int64
+	Modified time.Time
+	Open     func() (io.ReadCloser, error)
+}
+
+// ErrSizeMismatch indicates that an entry's content was shorter than its
+// declared size, usually because the file changed after the archive was laid
+// out.
+var ErrSizeMismatch = errors.New("zipstream: entry size changed")
+
+type entryLayout struct {
+	offset   int64 // local header
+	dataFrom int64
+	dataTo   int64
+	descTo   int64
+	zip64    bool
+}
+
+// Archive is the precomputed layout of a ZIP archive.
+type Archive struct {
+	entries  []Entry
+	layout   []entryLayout
+	cdOffset int64
+	cdSize   int64
+	zip64End bool
+	size     int64
+}
+
+// New lays out an archive of entries in order.
+func New(entries []Entry) *Archive {
+	a := &Archive{entries: entries, layout: make([]entryLayout, len(entries))}
+
+	var pos int64
+	for i, entry := range entries {
+		l := entryLayout{offset: pos, zip64: entry.Size >= uint32max || pos >= uint32max}
+		l.dataFrom = pos + localHeaderLen + int64(len(entry.Name))
+		l.dataTo = l.dataFrom + entry.Size
+		l.descTo = l.dataTo + descriptorLen
+		if l.zip64 {
+			l.descTo = l.dataTo + descriptor64Len
+		}
+		a.layout[i] = l
+		pos = l.descTo
+	}
+
+	a.cdOffset = pos
+	for i, entry := range entries {
+		a.cdSize += centralHeaderLen + int64(len(entry.Name))
+		if a.layout[i].zip64 {
+			a.cdSize += zip64ExtraLen
+		}
+	}
+
+	a.zip64End = len(entries) >= uint16max || a.cdOffset >= uint32max || a.cdSize >= uint32max
+	a.size = a.cdOffset + a.cdSize + endRecordLen
+	if a.zip64End {
+		a.size += end64RecordLen + end64LocatorLen
+	}
+	return a
+}
+
+// Size returns the length of the archive in bytes.
+func (a *Archive) Size() int64 {
+	return a.size
+}
+
+// WriteTo writes the whole archive to w.
+func (a *Archive) WriteTo(w io.Writer) (int64, error) {
+	out := &window{w: w, start: 0, end: a.size}
+	err := a.write(out)
+	return out.written, err
+}
+
+// WriteRange writes bytes [start, end) of the archive to w. Entries before
+// start are still read to compute the checksums stored later in the archive,
+// but only when the range reaches those checksums.
+func (a *Archive) WriteRange(w io.Writer, start, end int64) error {
+	if start < 0 || end > a.size || start > end {
+		return fmt.Errorf("zipstream: invalid range %d-%d of %d", start, end, a.size)
+	}
+	return a.write(&window{w: w, start: start, end: end})
+}
+
+func (a *Archive) write(out *window) error {
+	crcs := make([]uint32, len(a.entries))
+
+	for i, entry := range a.entries {
+		l := a.layout[i]
+		if out.pos >= out.end {
+			return nil
+		}
+
+		if err := out.write(a.localHeader(entry, l)); err != nil {
+			return err
+		}
+
+		sendData := out.overlaps(l.dataFrom, l.dataTo)
+		needCRC := out.overlaps(l.dataTo, l.descTo) || out.end > a.cdOffset
+		if sendData || needCRC {
+			crc, err := copyEntry(out, entry, sendData)
+			if err != nil {
+				return fmt.Errorf("zipstream: %s: %w", entry.Name, err)
+			}
+			crcs[i] = crc
+		} else {
+			out.skip(entry.Size)
+		}
+
+		if err := out.write(descriptor(crcs[i], entry.Size, l.zip64)); err != nil {
+			return err
+		}
+	}
+
+	for i, entry := range a.entries {
+		if out.pos >= out.end {
+			return nil
+		}
+		if err := out.write(a.centralHeader(entry, a.layout[i], crcs[i])); err != nil {
+			return err
+		}
+	}
+
+	return out.write(a.endRecords())
+}
+
+// copyEntry streams the entry through a checksum, forwarding it to out when
+// send is set and skipping over its bytes otherwise.
+func copyEntry(out *window, entry Entry, send bool) (uint32, error) {
+	src, err := entry.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	hash := crc32.NewIEEE()
+	var dst io.Writer = hash
+	if send {
+		dst = io.MultiWriter(hash, out)
+	}
+	copied, err := io.CopyN(dst, src, entry.Size)
+	if errors.Is(err, io.EOF) {
+		err = ErrSizeMismatch
+	}
+	if !send {
+		out.skip(copied)
+	}
+	return hash.Sum32(), err
+}
+
+func (a *Archive) localHeader(entry Entry, l entryLayout) []byte {
+	version := uint16(versionDefault)
+	if l.zip64 {
+		version = versionZip64
+	}
+	date, clock := dosTime(entry.Modified)
+
+	b := make([]byte, 0, localHeaderLen+len(entry.Name))
+	b = binary.LittleEndian.AppendUint32(b, 0x04034b50)
+	b = binary.LittleEndian.AppendUint16(b, version)
+	b = binary.LittleEndian.AppendUint16(b, flagDataDescriptor|flagUTF8)
+	b = binary.LittleEndian.AppendUint16(b, 0) // stored
+	b = binary.LittleEndian.AppendUint16(b, clock)
+	b = binary.LittleEndian.AppendUint16(b, date)
+	// CRC and sizes follow the data in the descriptor.
+	b = binary.LittleEndian.AppendUint32(b, 0)
+	b = binary.LittleEndian.AppendUint32(b, 0)
+	b = binary.LittleEndian.AppendUint32(b, 0)
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(entry.Name)))
+	b = binary.LittleEndian.AppendUint16(b, 0)
+	return append(b, entry.Name...)
+}
+
+func descriptor(crc uint32, size int64, zip64 bool) []byte {
+	b := make([]byte, 0, descriptor64Len)
+	b = binary.LittleEndian.AppendUint32(b, 0x08074b50)
+	b = binary.LittleEndian.AppendUint32(b, crc)
+	if zip64 {
+		b = binary.LittleEndian.AppendUint64(b, uint64(size))
+		return binary.LittleEndian.AppendUint64(b, uint64(size))
+	}
+	b = binary.LittleEndian.AppendUint32(b, uint32(size))
+	return binary.LittleEndian.AppendUint32(b, uint32(size))
+}
+
+func (a *Archive) centralHeader(entry Entry, l entryLayout, crc uint32) []byte {
+	version := uint16(versionDefault)
+	size32, offset32 := uint32(entry.Size), uint32(l.offset)
+	extraLen := 0
+	if l.zip64 {
+		version = versionZip64
+		size32, offset32 = uint32max, uint32max
+		extraLen = zip64ExtraLen
+	}
+	date, clock := dosTime(entry.Modified)
+
+	b := make([]byte, 0, centralHeaderLen+len(entry.Name)+extraLen)
+	b = binary.LittleEndian.AppendUint32(b, 0x02014b50)
+	b = binary.LittleEndian.AppendUint16(b, creatorUnix|version)
+	b = binary.LittleEndian.AppendUint16(b, version)
+	b = binary.LittleEndian.AppendUint16(b, flagDataDescriptor|flagUTF8)
+	b = binary.LittleEndian.AppendUint16(b, 0) // stored
+	b = binary.LittleEndian.AppendUint16(b, clock)
+	b = binary.LittleEndian.AppendUint16(b, date)
+	b = binary.LittleEndian.AppendUint32(b, crc)
+	b = binary.LittleEndian.AppendUint32(b, size32) // compressed
+	b = binary.LittleEndian.AppendUint32(b, size32) // uncompressed
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(entry.Name)))
+	b = binary.LittleEndian.AppendUint16(b, uint16(extraLen))
+	b = binary.LittleEndian.AppendUint16(b, 0) // comment
+	b = binary.LittleEndian.AppendUint16(b, 0) // disk
+	b = binary.LittleEndian.AppendUint16(b, 0) // internal attributes
+	b = binary.LittleEndian.AppendUint32(b, unixRegularFileAttrs)
+	b = binary.LittleEndian.AppendUint32(b, offset32)
+	b = append(b, entry.Name...)
+	if l.zip64 {
+		b = binary.LittleEndian.AppendUint16(b, 0x0001)
+		b = binary.LittleEndian.AppendUint16(b, zip64ExtraLen-4)
+		b = binary.LittleEndian.AppendUint64(b, uint64(entry.Size))
+		b = binary.LittleEndian.AppendUint64(b, uint64(entry.Size))
+		b = binary.LittleEndian.AppendUint64(b, uint64(l.offset))
+	}
+	return b
+}
+
+func (a *Archive) endRecords() []byte {
+	count := uint64(len(a.entries))
+	b := make([]byte, 0, end64RecordLen+end64LocatorLen+endRecordLen)
+
+	count16, cdSize32, cdOffset32 := uint16(count), uint32(a.cdSize), uint32(a.cdOffset)
+	if a.zip64End {
+		end64Offset := uint64(a.cdOffset + a.cdSize)
+		b = binary.LittleEndian.AppendUint32(b, 0x06064b50)
+		b = binary.LittleEndian.AppendUint64(b, end64RecordLen-12)
+		b = binary.LittleEndian.AppendUint16(b, creatorUnix|versionZip64)
+		b = binary.LittleEndian.AppendUint16(b, versionZip64)
+		b = binary.LittleEndian.AppendUint32(b, 0) // this disk
+		b = binary.LittleEndian.AppendUint32(b, 0) // central directory disk
+		b = binary.LittleEndian.AppendUint64(b, count)
+		b = binary.LittleEndian.AppendUint64(b, count)
+		b = binary.LittleEndian.AppendUint64(b, uint64(a.cdSize))
+		b = binary.LittleEndian.AppendUint64(b, uint64(a.cdOffset))
+
+		b = binary.LittleEndian.AppendUint32(b, 0x07064b50)
+		b = binary.LittleEndian.AppendUint32(b, 0) // disk with the zip64 end record
+		b = binary.LittleEndian.AppendUint64(b, end64Offset)
+		b = binary.LittleEndian.AppendUint32(b, 1) // total disks
+
+		count16, cdSize32, cdOffset32 = uint16max, uint32max, uint32max
+	}
+
+	b = binary.LittleEndian.AppendUint32(b, 0x06054b50)
+	b = binary.LittleEndian.AppendUint16(b, 0) // this disk
+	b = binary.LittleEndian.AppendUint16(b, 0) // central directory disk
+	b = binary.LittleEndian.AppendUint16(b, count16)
+	b = binary.LittleEndian.AppendUint16(b, count16)
+	b = binary.LittleEndian.AppendUint32(b, cdSize32)
+	b = binary.LittleEndian.AppendUint32(b, cdOffset32)
+	return binary.LittleEndian.AppendUint16(b, 0) // comment
+}
+
+// dosTime converts t to the MS-DOS date and time fields, which cannot
+// represent dates before 1980.
+func dosTime(t time.Time) (date, clock uint16) {
+	if t.Year() < 1980 {
+		t = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	date = uint16(t.Day() + int(t.Month())<<5 + (t.Year()-1980)<<9)
+	clock = uint16(t.Second()/2 + t.Minute()<<5 + t.Hour()<<11)
+	return date, clock
+}
+
+// window forwards the bytes of the archive that fall in [start, end) and
+// discards the rest while tracking the archive position.
+type window struct {
+	w       io.Writer
+	pos     int64
+	start   int64
+	end     int64
+	written int64
+}
+
+func (o *window) overlaps(from, to int64) bool {
+	return from < o.end && to > o.start
+}
+
+func (o *window) skip(n int64) {
+	o.pos += n
+}
+
+func (o *window) write(p []byte) error {
+	_, err := o.Write(p)
+	return err
+}
+
+func (o *window) Write(p []byte) (int, error) {
+	from, to := o.pos, o.pos+int64(len(p))
+	o.pos = to
+	if !o.overlaps(from, to) {
+		return len(p), nil
+	}
+
+	lo := max(o.start-from, 0)
+	hi := min(o.end-from, int64(len(p)))
+	n, err := o.w.Write(p[lo:hi])
+	o.written += int64(n)
+	if err != nil {
+		return int(lo) + n, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,136 @@
+package zipstream_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Oxyrus/memories/internal/zipstream"
+)
+
+func testEntries(files map[string]string, order []string) []zipstream.Entry {
+	modified := time.Date(2024, 7, 2, 18, 30, 0, 0, time.UTC)
+	entries := make([]zipstream.Entry, 0, len(order))
+	for _, name := range order {
+		content := files[name]
+		entries = append(entries, zipstream.Entry{
+			Name:     name,
+			Size:     int64(len(content)),
+			Modified: modified,
+			Open: func() (io.ReadCloser, error) {
+				return io.NopCloser(strings.NewReader(content)), nil
+			},
+		})
+	}
+	return entries
+}
+
+func TestArchiveIsReadable(t *testing.T) {
+	files := map[string]string{
+		"album/001-sunset.jpg": strings.Repeat("sunset ", 500),
+		"album/002-café.jpg":   "café au lait",
+		"album/003-empty.jpg":  "",
+		"album/captions.txt":   "001-sunset.jpg: Sunset\n",
+	}
+	order := []string{"album/001-sunset.jpg", "album/002-café.jpg", "album/003-empty.jpg", "album/captions.txt"}
+	archive := zipstream.New(testEntries(files, order))
+
+	var buf bytes.Buffer
+	written, err := archive.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("write archive: %v", err)
+	}
+	if written != archive.Size() || int64(buf.Len()) != archive.Size() {
+		t.Fatalf("expected %d bytes, wrote %d (buffer %d)", archive.Size(), written, buf.Len())
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	if len(reader.File) != len(order) {
+		t.Fatalf("expected %d entries, got %d", len(order), len(reader.File))
+	}
+	for i, file := range reader.File {
+		if file.Name != order[i] {
+			t.Fatalf("entry %d: expected %q, got %q", i, order[i], file.Name)
+		}
+		if file.Method != zip.Store {
+			t.Fatalf("entry %s: expected stored entry, got method %d", file.Name, file.Method)
+		}
+		if got := file.Modified; !got.Equal(time.Date(2024, 7, 2, 18, 30, 0, 0, got.Location())) {
+			t.Fatalf("entry %s: unexpected modification time %v", file.Name, got)
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", file.Name, err)
+		}
+		// Reading to EOF verifies the CRC.
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", file.Name, err)
+		}
+		if string(content) != files[file.Name] {
+			t.Fatalf("entry %s: unexpected content %q", file.Name, content)
+		}
+	}
+}
+
+func TestArchiveWriteRangeMatchesFullArchive(t *testing.T) {
+	files := map[string]string{
+		"a.jpg": strings.Repeat("a", 300),
+		"b.jpg": strings.Repeat("b", 700),
+		"c.jpg": strings.Repeat("c", 50),
+	}
+	archive := zipstream.New(testEntries(files, []string{"a.jpg", "b.jpg", "c.jpg"}))
+
+	var full bytes.Buffer
+	if _, err := archive.WriteTo(&full); err != nil {
+		t.Fatalf("write archive: %v", err)
+	}
+
+	size := archive.Size()
+	ranges := [][2]int64{
+		{0, size},
+		{0, 10},
+		{5, 400},
+		{350, 360},
+		{size / 2, size},
+		{size - 22, size},
+		{size, size},
+	}
+	for _, r := range ranges {
+		var part bytes.Buffer
+		if err := archive.WriteRange(&part, r[0], r[1]); err != nil {
+			t.Fatalf("range %d-%d: %v", r[0], r[1], err)
+		}
+		if !bytes.Equal(part.Bytes(), full.Bytes()[r[0]:r[1]]) {
+			t.Fatalf("range %d-%d does not match the full archive", r[0], r[1])
+		}
+	}
+
+	if err := archive.WriteRange(io.Discard, 10, size+1); err == nil {
+		t.Fatal("expected a range past the end to be rejected")
+	}
+}
+
+func TestArchiveReportsShortEntries(t *testing.T) {
+	archive := zipstream.New([]zipstream.Entry{{
+		Name: "short.jpg",
+		Size: 10,
+		Open: func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("abc")), nil
+		},
+	}})
+
+	_, err := archive.WriteTo(io.Discard)
+	if !errors.Is(err, zipstream.ErrSizeMismatch) {
+		t.Fatalf("expected ErrSizeMismatch, got %v", err)
+	}
+}
@@ -42,7 +42,7 @@ func MainLayout(title string) templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "</title><style>\n                :root {\n                    color-scheme: light;\n                }\n                *, *::before, *::after { box-sizing: border-box; }\n                body {\n                    margin: 0;\n                    min-height: 100vh;\n                    font-family: \"Inter\", -apple-system, BlinkMacSystemFont, \"Segoe UI\", sans-serif;\n                    background: #ffffff;\n                    color: #111111;\n                    -webkit-font-smoothing: antialiased;\n                }\n                main {\n                    margin: 0 auto;\n                    max-width: 960px;\n                    padding: 4rem 2rem;\n                    display: flex;\n                    flex-direction: column;\n                    gap: 2.75rem;\n                }\n                a {\n                    color: inherit;\n                }\n                h1, h2 {\n                    margin: 0;\n                    font-weight: 600;\n                    letter-spacing: -0.02em;\n                }\n                h1 {\n                    font-size: 2.4rem;\n                }\n                h2 {\n                    font-size: 1.5rem;\n                }\n                p {\n                    margin: 0;\n                    color: #3c3c3c;\n                    line-height: 1.5;\n                }\n                form {\n                    display: flex;\n                    flex-direction: column;\n                    gap: 1.2rem;\n                }\n                header {\n                    display: flex;\n                    flex-direction: column;\n                    gap: 0.75rem;\n                }\n                header div {\n                    display: flex;\n                    flex-direction: column;\n                    gap: 0.35rem;\n                }\n                .primary-action {\n                    display: inline-flex;\n                    align-items: center;\n                    justify-content: center;\n                    border-radius: 999px;\n                    border: 1px solid #111111;\n                    padding: 0.55rem 1.15rem;\n                    font-weight: 600;\n                    color: #ffffff;\n                    background: #111111;\n                    text-decoration: none;\n                    transition: background-color 0.15s ease, color 0.15s ease;\n                }\n                .primary-action:hover {\n                    background: #000000;\n                }\n                .primary-action:focus-visible {\n                    outline: 2px solid #111111;\n                    outline-offset: 3px;\n                }\n                .button-secondary {\n                    display: inline-flex;\n                    align-items: center;\n                    justify-content: center;\n                    border-radius: 999px;\n                    border: 1px solid rgba(17, 17, 17, 0.15);\n                    padding: 0.55rem 1.15rem;\n                    font-weight: 500;\n                    color: #111111;\n                    text-decoration: none;\n                    transition: border-color 0.15s ease, background-color 0.15s ease;\n                }\n                .button-secondary:hover {\n                    border-color: #111111;\n                    background: rgba(17, 17, 17, 0.05);\n                }\n                .album-grid {\n                    list-style: none;\n                    margin: 0;\n                    padding: 0;\n                    display: flex;\n                    flex-direction: column;\n                    gap: 1.5rem;\n                }\n                .album-grid li {\n                    padding: 1.5rem 0;\n                    border-bottom: 1px solid rgba(17, 17, 17, 0.12);\n                }\n                .album-grid li:last-child {\n                    border-bottom: none;\n                }\n                .album-grid article {\n                    display: flex;\n                    align-items: baseline;\n                    justify-content: space-between;\n                    gap: 1.5rem;\n                }\n                .album-title {\n                    font-size: 1.15rem;\n                    font-weight: 600;\n                }\n                .album-meta {\n                    color: #5b5b5b;\n                    font-size: 0.95rem;\n                }\n                label {\n                    display: flex;\n                    flex-direction: column;\n                    gap: 0.45rem;\n                    font-weight: 500;\n                    color: #111111;\n                }\n                input, textarea, select {\n                    padding: 0.9rem 1rem;\n                    border-radius: 14px;\n                    border: 1px solid rgba(17, 17, 17, 0.18);\n                    background: #ffffff;\n                    font-size: 1rem;\n                    transition: border-color 0.2s ease, box-shadow 0.2s ease;\n                }\n                input:focus-visible, textarea:focus-visible, select:focus-visible {\n                    outline: none;\n                    border-color: #111111;\n                    box-shadow: 0 0 0 3px rgba(17, 17, 17, 0.12);\n                }\n                textarea {\n                    resize: vertical;\n                    min-height: 140px;\n                }\n                button {\n                    padding: 0.9rem 1.2rem;\n                    border-radius: 999px;\n                    border: none;\n                    background: #111111;\n                    color: #ffffff;\n                    font-weight: 600;\n                    font-size: 1rem;\n                    cursor: pointer;\n                    transition: background-color 0.2s ease, transform 0.15s ease;\n                }\n                button:hover {\n                    background: #000000;\n                    transform: translateY(-1px);\n                }\n                button:focus-visible {\n                    outline: 2px solid #111111;\n                    outline-offset: 3px;\n                }\n                .form-footnote {\n                    text-align: center;\n                    font-size: 0.85rem;\n                    color: #5b5b5b;\n                }\n                .album-photos {\n                    display: flex;\n                    flex-direction: column;\n                    gap: 1.5rem;\n                }\n                .photo-upload {\n                    padding: 1.5rem;\n                    border-radius: 16px;\n                    border: 1px solid rgba(17, 17, 17, 0.1);\n                    background: #ffffff;\n                    display: grid;\n                    gap: 1.2rem;\n                }\n                .photo-grid {\n                    list-style: none;\n                    margin: 0;\n                    padding: 0;\n                    display: grid;\n                    gap: 1.25rem;\n                    grid-template-columns: repeat(auto-fill, minmax(180px, 1fr));\n                }\n                .photo-card {\n                    display: flex;\n                    flex-direction: column;\n                    gap: 0.75rem;\n                    padding: 1rem;\n                    border-radius: 18px;\n                    border: 1px solid rgba(17, 17, 17, 0.12);\n                    background: #ffffff;\n                    overflow: hidden;\n                }\n                .photo-card figure {\n                    margin: 0;\n                    display: flex;\n                    flex-direction: column;\n                    gap: 0.6rem;\n                    height: 100%;\n                }\n                .photo-card img {\n                    display: block;\n                    width: 100%;\n                    aspect-ratio: 4 / 5;\n                    object-fit: cover;\n                    max-height: 320px;\n                    border-radius: 14px;\n                    border: 1px solid rgba(17, 17, 17, 0.18);\n                    background: #ffffff;\n                }\n                .photo-card figcaption {\n                    display: flex;\n                    flex-direction: column;\n                    gap: 0.3rem;\n                    font-size: 0.95rem;\n                }\n                .photo-card strong {\n                    font-weight: 600;\n                    color: #111111;\n                }\n                .photo-meta {\n                    color: #5b5b5b;\n                    font-size: 0.85rem;\n                }\n                .empty-state {\n                    color: #5b5b5b;\n                }\n                body:has(.public-album) {\n                    background: #040404;\n                    color: #f5f5f5;\n                }\n                main:has(.public-album) {\n                    max-width: none;\n                    width: 100%;\n                    padding: 0;\n                    min-height: 100vh;\n                }\n                main:has(.public-album) > .public-album {\n                    width: 100%;\n                }\n                .public-album {\n                    display: flex;\n                    flex-direction: column;\n                    min-height: 100vh;\n                    background: #050505;\n                    color: #f5f5f5;\n                }\n                .public-album__stage {\n                    flex: 1;\n                    position: relative;\n                    display: flex;\n                    align-items: center;\n                    justify-content: center;\n                }\n                .album-hero {\n                    margin: 0;\n                    position: relative;\n                    width: min(100%, 1400px);\n                }\n                .album-hero img {\n                    width: 100%;\n                    height: auto;\n                    display: block;\n                    object-fit: contain;\n                    max-height: calc(100vh - 220px);\n                    background: #090909;\n                    box-shadow: 0 30px 80px rgba(0, 0, 0, 0.65);\n                    cursor: zoom-in;\n                }\n                .album-hero__details {\n                    display: flex;\n                    flex-direction: column;\n                    gap: 0.4rem;\n                    padding: clamp(1rem, 2.5vw, 2rem) clamp(1.5rem, 3vw, 3rem);\n                    background: linear-gradient(180deg, rgba(0, 0, 0, 0) 0%, rgba(0, 0, 0, 0.75) 100%);\n                    border-radius: 0 0 24px 24px;\n                }\n                .album-hero__details h2 {\n                    margin: 0;\n                    font-size: clamp(1.05rem, 2vw, 1.3rem);\n                    font-weight: 600;\n                    color: #fafafa;\n                }\n                .album-hero__meta {\n                    display: flex;\n                    flex-wrap: wrap;\n                    gap: 0.75rem;\n                    font-size: 0.85rem;\n                    color: rgba(245, 245, 245, 0.8);\n                }\n                .album-carousel {\n                    border-top: 1px solid rgba(255, 255, 255, 0.08);\n                    background: rgba(0, 0, 0, 0.94);\n                    padding: 0.9rem clamp(1rem, 3vw, 2.5rem);\n                }\n                .album-carousel__track {\n                    display: flex;\n                    gap: 0.5rem;\n                    overflow-x: auto;\n                    padding-bottom: 0.3rem;\n                    scrollbar-width: thin;\n                }\n                .album-carousel__track::-webkit-scrollbar {\n                    height: 5px;\n                }\n                .album-carousel__track::-webkit-scrollbar-thumb {\n                    background: rgba(255, 255, 255, 0.15);\n                    border-radius: 999px;\n                }\n                .album-carousel__thumb {\n                    border: 1px solid transparent;\n                    border-radius: 10px;\n                    padding: 0.15rem;\n                    background: transparent;\n                    cursor: pointer;\n                    transition: transform 0.2s ease, border-color 0.2s ease, box-shadow 0.2s ease;\n                    display: inline-flex;\n                }\n                .album-carousel__thumb img {\n                    display: block;\n                    width: 72px;\n                    height: 72px;\n                    object-fit: cover;\n                    border-radius: 6px;\n                    filter: saturate(0.75);\n                    opacity: 0.75;\n                    transition: filter 0.2s ease, opacity 0.2s ease;\n                }\n                .album-carousel__thumb:hover img {\n                    filter: saturate(1);\n                    opacity: 0.9;\n                }\n                .album-carousel__thumb.is-active {\n                    border-color: rgba(255, 255, 255, 0.6);\n                    box-shadow: 0 6px 16px rgba(0, 0, 0, 0.45);\n                }\n                .album-carousel__thumb.is-active img {\n                    filter: saturate(1);\n                    opacity: 1;\n                }\n                .album-carousel__thumb:not(.is-active):hover {\n                    transform: translateY(-2px);\n                }\n                .public-album__stage button {\n                    display: none;\n                }\n                .lightbox[hidden] {\n                    display: none;\n                }\n                .lightbox {\n                    position: fixed;\n                    inset: 0;\n                    z-index: 1000;\n                    display: flex;\n                    align-items: center;\n                    justify-content: center;\n                    background: rgba(0, 0, 0, 0.75);\n                    backdrop-filter: blur(6px);\n                }\n                .lightbox__backdrop {\n                    position: absolute;\n                    inset: 0;\n                    background: rgba(0, 0, 0, 0.8);\n                }\n                .lightbox__content {\n                    position: relative;\n                    z-index: 1;\n                    width: 100%;\n                    max-width: min(1600px, 95vw);\n                    padding: clamp(1.25rem, 4vw, 3rem);\n                    display: flex;\n                    align-items: center;\n                    justify-content: center;\n                }\n                .lightbox__figure {\n                    display: flex;\n                    flex-direction: column;\n                    gap: 1rem;\n                    width: 100%;\n                }\n                .lightbox__figure img {\n                    width: 100%;\n                    max-height: calc(100vh - 100px);\n                    object-fit: contain;\n                    border-radius: 24px;\n                    background: #050505;\n                    border: 1px solid rgba(255, 255, 255, 0.2);\n                    box-shadow: 0 30px 80px rgba(0, 0, 0, 0.6);\n                }\n                .lightbox__details {\n                    display: flex;\n                    align-items: center;\n                    justify-content: space-between;\n                    gap: 1rem;\n                    flex-wrap: wrap;\n                    color: #f5f5f5;\n                }\n                .lightbox__details h2 {\n                    margin: 0;\n                    font-size: clamp(1rem, 2vw, 1.25rem);\n                    font-weight: 600;\n                }\n                .lightbox__meta {\n                    display: flex;\n                    flex-wrap: wrap;\n                    gap: 0.75rem;\n                    font-size: 0.9rem;\n                    color: rgba(245, 245, 245, 0.8);\n                }\n                .lightbox__close {\n                    position: absolute;\n                    top: clamp(1rem, 3vw, 2rem);\n                    right: clamp(1rem, 3vw, 2rem);\n                    background: #111111;\n                    color: #f5f5f5;\n                    border: 1px solid rgba(255, 255, 255, 0.2);\n                    width: 3rem;\n                    height: 3rem;\n                    border-radius: 50%;\n                    font-size: 1.6rem;\n                    line-height: 1;\n                    cursor: pointer;\n                    display: flex;\n                    align-items: center;\n                    justify-content: center;\n                    transition: background 0.2s ease;\n                }\n                .lightbox__control {\n                    position: absolute;\n                    top: 50%;\n                    width: 3.2rem;\n                    height: 3.2rem;\n                    border-radius: 50%;\n                    border: 1px solid rgba(255, 255, 255, 0.2);\n                    background: #111111;\n                    color: #f5f5f5;\n                    font-size: 2rem;\n                    line-height: 1;\n                    cursor: pointer;\n                    display: flex;\n                    align-items: center;\n                    justify-content: center;\n                    transition: background 0.2s ease, box-shadow 0.2s ease;\n                }\n                .lightbox__control--prev {\n                    left: clamp(1rem, 3vw, 2rem);\n                }\n                .lightbox__control--next {\n                    right: clamp(1rem, 3vw, 2rem);\n                }\n                .lightbox__close:hover,\n                .lightbox__control:hover {\n                    background: rgba(255, 255, 255, 0.15);\n                }\n                .lightbox__close:focus-visible,\n                .lightbox__control:focus-visible {\n                    outline: 2px solid #ffffff;\n                    outline-offset: 3px;\n                }\n                @media (max-width: 700px) {\n                    main {\n                        padding: 3rem 1.25rem;\n                    }\n                    h1 {\n                        font-size: 2rem;\n                    }\n                    .photo-grid {\n                        grid-template-columns: repeat(auto-fill, minmax(150px, 1fr));\n                    }\n                    body:has(.public-album) main {\n                        padding: 0;\n                    }\n                    .public-album__stage {\n                        padding: 1rem;\n                    }\n                    .album-hero__details {\n                        position: static;\n                        background: none;\n                        padding: 0;\n                        margin-top: 1rem;\n                    }\n                    .album-hero img {\n                        max-height: calc(100vh - 260px);\n                        border-radius: 18px;\n                    }\n                    .album-carousel {\n                        padding: 1rem;\n                    }\n                    .album-carousel__thumb img {\n                        min-width: 72px;\n                    }\n                    .lightbox__content {\n                        padding: 1rem;\n                    }\n                    .lightbox__figure img {\n                        border-radius: 18px;\n                    }\n                    .lightbox__control {\n                        width: 2.75rem;\n                        height: 2.75rem;\n                    }\n                    .lightbox__close {\n                        width: 2.75rem;\n                        height: 2.75rem;\n                    }\n                }\n            </style></head><body><main>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "</title><style>\n                :root {\n                    color-scheme: light;\n                }\n                *, *::before, *::after { box-sizing: border-box; }\n                body {\n                    margin: 0;\n                    min-height: 100vh;\n                    font-family: \"Inter\", -apple-system, BlinkMacSystemFont, \"Segoe UI\", sans-serif;\n                    background: #ffffff;\n                    color: #111111;\n                    -webkit-font-smoothing: antialiased;\n                }\n                main {\n                    margin: 0 auto;\n                    max-width: 960px;\n                    padding: 4rem 2rem;\n                    display: flex;\n                    flex-direction: column;\n                    gap: 2.75rem;\n                }\n                a {\n                    color: inherit;\n                }\n                h1, h2 {\n                    margin: 0;\n                    font-weight: 600;\n                    letter-spacing: -0.02em;\n                }\n                h1 {\n                    font-size: 2.4rem;\n                }\n                h2 {\n                    font-size: 1.5rem;\n                }\n                p {\n                    margin: 0;\n                    color: #3c3c3c;\n                    line-height: 1.5;\n                }\n                form {\n                    display: flex;\n                    flex-direction: column;\n                    gap: 1.2rem;\n                }\n                header {\n                    display: flex;\n                    flex-direction: column;\n                    gap: 0.75rem;\n                }\n                header div {\n                    display: flex;\n                    flex-direction: column;\n                    gap: 0.35rem;\n                }\n                .primary-action {\n                    display: inline-flex;\n                    align-items: center;\n                    justify-content: center;\n                    border-radius: 999px;\n                    border: 1px solid #111111;\n                    padding: 0.55rem 1.15rem;\n                    font-weight: 600;\n                    color: #ffffff;\n                    background: #111111;\n                    text-decoration: none;\n                    transition: background-color 0.15s ease, color 0.15s ease;\n                }\n                .primary-action:hover {\n                    background: #000000;\n                }\n                .primary-action:focus-visible {\n                    outline: 2px solid #111111;\n                    outline-offset: 3px;\n                }\n                .button-secondary {\n                    display: inline-flex;\n                    align-items: center;\n                    justify-content: center;\n                    border-radius: 999px;\n                    border: 1px solid rgba(17, 17, 17, 0.15);\n                    padding: 0.55rem 1.15rem;\n                    font-weight: 500;\n                    color: #111111;\n                    background: transparent;\n                    text-decoration: none;\n                    transition: border-color 0.15s ease, background-color 0.15s ease;\n                }\n                .button-secondary:hover {\n                    border-color: #111111;\n                    background: rgba(17, 17, 17, 0.05);\n                }\n                .album-grid {\n                    list-style: none;\n                    margin: 0;\n                    padding: 0;\n                    display: flex;\n                    flex-direction: column;\n                    gap: 1.5rem;\n                }\n                .album-grid li {\n                    padding: 1.5rem 0;\n                    border-bottom: 1px solid rgba(17, 17, 17, 0.12);\n                }\n                .album-grid li:last-child {\n                    border-bottom: none;\n                }\n                .album-grid article {\n                    display: flex;\n                    align-items: baseline;\n                    justify-content: space-between;\n                    gap: 1.5rem;\n                }\n                .album-thumb {\n                    width: 72px;\n                    height: 72px;\n                    flex-shrink: 0;\n                    align-self: center;\n                    object-fit: cover;\n                    border-radius: 12px;\n                    border: 1px solid rgba(17, 17, 17, 0.12);\n                }\n                .album-title {\n                    font-size: 1.15rem;\n                    font-weight: 600;\n                }\n                .album-meta {\n                    color: #5b5b5b;\n                    font-size: 0.95rem;\n                }\n                label {\n                    display: flex;\n                    flex-direction: column;\n                    gap: 0.45rem;\n                    font-weight: 500;\n                    color: #111111;\n                }\n                .checkbox-label {\n                    flex-direction: row;\n                    align-items: center;\n                }\n                .checkbox-label input {\n                    padding: 0;\n                }\n                input, textarea, select {\n                    padding: 0.9rem 1rem;\n                    border-radius: 14px;\n                    border: 1px solid rgba(17, 17, 17, 0.18);\n                    background: #ffffff;\n                    font-size: 1rem;\n                    transition: border-color 0.2s ease, box-shadow 0.2s ease;\n                }\n                input:focus-visible, textarea:focus-visible, select:focus-visible {\n                    outline: none;\n                    border-color: #111111;\n                    box-shadow: 0 0 0 3px rgba(17, 17, 17, 0.12);\n                }\n                textarea {\n                    resize: vertical;\n                    min-height: 140px;\n                }\n                button {\n                    padding: 0.9rem 1.2rem;\n                    border-radius: 999px;\n                    border: none;\n                    background: #111111;\n                    color: #ffffff;\n                    font-weight: 600;\n                    font-size: 1rem;\n                    cursor: pointer;\n                    transition: background-color 0.2s ease, transform 0.15s ease;\n                }\n                button:hover {\n                    background: #000000;\n                    transform: translateY(-1px);\n                }\n                button:focus-visible {\n                    outline: 2px solid #111111;\n                    outline-offset: 3px;\n                }\n                .form-footnote {\n                    text-align: center;\n                    font-size: 0.85rem;\n                    color: #5b5b5b;\n                }\n                .album-photos {\n                    display: flex;\n                    flex-direction: column;\n                    gap: 1.5rem;\n                }\n                .photo-upload {\n                    padding: 1.5rem;\n                    border-radius: 16px;\n                    border: 1px solid rgba(17, 17, 17, 0.1);\n                    background: #ffffff;\n                    display: grid;\n                    gap: 1.2rem;\n                }\n                .photo-grid {\n                    list-style: none;\n                    margin: 0;\n                    padding: 0;\n                    display: grid;\n                    gap: 1.25rem;\n                    grid-template-columns: repeat(auto-fill, minmax(180px, 1fr));\n                }\n                .photo-card {\n                    display: flex;\n                    flex-direction: column;\n                    gap: 0.75rem;\n                    padding: 1rem;\n                    border-radius: 18px;\n                    border: 1px solid rgba(17, 17, 17, 0.12);\n                    background: #ffffff;\n                    overflow: hidden;\n                }\n                .photo-card figure {\n                    margin: 0;\n                    display: flex;\n                    flex-direction: column;\n                    gap: 0.6rem;\n                    height: 100%;\n                }\n                .photo-card img {\n                    display: block;\n                    width: 100%;\n                    height: auto;\n                    aspect-ratio: 4 / 5;\n                    object-fit: cover;\n                    max-height: 320px;\n                    border-radius: 14px;\n                    border: 1px solid rgba(17, 17, 17, 0.18);\n                    background: #ffffff;\n                }\n                .photo-card figcaption {\n                    display: flex;\n                    flex-direction: column;\n                    gap: 0.3rem;\n                    font-size: 0.95rem;\n                }\n                .photo-card strong {\n                    font-weight: 600;\n                    color: #111111;\n                }\n                .photo-meta {\n                    color: #5b5b5b;\n                    font-size: 0.85rem;\n                }\n                .upload-progress {\n                    width: 100%;\n                }\n                .upload-summary {\n                    list-style: none;\n                    margin: 0;\n                    padding: 0;\n                    display: flex;\n                    flex-direction: column;\n                    gap: 0.35rem;\n                    font-size: 0.9rem;\n                }\n                .upload-summary__item {\n                    display: flex;\n                    gap: 0.75rem;\n                    color: #5b5b5b;\n                }\n                .watch-failures {\n                    display: flex;\n                    flex-direction: column;\n                    gap: 0.75rem;\n                    margin-bottom: 2rem;\n                    padding: 1.25rem 1.5rem;\n                    border: 1px solid rgba(17, 17, 17, 0.2);\n                    border-radius: 16px;\n                }\n                .watch-failures h2,\n                .watch-failures p {\n                    margin: 0;\n                }\n                .watch-failures .upload-summary__item {\n                    flex-wrap: wrap;\n                    align-items: center;\n                }\n                .watch-failures__time {\n                    color: #5b5b5b;\n                    font-weight: 400;\n                }\n                .upload-report {\n                    margin: 0;\n                    font-weight: 600;\n                }\n                .upload-summary__item--error {\n                    color: #111111;\n                    font-weight: 600;\n                }\n                .similar-filter {\n                    flex-direction: row;\n                    align-items: flex-end;\n                    gap: 1rem;\n                }\n                .photo-order {\n                    flex-direction: row;\n                    align-items: center;\n                    justify-content: space-between;\n                    gap: 1rem;\n                }\n                .photo-card[draggable=\"true\"] {\n                    cursor: grab;\n                }\n                .photo-card.is-dragging {\n                    opacity: 0.4;\n                }\n                .photo-edit {\n                    gap: 0.6rem;\n                    font-size: 0.9rem;\n                }\n                .photo-edit input {\n                    padding: 0.55rem 0.7rem;\n                    border-radius: 10px;\n                    font-size: 0.9rem;\n                }\n                .photo-actions {\n                    display: flex;\n                    flex-direction: row;\n                    flex-wrap: wrap;\n                    gap: 0.5rem;\n                }\n                .photo-badge {\n                    align-self: flex-start;\n                    padding: 0.15rem 0.6rem;\n                    border-radius: 999px;\n                    background: #111111;\n                    color: #ffffff;\n                    font-size: 0.75rem;\n                    font-weight: 600;\n                }\n                .button-small {\n                    padding: 0.45rem 0.95rem;\n                    font-size: 0.9rem;\n                    font-weight: 500;\n                }\n                .button-danger {\n                    display: inline-flex;\n                    align-items: center;\n                    justify-content: center;\n                    align-self: flex-start;\n                    border-radius: 999px;\n                    text-decoration: none;\n                    padding: 0.45rem 0.95rem;\n                    font-size: 0.9rem;\n                    font-weight: 500;\n                    background: transparent;\n                    color: #b00020;\n                    border: 1px solid rgba(176, 0, 32, 0.35);\n                }\n                .button-danger:hover {\n                    background: rgba(176, 0, 32, 0.08);\n                    border-color: #b00020;\n                }\n                .empty-state {\n                    color: #5b5b5b;\n                }\n                body:has(.public-album) {\n                    background: #040404;\n                    color: #f5f5f5;\n                }\n                main:has(.public-album) {\n                    max-width: none;\n                    width: 100%;\n                    padding: 0;\n                    min-height: 100vh;\n                }\n                main:has(.public-album) > .public-album {\n                    width: 100%;\n                }\n                .public-album {\n                    display: flex;\n                    flex-direction: column;\n                    min-height: 100vh;\n                    background: #050505;\n                    color: #f5f5f5;\n                }\n                .public-album__download {\n                    margin: 0;\n                    text-align: center;\n                    font-size: 0.9rem;\n                }\n                .public-album__stage {\n                    flex: 1;\n                    position: relative;\n                    display: flex;\n                    align-items: center;\n                    justify-content: center;\n                }\n                .album-hero {\n                    margin: 0;\n                    position: relative;\n                    width: min(100%, 1400px);\n                }\n                .album-hero img {\n                    width: 100%;\n                    height: auto;\n                    display: block;\n                    object-fit: contain;\n                    max-height: calc(100vh - 220px);\n                    background: #090909;\n                    box-shadow: 0 30px 80px rgba(0, 0, 0, 0.65);\n                    cursor: zoom-in;\n                }\n                .album-hero__details {\n                    display: flex;\n                    flex-direction: column;\n                    gap: 0.4rem;\n                    padding: clamp(1rem, 2.5vw, 2rem) clamp(1.5rem, 3vw, 3rem);\n                    background: linear-gradient(180deg, rgba(0, 0, 0, 0) 0%, rgba(0, 0, 0, 0.75) 100%);\n                    border-radius: 0 0 24px 24px;\n                }\n                .album-hero__details h2 {\n                    margin: 0;\n                    font-size: clamp(1.05rem, 2vw, 1.3rem);\n                    font-weight: 600;\n                    color: #fafafa;\n                }\n                .album-hero__meta {\n                    display: flex;\n                    flex-wrap: wrap;\n                    gap: 0.75rem;\n                    font-size: 0.85rem;\n                    color: rgba(245, 245, 245, 0.8);\n                }\n                .album-carousel {\n                    border-top: 1px solid rgba(255, 255, 255, 0.08);\n                    background: rgba(0, 0, 0, 0.94);\n                    padding: 0.9rem clamp(1rem, 3vw, 2.5rem);\n                }\n                .album-carousel__track {\n                    display: flex;\n                    gap: 0.5rem;\n                    overflow-x: auto;\n                    padding-bottom: 0.3rem;\n                    scrollbar-width: thin;\n                }\n                .album-carousel__track::-webkit-scrollbar {\n                    height: 5px;\n                }\n                .album-carousel__track::-webkit-scrollbar-thumb {\n                    background: rgba(255, 255, 255, 0.15);\n                    border-radius: 999px;\n                }\n                .album-carousel__thumb {\n                    border: 1px solid transparent;\n                    border-radius: 10px;\n                    padding: 0.15rem;\n                    background: transparent;\n                    cursor: pointer;\n                    transition: transform 0.2s ease, border-color 0.2s ease, box-shadow 0.2s ease;\n                    display: inline-flex;\n                }\n                .album-carousel__thumb img {\n                    display: block;\n                    width: 72px;\n                    height: 72px;\n                    object-fit: cover;\n                    border-radius: 6px;\n                    filter: saturate(0.75);\n                    opacity: 0.75;\n                    transition: filter 0.2s ease, opacity 0.2s ease;\n                }\n                .album-carousel__thumb:hover img {\n                    filter: saturate(1);\n                    opacity: 0.9;\n                }\n                .album-carousel__thumb.is-active {\n                    border-color: rgba(255, 255, 255, 0.6);\n                    box-shadow: 0 6px 16px rgba(0, 0, 0, 0.45);\n                }\n                .album-carousel__thumb.is-active img {\n                    filter: saturate(1);\n                    opacity: 1;\n                }\n                .album-carousel__thumb:not(.is-active):hover {\n                    transform: translateY(-2px);\n                }\n                .public-album__stage button {\n                    display: none;\n                }\n                .lightbox[hidden] {\n                    display: none;\n                }\n                .lightbox {\n                    position: fixed;\n                    inset: 0;\n                    z-index: 1000;\n                    display: flex;\n                    align-items: center;\n                    justify-content: center;\n                    background: rgba(0, 0, 0, 0.75);\n                    backdrop-filter: blur(6px);\n                }\n                .lightbox__backdrop {\n                    position: absolute;\n                    inset: 0;\n                    background: rgba(0, 0, 0, 0.8);\n                }\n                .lightbox__content {\n                    position: relative;\n                    z-index: 1;\n                    width: 100%;\n                    max-width: min(1600px, 95vw);\n                    padding: clamp(1.25rem, 4vw, 3rem);\n                    display: flex;\n                    align-items: center;\n                    justify-content: center;\n                }\n                .lightbox__figure {\n                    display: flex;\n                    flex-direction: column;\n                    gap: 1rem;\n                    width: 100%;\n                }\n                .lightbox__figure img {\n                    width: 100%;\n                    height: auto;\n                    max-height: calc(100vh - 100px);\n                    object-fit: contain;\n                    border-radius: 24px;\n                    background: #050505;\n                    border: 1px solid rgba(255, 255, 255, 0.2);\n                    box-shadow: 0 30px 80px rgba(0, 0, 0, 0.6);\n                }\n                .lightbox__details {\n                    display: flex;\n                    align-items: center;\n                    justify-content: space-between;\n                    gap: 1rem;\n                    flex-wrap: wrap;\n                    color: #f5f5f5;\n                }\n                .lightbox__details h2 {\n                    margin: 0;\n                    font-size: clamp(1rem, 2vw, 1.25rem);\n                    font-weight: 600;\n                }\n                .lightbox__meta {\n                    display: flex;\n                    flex-wrap: wrap;\n                    gap: 0.75rem;\n                    font-size: 0.9rem;\n                    color: rgba(245, 245, 245, 0.8);\n                }\n                .lightbox__info-toggle {\n                    background: transparent;\n                    color: inherit;\n                    border: 1px solid rgba(255, 255, 255, 0.35);\n                    border-radius: 999px;\n                    padding: 0.15rem 0.75rem;\n                    font-size: 0.85rem;\n                    cursor: pointer;\n                }\n                .lightbox__info-toggle[aria-expanded=\"true\"] {\n                    background: rgba(255, 255, 255, 0.15);\n                }\n                .lightbox__info {\n                    margin: 0;\n                    font-size: 0.9rem;\n                    color: rgba(245, 245, 245, 0.8);\n                }\n                .lightbox__close {\n                    position: absolute;\n                    top: clamp(1rem, 3vw, 2rem);\n                    right: clamp(1rem, 3vw, 2rem);\n                    background: #111111;\n                    color: #f5f5f5;\n                    border: 1px solid rgba(255, 255, 255, 0.2);\n                    width: 3rem;\n                    height: 3rem;\n                    border-radius: 50%;\n                    font-size: 1.6rem;\n                    line-height: 1;\n                    cursor: pointer;\n                    display: flex;\n                    align-items: center;\n                    justify-content: center;\n                    transition: background 0.2s ease;\n                }\n                .lightbox__control {\n                    position: absolute;\n                    top: 50%;\n                    width: 3.2rem;\n                    height: 3.2rem;\n                    border-radius: 50%;\n                    border: 1px solid rgba(255, 255, 255, 0.2);\n                    background: #111111;\n                    color: #f5f5f5;\n                    font-size: 2rem;\n                    line-height: 1;\n                    cursor: pointer;\n                    display: flex;\n                    align-items: center;\n                    justify-content: center;\n                    transition: background 0.2s ease, box-shadow 0.2s ease;\n                }\n                .lightbox__control--prev {\n                    left: clamp(1rem, 3vw, 2rem);\n                }\n                .lightbox__control--next {\n                    right: clamp(1rem, 3vw, 2rem);\n                }\n                .lightbox__close:hover,\n                .lightbox__control:hover {\n                    background: rgba(255, 255, 255, 0.15);\n                }\n                .lightbox__close:focus-visible,\n                .lightbox__control:focus-visible {\n                    outline: 2px solid #ffffff;\n                    outline-offset: 3px;\n                }\n                @media (max-width: 700px) {\n                    main {\n                        padding: 3rem 1.25rem;\n                    }\n                    h1 {\n                        font-size: 2rem;\n                    }\n                    .photo-grid {\n                        grid-template-columns: repeat(auto-fill, minmax(150px, 1fr));\n                    }\n                    body:has(.public-album) main {\n                        padding: 0;\n                    }\n                    .public-album__stage {\n                        padding: 1rem;\n                    }\n                    .album-hero__details {\n                        position: static;\n                        background: none;\n                        padding: 0;\n                        margin-top: 1rem;\n                    }\n                    .album-hero img {\n                        max-height: calc(100vh - 260px);\n                        border-radius: 18px;\n                    }\n                    .album-carousel {\n                        padding: 1rem;\n                    }\n                    .album-carousel__thumb img {\n                        min-width: 72px;\n                    }\n                    .lightbox__content {\n                        padding: 1rem;\n                    }\n                    .lightbox__figure img {\n                        border-radius: 18px;\n                    }\n                    .lightbox__control {\n                        width: 2.75rem;\n                        height: 2.75rem;\n                    }\n                    .lightbox__close {\n                        width: 2.75rem;\n                        height: 2.75rem;\n                    }\n                }\n            </style></head><body><main>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}